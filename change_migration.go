@@ -0,0 +1,431 @@
+package amigo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alexisvisco/amigo/pkg/schema"
+)
+
+// Changer is implemented by Go migrations that describe their change once with a Schema.
+// The down migration is derived by reverting the recorded operations in reverse order, see ChangeMigration.
+//
+// Change only records operations: it is called to plan both directions and must not touch the database.
+type Changer interface {
+	Name() string
+	Date() int64
+	Change(ctx context.Context, s *Schema)
+}
+
+// ChangeMigration adapts a Changer to the Migration interface.
+//
+// Example usage:
+//
+//	func (m Migration20240101120000CreateUsers) Change(ctx context.Context, s *amigo.Schema) {
+//	    s.CreateTable("users", func(t *schema.Table) {
+//	        t.Column(schema.BigInt("id").PrimaryKey(), schema.Text("email").NotNull())
+//	    })
+//	    s.AddIndex(schema.Index{Table: "users", Columns: []string{"email"}, Unique: true})
+//	}
+//
+//	func (m Migration20240101120000CreateUsers) Up(ctx context.Context, db *sql.DB) error {
+//	    return amigo.ChangeMigration{Changer: m, Transactional: true}.Up(ctx, db)
+//	}
+//
+// Down and Irreversible delegate the same way, `generate --change` writes this boilerplate.
+// Statements are rendered for the driver running the migration, see DriverFromContext.
+type ChangeMigration struct {
+	Changer
+
+	// Transactional runs the statements of a direction in a single transaction
+	Transactional bool
+}
+
+// Up executes the recorded operations in order
+func (c ChangeMigration) Up(ctx context.Context, db *sql.DB) error {
+	statements, err := c.upStatements(ctx)
+	if err != nil {
+		return err
+	}
+	return c.exec(ctx, db, statements)
+}
+
+// Down executes the inverse of the recorded operations in reverse order.
+// It returns ErrIrreversible without executing anything when one of the operations cannot be reverted.
+func (c ChangeMigration) Down(ctx context.Context, db *sql.DB) error {
+	statements, err := c.downStatements(ctx)
+	if err != nil {
+		return err
+	}
+	return c.exec(ctx, db, statements)
+}
+
+// upStatements returns the statements applying the recorded operations
+func (c ChangeMigration) upStatements(ctx context.Context) ([]string, error) {
+	s, err := c.record(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect, err := contextDialect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []string
+	for _, op := range s.operations {
+		up, err := op.up(dialect)
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s: %w", op.describe(), err)
+		}
+		statements = append(statements, up)
+	}
+	return statements, nil
+}
+
+// downStatements returns the statements reverting the recorded operations, last operation first
+func (c ChangeMigration) downStatements(ctx context.Context) ([]string, error) {
+	s, err := c.record(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range s.operations {
+		if !op.reversible() {
+			return nil, fmt.Errorf("%w: %s cannot be reverted", ErrIrreversible, op.describe())
+		}
+	}
+
+	dialect, err := contextDialect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []string
+	for i := len(s.operations) - 1; i >= 0; i-- {
+		down, err := s.operations[i].down(dialect)
+		if err != nil {
+			return nil, fmt.Errorf("failed to revert %s: %w", s.operations[i].describe(), err)
+		}
+		statements = append(statements, down)
+	}
+	return statements, nil
+}
+
+// Irreversible reports whether one of the recorded operations cannot be reverted, see Irreversible
+func (c ChangeMigration) Irreversible() bool {
+	s, err := c.record(context.Background())
+	if err != nil {
+		return false
+	}
+
+	for _, op := range s.operations {
+		if !op.reversible() {
+			return true
+		}
+	}
+	return false
+}
+
+// record calls Change with an empty schema and returns the recorded operations
+func (c ChangeMigration) record(ctx context.Context) (*Schema, error) {
+	s := &Schema{}
+	c.Change(ctx, s)
+	if s.err != nil {
+		return nil, fmt.Errorf("invalid change in migration %s: %w", c.Name(), s.err)
+	}
+	return s, nil
+}
+
+// exec executes the statements, in a transaction when the migration is transactional
+func (c ChangeMigration) exec(ctx context.Context, db *sql.DB, statements []string) error {
+	if c.Transactional {
+		return Tx(ctx, db, func(tx *sql.Tx) error {
+			chain := NewChainExecTx(ctx, tx)
+			for _, statement := range statements {
+				chain.Exec(statement)
+			}
+			return chain.Err()
+		})
+	}
+
+	chain := NewChainExec(ctx, db)
+	for _, statement := range statements {
+		chain.Exec(statement)
+	}
+	return chain.Err()
+}
+
+// Schema records the operations of a Changer, defined with the types of pkg/schema like schema.Builder does, to
+// execute or revert them later. Invalid operations are reported when the migration runs.
+type Schema struct {
+	operations []schemaOperation
+	err        error
+}
+
+// schemaOperation is an operation recorded by a Schema
+type schemaOperation interface {
+	// up returns the statement applying the operation
+	up(d schema.Dialect) (string, error)
+	// down returns the statement reverting the operation, only called when it is reversible
+	down(d schema.Dialect) (string, error)
+	// reversible reports whether the operation can be reverted
+	reversible() bool
+	// describe returns a short description of the operation for error messages
+	describe() string
+}
+
+func (s *Schema) record(op schemaOperation, err error) {
+	if err != nil {
+		s.err = errors.Join(s.err, err)
+		return
+	}
+	s.operations = append(s.operations, op)
+}
+
+// CreateTable creates the table defined by build, as schema.Builder.CreateTable does. Reverted with DROP TABLE.
+func (s *Schema) CreateTable(name string, build func(t *schema.Table)) {
+	s.record(createTableOperation{name: name, build: build}, requireTable(name, build))
+}
+
+// DropTable drops a table. It is reversible only when build defines the table to recreate.
+func (s *Schema) DropTable(name string, build func(t *schema.Table)) {
+	if build == nil {
+		s.record(dropTableOperation{name: name}, requireName("table", name))
+		return
+	}
+	s.record(dropTableOperation{name: name, build: build}, requireTable(name, build))
+}
+
+// AddColumn adds a column to a table. Reverted with DROP COLUMN.
+func (s *Schema) AddColumn(table string, column *schema.Column) {
+	s.record(addColumnOperation{table: table, column: column}, errors.Join(requireName("table", table), requireColumn(column)))
+}
+
+// RemoveColumn drops a column from a table. It is reversible only when column defines the column to recreate.
+func (s *Schema) RemoveColumn(table, name string, column *schema.Column) {
+	err := errors.Join(requireName("table", table), requireName("column", name))
+	if column != nil {
+		err = errors.Join(err, requireColumn(column))
+		if column.Name() != name {
+			err = errors.Join(err, fmt.Errorf("column %s to remove is defined as %s", name, column.Name()))
+		}
+	}
+	s.record(removeColumnOperation{table: table, name: name, column: column}, err)
+}
+
+// RenameColumn renames a column. Reverted by renaming it back.
+func (s *Schema) RenameColumn(table, from, to string) {
+	err := errors.Join(requireName("table", table), requireName("column", from), requireName("column", to))
+	s.record(renameColumnOperation{table: table, from: from, to: to}, err)
+}
+
+// AddIndex creates an index, named idx_<table>_<columns> when its name is empty. Reverted with DROP INDEX.
+func (s *Schema) AddIndex(index schema.Index) {
+	s.record(addIndexOperation{index: index}, validateIndex(index))
+}
+
+// RemoveIndex drops an index, named idx_<table>_<columns> when its name is empty. Reverted by creating the
+// index again, its columns and options must be the ones it was created with.
+func (s *Schema) RemoveIndex(index schema.Index) {
+	s.record(removeIndexOperation{index: index}, validateIndex(index))
+}
+
+// Exec records a raw statement. It cannot be reverted, use Reversible to provide the down statement.
+func (s *Schema) Exec(query string) {
+	s.record(execOperation{query: query}, requireName("query", query))
+}
+
+// Reversible records a raw statement with the statement reverting it
+func (s *Schema) Reversible(up, down string) {
+	s.record(execOperation{query: up, revert: down, isReversible: true}, errors.Join(requireName("query", up), requireName("query", down)))
+}
+
+// requireTable returns an error when the table has no name or no definition
+func requireTable(name string, build func(t *schema.Table)) error {
+	if build == nil {
+		return fmt.Errorf("table %s has no columns", name)
+	}
+	return requireName("table", name)
+}
+
+// requireColumn returns an error when the column has no name or no type
+func requireColumn(column *schema.Column) error {
+	if column == nil {
+		return errors.New("column is required")
+	}
+	return errors.Join(requireName("column", column.Name()), requireName("type of column "+column.Name(), string(column.Type())))
+}
+
+func validateIndex(idx schema.Index) error {
+	if len(idx.Columns) == 0 {
+		return fmt.Errorf("index %s has no columns", idx.IndexName())
+	}
+	return requireName("table", idx.Table)
+}
+
+// requireName returns an error when value is empty
+func requireName(what, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("%s is required", what)
+	}
+	return nil
+}
+
+type createTableOperation struct {
+	name  string
+	build func(t *schema.Table)
+}
+
+func (o createTableOperation) up(d schema.Dialect) (string, error) {
+	return schema.CreateTableSQL(d, o.name, o.build)
+}
+
+func (o createTableOperation) down(d schema.Dialect) (string, error) {
+	return schema.DropTableSQL(d, o.name)
+}
+
+func (o createTableOperation) reversible() bool { return true }
+
+func (o createTableOperation) describe() string {
+	return "create table " + o.name
+}
+
+type dropTableOperation struct {
+	name  string
+	build func(t *schema.Table) // nil when the table is unknown
+}
+
+func (o dropTableOperation) up(d schema.Dialect) (string, error) {
+	return schema.DropTableSQL(d, o.name)
+}
+
+func (o dropTableOperation) down(d schema.Dialect) (string, error) {
+	return schema.CreateTableSQL(d, o.name, o.build)
+}
+
+func (o dropTableOperation) reversible() bool { return o.build != nil }
+
+func (o dropTableOperation) describe() string {
+	return "drop table " + o.name
+}
+
+type addColumnOperation struct {
+	table  string
+	column *schema.Column
+}
+
+func (o addColumnOperation) up(d schema.Dialect) (string, error) {
+	return schema.AddColumnSQL(d, o.table, o.column)
+}
+
+func (o addColumnOperation) down(d schema.Dialect) (string, error) {
+	return schema.DropColumnSQL(d, o.table, o.column.Name())
+}
+
+func (o addColumnOperation) reversible() bool { return true }
+
+func (o addColumnOperation) describe() string {
+	return fmt.Sprintf("add column %s.%s", o.table, o.column.Name())
+}
+
+type removeColumnOperation struct {
+	table  string
+	name   string
+	column *schema.Column // nil when the column is unknown
+}
+
+func (o removeColumnOperation) up(d schema.Dialect) (string, error) {
+	return schema.DropColumnSQL(d, o.table, o.name)
+}
+
+func (o removeColumnOperation) down(d schema.Dialect) (string, error) {
+	return schema.AddColumnSQL(d, o.table, o.column)
+}
+
+func (o removeColumnOperation) reversible() bool { return o.column != nil }
+
+func (o removeColumnOperation) describe() string {
+	return fmt.Sprintf("remove column %s.%s", o.table, o.name)
+}
+
+type renameColumnOperation struct {
+	table, from, to string
+}
+
+func (o renameColumnOperation) up(d schema.Dialect) (string, error) {
+	return schema.RenameColumnSQL(d, o.table, o.from, o.to)
+}
+
+func (o renameColumnOperation) down(d schema.Dialect) (string, error) {
+	return schema.RenameColumnSQL(d, o.table, o.to, o.from)
+}
+
+func (o renameColumnOperation) reversible() bool { return true }
+
+func (o renameColumnOperation) describe() string {
+	return fmt.Sprintf("rename column %s.%s to %s", o.table, o.from, o.to)
+}
+
+type addIndexOperation struct {
+	index schema.Index
+}
+
+func (o addIndexOperation) up(d schema.Dialect) (string, error) {
+	return schema.CreateIndexSQL(d, o.index)
+}
+
+func (o addIndexOperation) down(d schema.Dialect) (string, error) {
+	return schema.DropIndexSQL(d, o.index.Table, o.index.IndexName())
+}
+
+func (o addIndexOperation) reversible() bool { return true }
+
+func (o addIndexOperation) describe() string {
+	return "add index " + o.index.IndexName()
+}
+
+type removeIndexOperation struct {
+	index schema.Index
+}
+
+func (o removeIndexOperation) up(d schema.Dialect) (string, error) {
+	return schema.DropIndexSQL(d, o.index.Table, o.index.IndexName())
+}
+
+func (o removeIndexOperation) down(d schema.Dialect) (string, error) {
+	return schema.CreateIndexSQL(d, o.index)
+}
+
+func (o removeIndexOperation) reversible() bool { return true }
+
+func (o removeIndexOperation) describe() string {
+	return "remove index " + o.index.IndexName()
+}
+
+type execOperation struct {
+	query        string
+	revert       string
+	isReversible bool
+}
+
+func (o execOperation) up(schema.Dialect) (string, error) {
+	return o.query, nil
+}
+
+func (o execOperation) down(schema.Dialect) (string, error) {
+	return o.revert, nil
+}
+
+func (o execOperation) reversible() bool { return o.isReversible }
+
+func (o execOperation) describe() string {
+	query := strings.Join(strings.Fields(o.query), " ")
+	if len(query) > 40 {
+		query = query[:40] + "..."
+	}
+	return fmt.Sprintf("exec %q", query)
+}
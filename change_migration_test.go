@@ -0,0 +1,139 @@
+package amigo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/alexisvisco/amigo/pkg/schema"
+)
+
+type testChanger struct {
+	change func(s *Schema)
+}
+
+func (m testChanger) Name() string { return "test" }
+func (m testChanger) Date() int64  { return 20240101120000 }
+func (m testChanger) Change(_ context.Context, s *Schema) {
+	m.change(s)
+}
+
+func TestChangeMigration(t *testing.T) {
+	tests := []struct {
+		name             string
+		change           func(s *Schema)
+		wantUp           []string
+		wantDown         []string
+		wantIrreversible bool
+	}{
+		{
+			name: "operations are reverted in reverse order",
+			change: func(s *Schema) {
+				s.CreateTable("users", func(t *schema.Table) {
+					t.Column(schema.NewColumn("id", "BIGINT").PrimaryKey(), schema.NewColumn("email", "TEXT").NotNull().Default("''"))
+				})
+				s.AddColumn("users", schema.NewColumn("age", "INT"))
+				s.AddIndex(schema.Index{Table: "users", Columns: []string{"email"}, Unique: true})
+				s.RenameColumn("users", "age", "years")
+			},
+			wantUp: []string{
+				"CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT NOT NULL DEFAULT '')",
+				"ALTER TABLE users ADD COLUMN age INT",
+				"CREATE UNIQUE INDEX idx_users_email ON users (email)",
+				"ALTER TABLE users RENAME COLUMN age TO years",
+			},
+			wantDown: []string{
+				"ALTER TABLE users RENAME COLUMN years TO age",
+				"DROP INDEX idx_users_email",
+				"ALTER TABLE users DROP COLUMN age",
+				"DROP TABLE users",
+			},
+		},
+		{
+			name: "removals are reversible when the definition is known",
+			change: func(s *Schema) {
+				s.RemoveColumn("users", "age", schema.NewColumn("age", "INT").NotNull())
+				s.RemoveIndex(schema.Index{Name: "users_email", Table: "users", Columns: []string{"email"}})
+			},
+			wantUp: []string{
+				"ALTER TABLE users DROP COLUMN age",
+				"DROP INDEX users_email",
+			},
+			wantDown: []string{
+				"CREATE INDEX users_email ON users (email)",
+				"ALTER TABLE users ADD COLUMN age INT NOT NULL",
+			},
+		},
+		{
+			name: "raw statements are irreversible",
+			change: func(s *Schema) {
+				s.Reversible("UPDATE users SET a = 1", "UPDATE users SET a = 0")
+				s.Exec("DELETE FROM users")
+			},
+			wantUp:           []string{"UPDATE users SET a = 1", "DELETE FROM users"},
+			wantIrreversible: true,
+		},
+		{
+			name: "removing a column without its definition is irreversible",
+			change: func(s *Schema) {
+				s.RemoveColumn("users", "age", nil)
+			},
+			wantUp:           []string{"ALTER TABLE users DROP COLUMN age"},
+			wantIrreversible: true,
+		},
+		{
+			name: "dropping a table without its columns is irreversible",
+			change: func(s *Schema) {
+				s.DropTable("users", nil)
+			},
+			wantUp:           []string{"DROP TABLE users"},
+			wantIrreversible: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := ChangeMigration{Changer: testChanger{change: tt.change}}
+			ctx := ContextWithDriver(context.Background(), NewPostgresDriver(""))
+
+			up, err := m.upStatements(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(up, tt.wantUp) {
+				t.Errorf("up:\ngot:  %q\nwant: %q", up, tt.wantUp)
+			}
+
+			if got := m.Irreversible(); got != tt.wantIrreversible {
+				t.Errorf("Irreversible: got %v, want %v", got, tt.wantIrreversible)
+			}
+
+			down, err := m.downStatements(ctx)
+			if tt.wantIrreversible {
+				if !errors.Is(err, ErrIrreversible) {
+					t.Errorf("down: got error %v, want ErrIrreversible", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(down, tt.wantDown) {
+				t.Errorf("down:\ngot:  %q\nwant: %q", down, tt.wantDown)
+			}
+		})
+	}
+}
+
+func TestChangeMigration_invalid(t *testing.T) {
+	m := ChangeMigration{Changer: testChanger{change: func(s *Schema) {
+		s.CreateTable("users", func(t *schema.Table) {})
+		s.AddColumn("users", schema.NewColumn("age", ""))
+	}}}
+
+	ctx := ContextWithDriver(context.Background(), NewPostgresDriver(""))
+	if _, err := m.upStatements(ctx); err == nil {
+		t.Fatal("expected an error for invalid operations")
+	}
+}
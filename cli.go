@@ -1,10 +1,16 @@
 package amigo
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/alexisvisco/amigo/pkg/termcolor"
 )
 
 // CLI represents the command-line interface for migrations
@@ -12,13 +18,27 @@ type CLI struct {
 	config               Configuration
 	runner               *Runner
 	migrations           []Migration
+	seeds                []Seeder
 	output               io.Writer
 	errorOutput          io.Writer
 	cliOutput            *cliOutput
 	directory            string
+	seedDirectory        string
 	defaultTransactional bool
 	defaultFileFormat    string
 	packageName          string
+	input                io.Reader
+	confirmer            Confirmer
+	nonInteractive       bool
+	confirmationName     string
+	confirmedName        string
+	protectedEnvs        []string
+
+	// loadErr is the error of rendering the SQL templates with the final configuration, see renderTemplates
+	loadErr error
+
+	// interrupt is closed when the process receives SIGINT/SIGTERM while a command runs
+	interrupt chan struct{}
 }
 
 // CLIConfig holds the configuration for creating a CLI instance
@@ -29,15 +49,50 @@ type CLIConfig struct {
 	// Migrations is the list of available migrations
 	Migrations []Migration
 
+	// Seeds is the list of available seeds, see Seeder
+	Seeds []Seeder
+
 	// Output is the writer for standard output
 	Output io.Writer // defaults to os.Stdout
 
 	// ErrorOut is the writer for error messages
 	ErrorOut io.Writer // defaults to os.Stderr
 
+	// Input is the reader for confirmation answers
+	Input io.Reader // defaults to os.Stdin
+
+	// Confirmer asks the operator to confirm operations. Defaults to a prompt reading answers from Input.
+	Confirmer Confirmer
+
+	// NonInteractive grants confirmations without asking when Input is not a terminal, except the ones
+	// requiring ConfirmationName, which must be given with the --confirm-name flag.
+	// It can also be enabled with the --non-interactive flag or the AMIGO_NON_INTERACTIVE environment variable.
+	NonInteractive bool
+
+	// ConfirmationName, when not empty, must be typed by the operator (instead of answering yes) before
+	// destructive operations such as reverting all migrations. Typically the database name.
+	// Defaults to the environment name when one is configured.
+	ConfirmationName string
+
+	// Environment is the name of the environment the CLI runs against (e.g. production, staging).
+	// It overrides Config.Environment when not empty, is shown in every confirmation prompt and is recorded
+	// in the schema_migrations table.
+	Environment string
+
+	// AppVersion is the version of the application embedding the migrations. It overrides Config.AppVersion
+	// when not empty and is recorded in the schema_migrations table.
+	AppVersion string
+
+	// ProtectedEnvironments lists environments where destructive commands such as down are refused
+	// unless --i-know-what-im-doing is passed.
+	ProtectedEnvironments []string
+
 	// Directory is the location of the migrations files
 	Directory string
 
+	// SeedDirectory is the location of the seed files, defaults to the seeds folder of Directory
+	SeedDirectory string
+
 	// DefaultTransactional indicates if new migrations should be run inside a transaction by wrapping them in a Tx helper
 	// or putting the tx annotation in SQL files
 	DefaultTransactional bool
@@ -47,6 +102,10 @@ type CLIConfig struct {
 
 	// PackageName is the package name for generated Go files. If not specified, defaults to the directory name.
 	PackageName string
+
+	// Color controls ANSI colors in the output. The default (termcolor.ModeAuto) enables them only when Output
+	// is a terminal, honoring the NO_COLOR and FORCE_COLOR environment variables. The --color flag overrides it.
+	Color termcolor.Mode
 }
 
 // NewCLI creates a new CLI instance with the given configuration
@@ -57,6 +116,22 @@ func NewCLI(cfg CLIConfig) *CLI {
 	if cfg.ErrorOut == nil {
 		cfg.ErrorOut = os.Stderr
 	}
+	if cfg.Input == nil {
+		cfg.Input = os.Stdin
+	}
+	if cfg.Confirmer == nil {
+		cfg.Confirmer = NewPromptConfirmer(cfg.Input, cfg.Output)
+	}
+
+	if cfg.Environment != "" {
+		cfg.Config.Environment = cfg.Environment
+	}
+	if cfg.AppVersion != "" {
+		cfg.Config.AppVersion = cfg.AppVersion
+	}
+	if cfg.ConfirmationName == "" {
+		cfg.ConfirmationName = cfg.Config.Environment
+	}
 
 	// Use the folder name as package name if not specified
 	packageName := cfg.PackageName
@@ -67,25 +142,74 @@ func NewCLI(cfg CLIConfig) *CLI {
 		packageName = "migrations" // fallback default
 	}
 
+	if cfg.SeedDirectory == "" && cfg.Directory != "" {
+		cfg.SeedDirectory = filepath.Join(cfg.Directory, "seeds")
+	}
+
 	runner := NewRunner(cfg.Config)
+	migrations, seeds, loadErr := renderTemplates(cfg.Migrations, cfg.Seeds, cfg.Config)
 
 	return &CLI{
 		config:               cfg.Config,
 		runner:               runner,
-		migrations:           cfg.Migrations,
+		migrations:           migrations,
+		seeds:                seeds,
 		output:               cfg.Output,
 		errorOutput:          cfg.ErrorOut,
-		cliOutput:            newCLIOutput(),
+		cliOutput:            newCLIOutput(termcolor.Enabled(cfg.Color, cfg.Output)),
 		directory:            cfg.Directory,
+		seedDirectory:        cfg.SeedDirectory,
 		defaultTransactional: cfg.DefaultTransactional,
 		defaultFileFormat:    cfg.DefaultFileFormat,
 		packageName:          packageName,
+		input:                cfg.Input,
+		confirmer:            cfg.Confirmer,
+		nonInteractive:       cfg.NonInteractive || nonInteractiveFromEnv(),
+		confirmationName:     cfg.ConfirmationName,
+		protectedEnvs:        cfg.ProtectedEnvironments,
+		loadErr:              loadErr,
 	}
 }
 
 // Run executes the CLI with the given arguments
 // This is the main entry point that should be called from your main function
 func (c *CLI) Run(args []string) int {
+	fs := flag.NewFlagSet("amigo", flag.ContinueOnError)
+	fs.SetOutput(c.errorOutput)
+	fs.Usage = c.cliPrintHelp
+
+	var timeout time.Duration
+	fs.DurationVar(&timeout, "timeout", 0, "Maximum duration of the command (default: no timeout)")
+
+	var color string
+	fs.StringVar(&color, "color", "", "When to use colors: auto, always or never")
+
+	var nonInteractive bool
+	fs.BoolVar(&nonInteractive, "non-interactive", false, "Grant confirmations when stdin is not a terminal")
+
+	fs.StringVar(&c.confirmedName, "confirm-name", "", "Confirmation name granted in non-interactive mode")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	args = fs.Args()
+
+	if nonInteractive {
+		c.nonInteractive = true
+	}
+
+	if color != "" {
+		mode, err := termcolor.ParseMode(color)
+		if err != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+			return 1
+		}
+		c.cliOutput = newCLIOutput(termcolor.Enabled(mode, c.output))
+	}
+
 	if len(args) == 0 {
 		c.cliPrintHelp()
 		return 0
@@ -93,20 +217,48 @@ func (c *CLI) Run(args []string) int {
 
 	cmd := args[0]
 
+	if c.loadErr != nil && cmd != "help" {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", c.loadErr)))
+		return 1
+	}
+
+	ctx, stop := c.signalContext(context.Background(), gracefulCommands[cmd])
+	defer stop()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	switch cmd {
-	case "help", "-h", "--help":
+	case "help":
 		c.cliPrintHelp()
 		return 0
 	case "show-config":
-		return c.cliShowConfig(args[1:])
+		return c.cliShowConfig(ctx, args[1:])
 	case "generate":
 		return c.cliGenerate(args[1:])
 	case "up":
-		return c.cliUp(args[1:])
+		return c.cliUp(ctx, args[1:])
 	case "down":
-		return c.cliDown(args[1:])
+		return c.cliDown(ctx, args[1:])
 	case "status":
-		return c.cliStatus(args[1:])
+		return c.cliStatus(ctx, args[1:])
+	case "check":
+		return c.cliCheck(ctx, args[1:])
+	case "history":
+		return c.cliHistory(ctx, args[1:])
+	case "seed":
+		return c.cliSeed(ctx, args[1:])
+	case "squash":
+		return c.cliSquash(ctx, args[1:])
+	case "schema":
+		return c.cliSchema(ctx, args[1:])
+	case "lint":
+		return c.cliLint(ctx, args[1:])
+	case "test-rollback":
+		return c.cliTestRollback(ctx, args[1:])
 	default:
 		fmt.Fprintf(c.errorOutput, "Unknown command: %s\n\n", cmd)
 		c.cliPrintHelp()
@@ -116,7 +268,7 @@ func (c *CLI) Run(args []string) int {
 
 // cliPrintHelp displays the help message
 func (c *CLI) cliPrintHelp() {
-	help := `Usage: [command] [options]
+	help := `Usage: [global options] [command] [options]
 
 Commands:
   help          Show this help message
@@ -125,11 +277,31 @@ Commands:
   up            Run pending migrations
   down          Revert applied migrations
   status        Show migration status
+  check         Exit non-zero when the database does not match the migrations
+  history       Show the history of applied, reverted and failed migrations
+  seed          Run pending seeds
+  squash        Combine the oldest SQL migrations into a single migration
+  schema        Dump the database schema, or load it into an empty database
+  lint          Check the SQL migrations for dangerous operations
+  test-rollback Check that the down of every pending migration reverts its up
+
+Global options (before the command):
+  --timeout duration    Abort the command after this duration (e.g. 30s, 5m)
+  --color string        When to use colors: auto, always or never (default: auto)
+                        auto honors NO_COLOR/FORCE_COLOR and disables colors when
+                        the output is not a terminal
+  --non-interactive     Grant confirmations without asking when stdin is not a
+                        terminal (also enabled by AMIGO_NON_INTERACTIVE=true)
+  --confirm-name string Confirmation name to type, granted in non-interactive mode
+                        (required by down --steps=-1 when a name is configured)
 
 Options:
   -h, --help    Show help for a command
 
 Run '[command] --help' for more information on a command.
+
+On SIGINT/SIGTERM, up, down and seed finish the migration in flight and stop before
+the next one. A second signal aborts immediately.
 `
 	fmt.Fprint(c.output, help)
 }
@@ -0,0 +1,120 @@
+package amigo
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Exit codes returned by the check command
+const (
+	checkExitUpToDate = 0
+	checkExitError    = 1
+	checkExitPending  = 2
+	checkExitOrphaned = 3
+	checkExitDrift    = 4
+)
+
+// cliCheck verifies that the database matches the known migrations without prompting or writing anything
+func (c *CLI) cliCheck(ctx context.Context, args []string) int {
+	// Show help if requested (before parsing to avoid flag.Parse handling it)
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		c.cliCheckHelp()
+		return 0
+	}
+
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(c.errorOutput)
+
+	var quiet bool
+	fs.BoolVar(&quiet, "quiet", false, "Print nothing on success")
+	fs.BoolVar(&quiet, "q", false, "Print nothing on success (shorthand)")
+	selection := c.addSelectionFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return checkExitError
+	}
+
+	result, err := c.runner.Check(ctx, c.migrations, selection.options()...)
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+		return checkExitError
+	}
+
+	if result.LayoutUpgradePending && !quiet {
+		fmt.Fprintf(c.output, "%s\n", c.cliOutput.warning("Layout upgrade pending: amigo's tables are upgraded by the next command writing to the database"))
+	}
+
+	if result.UpToDate() {
+		if !quiet {
+			fmt.Fprintf(c.output, "Database is up to date (%d migration(s) applied)\n", result.Applied)
+		}
+		return checkExitUpToDate
+	}
+
+	if len(result.Pending) > 0 {
+		fmt.Fprintf(c.output, "%d pending migration(s): %s\n", len(result.Pending), c.formatCheckRecords(result.Pending))
+	}
+	if len(result.Orphaned) > 0 {
+		fmt.Fprintf(c.output, "%d applied migration(s) unknown to this binary: %s\n", len(result.Orphaned), c.formatCheckRecords(result.Orphaned))
+	}
+
+	if len(result.Drifted) > 0 {
+		fmt.Fprintf(c.output, "%d migration(s) changed since they were applied: %s\n", len(result.Drifted), c.formatCheckRecords(result.Drifted))
+	}
+
+	if len(result.Drifted) > 0 {
+		return checkExitDrift
+	}
+	if len(result.Orphaned) > 0 {
+		return checkExitOrphaned
+	}
+	return checkExitPending
+}
+
+// formatCheckRecords formats records as a comma separated list of "date_name", or "R_name" for repeatable migrations
+func (c *CLI) formatCheckRecords(records []MigrationRecord) string {
+	parts := make([]string, len(records))
+	for i, r := range records {
+		if r.Date == 0 {
+			parts[i] = repeatableFilePrefix + r.Name
+			continue
+		}
+		parts[i] = c.cliOutput.date(r.Date)
+		if r.Name != "" {
+			parts[i] += "_" + r.Name
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cliCheckHelp displays help for the check command
+func (c *CLI) cliCheckHelp() {
+	help := `Usage: check [options]
+
+Check that the database matches the migrations known by this binary.
+Never prompts and never writes to the database, which makes it suitable for
+readiness probes and CI pipelines.
+
+Options:
+  -q, --quiet    Print nothing on success
+  --env string   Environment selecting the migrations, migrations of other
+                 environments are not pending (default: the configured one)
+  --tags string  Comma separated tags selecting the migrations
+  -h, --help     Show this help message
+
+Exit codes:
+  0    The database is up to date
+  1    The check could not be performed
+  2    Some migrations are pending
+  3    Some applied migrations are unknown to this binary (orphaned records)
+  4    Some migrations changed since they were applied (checksum drift),
+       including repeatable migrations that must run again
+
+Examples:
+  check           Print a one-line summary and exit with the matching code
+  check --quiet   Only report through the exit code when up to date
+`
+	fmt.Fprint(c.output, help)
+}
@@ -1,18 +1,15 @@
 package amigo
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"os"
-	"slices"
-	"strings"
 	"text/tabwriter"
 )
 
 // cliDown reverts applied migrations
-func (c *CLI) cliDown(args []string) int {
+func (c *CLI) cliDown(ctx context.Context, args []string) int {
 	// Show help if requested (before parsing to avoid flag.Parse handling it)
 	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
 		c.cliDownHelp()
@@ -28,97 +25,142 @@ func (c *CLI) cliDown(args []string) int {
 	fs.BoolVar(&autoConfirm, "yes", false, "Skip confirmation prompt")
 	fs.BoolVar(&autoConfirm, "y", false, "Skip confirmation prompt (shorthand)")
 
+	var override bool
+	fs.BoolVar(&override, overrideProtectionFlag, false, "Allow reverting migrations in a protected environment")
+
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
 
-	ctx := context.Background()
-
-	// Get migration statuses to show what will be reverted
-	statuses, err := c.runner.GetMigrationsStatuses(ctx, c.migrations)
-	if err != nil {
-		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to get migration statuses: %v", err)))
+	if !c.guardProtectedEnvironment("down", override) {
 		return 1
 	}
 
-	var appliedMigrations []MigrationStatus
-	for _, status := range statuses {
-		if status.Applied {
-			appliedMigrations = append(appliedMigrations, status)
-		}
+	// Build options
+	var opts []RunnerDownOptsFunc
+	if steps >= 0 {
+		opts = append(opts, RunnerDownOptionSteps(steps))
 	}
 
-	// Sort by date descending (newest first)
-	slices.SortFunc(appliedMigrations, func(a, b MigrationStatus) int {
-		if a.Migration.Date > b.Migration.Date {
-			return -1
-		} else if a.Migration.Date < b.Migration.Date {
-			return 1
-		}
-		return 0
-	})
+	// Plan with the runner so the order shown is the order migrations are reverted in
+	plan, err := c.runner.PlanDown(ctx, c.migrations, opts...)
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+		return 1
+	}
 
-	if len(appliedMigrations) == 0 {
+	if len(plan) == 0 {
 		fmt.Fprintln(c.output, "No applied migrations to revert")
 		return 0
 	}
 
-	// Determine how many migrations will be reverted
-	migrationsToRevert := appliedMigrations
-	if steps > 0 && steps < len(appliedMigrations) {
-		migrationsToRevert = appliedMigrations[:steps]
+	migrationsToRevert := make([]MigrationStatus, 0, len(plan))
+	for _, m := range plan {
+		migrationsToRevert = append(migrationsToRevert, MigrationStatus{
+			Migration: MigrationRecord{Date: m.Date(), Name: m.Name()},
+			Applied:   true,
+		})
 	}
 
 	// Display migrations to revert
 	fmt.Fprintf(c.output, "The following %d migration(s) will be reverted:\n\n", len(migrationsToRevert))
 
+	var irreversible []Migration
 	w := tabwriter.NewWriter(c.output, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "Date\tName")
-	for _, m := range migrationsToRevert {
-		fmt.Fprintf(w, "%s\t%s\n", c.cliOutput.date(m.Migration.Date), m.Migration.Name)
+	fmt.Fprintln(w, "Date\tName\tReversible")
+	for _, m := range plan {
+		reversible := "yes"
+		if isIrreversible(m) {
+			reversible = c.cliOutput.error("no (irreversible)")
+			irreversible = append(irreversible, m)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.cliOutput.date(m.Date()), m.Name(), reversible)
 	}
 	w.Flush()
 
 	fmt.Fprintln(c.output, "")
 
+	// Nothing would be reverted anyway, the runner refuses to cross an irreversible migration
+	if len(irreversible) > 0 {
+		for _, m := range irreversible {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf(
+				"Error: cannot revert migration %s: %v", m.Name(), ErrIrreversible)))
+		}
+		return 1
+	}
+
+	// Reverting every migration requires typing the confirmation name when one is configured
+	confirmRequest := ConfirmRequest{Message: c.confirmMessage()}
+	if steps < 0 && c.confirmationName != "" {
+		message := "This will revert ALL applied migrations."
+		if c.config.Environment != "" {
+			message = fmt.Sprintf("This will revert ALL applied migrations on %s.", c.cliOutput.environment(c.config.Environment))
+		}
+		confirmRequest = ConfirmRequest{
+			Message:  message,
+			Expected: c.confirmationName,
+		}
+	}
+
 	// Prompt for confirmation unless --yes flag is set
 	if !autoConfirm {
-		fmt.Fprint(c.output, "Do you want to continue? (yes/no): ")
-		reader := bufio.NewReader(os.Stdin)
-		response, err := reader.ReadString('\n')
+		confirmed, err := c.confirm(ctx, confirmRequest)
+		if err != nil && c.interrupted() {
+			fmt.Fprintln(c.output, "Migration cancelled")
+			return exitCodeInterrupted
+		}
+		if errors.Is(err, errConfirmationNameRequired) {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+			return 1
+		}
 		if err != nil {
 			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to read input: %v", err)))
 			return 1
 		}
 
-		response = strings.TrimSpace(strings.ToLower(response))
-		if response != "yes" && response != "y" {
+		if !confirmed {
 			fmt.Fprintln(c.output, "Migration cancelled")
 			return 0
 		}
 	}
 
-	// Build options
-	var opts []RunnerDownOptsFunc
-	if steps >= 0 {
-		opts = append(opts, RunnerDownOptionSteps(steps))
-	}
-
-	// Run migrations using iterator to show progress
+	// Run migrations using iterator to show progress, stopping before the next one when interrupted
 	fmt.Fprintln(c.output, "")
-	migrationCount := 0
-	for result := range c.runner.DownIterator(ctx, c.migrations, opts...) {
+	var done []Migration
+	for result := range c.runner.DownIterator(ctx, c.migrations, append(opts, RunnerDownOptionStatementProgress())...) {
+		if result.Progress != nil {
+			c.printStatementProgress(result.Progress)
+			continue
+		}
+
 		if result.Error != nil {
 			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", result.Error)))
+			c.printStatementError(result.Error)
+			if c.interrupted() {
+				c.printInterruptSummary("reverted", done, migrationsToRevert)
+				return exitCodeInterrupted
+			}
 			return 1
 		}
 
-		migrationCount++
+		done = append(done, result.Migration)
 		fmt.Fprintf(c.output, "== %s: reverting (%s)\n\n", result.Migration.Name(), c.cliOutput.duration(result.Duration))
+
+		if c.interrupted() {
+			break
+		}
+	}
+
+	if c.interrupted() && len(done) < len(migrationsToRevert) {
+		c.printInterruptSummary("reverted", done, migrationsToRevert)
+		return exitCodeInterrupted
 	}
 
 	fmt.Fprintln(c.output, "")
-	fmt.Fprintf(c.output, "Successfully reverted %d migration(s)\n", migrationCount)
+	fmt.Fprintf(c.output, "Successfully reverted %d migration(s)\n", len(done))
+	if c.config.SchemaFile != "" {
+		fmt.Fprintf(c.output, "Updated schema file: %s\n", c.cliOutput.path(c.config.SchemaFile))
+	}
 	return 0
 }
 
@@ -126,11 +168,19 @@ func (c *CLI) cliDown(args []string) int {
 func (c *CLI) cliDownHelp() {
 	help := `Usage: down [options]
 
-Revert applied migrations.
+Revert applied migrations, newest first. A migration is always reverted before
+the migrations it depends on, and nothing is reverted while an applied
+migration that stays applied depends on one of the selected migrations.
+
+Nothing is reverted when one of the selected migrations is irreversible: a Go
+migration implementing amigo.Irreversible, or a SQL migration with an empty
+down section or a "-- migrate:down irreversible" annotation.
 
 Options:
   --steps int    Number of migrations to revert (default: 1)
   -y, --yes      Skip confirmation prompt
+  --i-know-what-im-doing
+                 Allow reverting migrations in a protected environment
   -h, --help     Show this help message
 
 Examples:
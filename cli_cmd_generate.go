@@ -25,10 +25,32 @@ func (c *CLI) cliGenerate(args []string) int {
 	var format string
 	fs.StringVar(&format, "format", c.defaultFileFormat, "File format (sql or go)")
 
+	var change bool
+	fs.BoolVar(&change, "change", false, "Generate a Go migration with a reversible Change method")
+
+	var repeatable bool
+	fs.BoolVar(&repeatable, "repeatable", false, "Generate a repeatable SQL migration, run again whenever it changes")
+
+	var seed bool
+	fs.BoolVar(&seed, "seed", false, "Generate a seed in the seeds directory")
+
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
 
+	if countTrue(change, repeatable, seed) > 1 {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error("Error: --change, --repeatable and --seed cannot be used together"))
+		return 1
+	}
+
+	// A change migration is always written in Go, a repeatable one in SQL
+	if change {
+		format = "go"
+	}
+	if repeatable {
+		format = "sql"
+	}
+
 	// Validate format
 	if format != "sql" && format != "go" {
 		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: invalid format '%s', must be 'sql' or 'go'", format)))
@@ -48,8 +70,13 @@ func (c *CLI) cliGenerate(args []string) int {
 	// Generate timestamp (YYYYMMDDHHMMSS format in UTC)
 	timestamp := time.Now().UTC().Format("20060102150405")
 
+	directory := c.directory
+	if seed {
+		directory = c.seedDirectory
+	}
+
 	// Create directory if it doesn't exist
-	if err := os.MkdirAll(c.directory, 0755); err != nil {
+	if err := os.MkdirAll(directory, 0755); err != nil {
 		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to create directory: %v", err)))
 		return 1
 	}
@@ -57,12 +84,18 @@ func (c *CLI) cliGenerate(args []string) int {
 	var filename, content string
 	var err error
 
-	if format == "sql" {
+	if seed {
+		filename = fmt.Sprintf("%s_%s.%s", timestamp, name, format)
+		content, err = c.generateSeedTemplate(format, name, timestamp)
+	} else if repeatable {
+		filename = fmt.Sprintf("%s%s.sql", repeatableFilePrefix, name)
+		content, err = c.generateSQLTemplate(true)
+	} else if format == "sql" {
 		filename = fmt.Sprintf("%s_%s.sql", timestamp, name)
-		content, err = c.generateSQLTemplate()
+		content, err = c.generateSQLTemplate(false)
 	} else {
 		filename = fmt.Sprintf("%s_%s.go", timestamp, name)
-		content, err = c.generateGoTemplate(name, timestamp)
+		content, err = c.generateGoTemplate(name, timestamp, change)
 	}
 
 	if err != nil {
@@ -70,7 +103,7 @@ func (c *CLI) cliGenerate(args []string) int {
 		return 1
 	}
 
-	filepath := filepath.Join(c.directory, filename)
+	filepath := filepath.Join(directory, filename)
 
 	// Check if file already exists
 	if _, err := os.Stat(filepath); err == nil {
@@ -84,6 +117,18 @@ func (c *CLI) cliGenerate(args []string) int {
 		return 1
 	}
 
+	if seed {
+		fmt.Fprintf(c.output, "Created seed: %s\n", c.cliOutput.path(filepath))
+
+		if err := c.generateSeedsList(); err != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to regenerate seeds.go: %v", err)))
+			return 1
+		}
+
+		fmt.Fprintf(c.output, "Updated seeds list: %s\n", c.cliOutput.path(directory+"/seeds.go"))
+		return 0
+	}
+
 	fmt.Fprintf(c.output, "Created migration: %s\n", c.cliOutput.path(filepath))
 
 	// Regenerate migrations.go
@@ -97,9 +142,14 @@ func (c *CLI) cliGenerate(args []string) int {
 	return 0
 }
 
-// generateSQLTemplate returns the SQL migration template
-func (c *CLI) generateSQLTemplate() (string, error) {
-	tmpl, err := template.New("sql").Parse(sqlTemplate)
+// generateSQLTemplate returns the SQL migration template, without down section when repeatable is set
+func (c *CLI) generateSQLTemplate(repeatable bool) (string, error) {
+	source := sqlTemplate
+	if repeatable {
+		source = sqlRepeatableTemplate
+	}
+
+	tmpl, err := template.New("sql").Parse(source)
 	if err != nil {
 		return "", err
 	}
@@ -118,9 +168,14 @@ func (c *CLI) generateSQLTemplate() (string, error) {
 	return buf.String(), nil
 }
 
-// generateGoTemplate returns the Go migration template
-func (c *CLI) generateGoTemplate(name, timestamp string) (string, error) {
-	tmpl, err := template.New("go").Parse(goTemplate)
+// generateGoTemplate returns the Go migration template, with a Change method instead of Up and Down when change is set
+func (c *CLI) generateGoTemplate(name, timestamp string, change bool) (string, error) {
+	source := goTemplate
+	if change {
+		source = goChangeTemplate
+	}
+
+	tmpl, err := template.New("go").Parse(source)
 	if err != nil {
 		return "", err
 	}
@@ -141,6 +196,45 @@ func (c *CLI) generateGoTemplate(name, timestamp string) (string, error) {
 	return buf.String(), nil
 }
 
+// generateSeedTemplate returns the seed template in the given format
+func (c *CLI) generateSeedTemplate(format, name, timestamp string) (string, error) {
+	source := sqlSeedTemplate
+	if format == "go" {
+		source = goSeedTemplate
+	}
+
+	tmpl, err := template.New("seed").Parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]interface{}{
+		"PackageName":   filepath.Base(c.seedDirectory),
+		"StructName":    sanitizeName(name),
+		"Name":          name,
+		"Timestamp":     timestamp,
+		"Transactional": c.defaultTransactional,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// countTrue returns how many of the given flags are set
+func countTrue(flags ...bool) int {
+	count := 0
+	for _, f := range flags {
+		if f {
+			count++
+		}
+	}
+	return count
+}
+
 // sanitizeName converts a migration name to a valid Go identifier
 func sanitizeName(name string) string {
 	// Simple title case conversion
@@ -161,6 +255,13 @@ Generate a new migration file with the given name.
 
 Options:
   --format string    File format: 'sql' or 'go' (default: configured value)
+  --change           Generate a Go migration with a Change method, its down
+                     is derived automatically
+  --repeatable       Generate a repeatable SQL migration R_<name>.sql, for
+                     views, functions or grants: it has no date and runs
+                     again after the versioned migrations whenever it changes
+  --seed             Generate a seed in the seeds directory (sql or go), see
+                     the seed command
   -h, --help         Show this help message
 
 Arguments:
@@ -169,6 +270,9 @@ Arguments:
 Examples:
   generate create_users_table
   generate --format=go add_email_column
+  generate --change create_posts_table
+  generate --repeatable refresh_views
+  generate --seed countries
   generate "create users table"
 `
 	fmt.Fprint(c.output, help)
@@ -37,3 +37,74 @@ func (m Migration{{.Timestamp}}{{.StructName}}) Down(ctx context.Context, db *sq
 	return nil
 {{end}}}
 `
+
+const goChangeTemplate = `package {{.PackageName}}
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/alexisvisco/amigo"
+)
+
+type Migration{{.Timestamp}}{{.StructName}} struct{}
+
+func (m Migration{{.Timestamp}}{{.StructName}}) Name() string {
+	return "{{.Name}}"
+}
+
+func (m Migration{{.Timestamp}}{{.StructName}}) Date() int64 {
+	return {{.Timestamp}}
+}
+
+// Change describes the migration, down is derived by reverting the operations in reverse order
+func (m Migration{{.Timestamp}}{{.StructName}}) Change(ctx context.Context, s *amigo.Schema) {
+	// TODO: implement change migration
+}
+
+func (m Migration{{.Timestamp}}{{.StructName}}) Up(ctx context.Context, db *sql.DB) error {
+	return m.adapter().Up(ctx, db)
+}
+
+func (m Migration{{.Timestamp}}{{.StructName}}) Down(ctx context.Context, db *sql.DB) error {
+	return m.adapter().Down(ctx, db)
+}
+
+func (m Migration{{.Timestamp}}{{.StructName}}) Irreversible() bool {
+	return m.adapter().Irreversible()
+}
+
+func (m Migration{{.Timestamp}}{{.StructName}}) adapter() amigo.ChangeMigration {
+	return amigo.ChangeMigration{Changer: m, Transactional: {{.Transactional}}}
+}
+`
+
+const goSeedTemplate = `package {{.PackageName}}
+
+import (
+	"context"
+	"database/sql"
+{{if .Transactional}}
+	"github.com/alexisvisco/amigo"
+{{end}})
+
+type Seed{{.Timestamp}}{{.StructName}} struct{}
+
+func (s Seed{{.Timestamp}}{{.StructName}}) Name() string {
+	return "{{.Timestamp}}_{{.Name}}"
+}
+
+// Environments restricts the seed to these environments, nil runs it everywhere
+func (s Seed{{.Timestamp}}{{.StructName}}) Environments() []string {
+	return nil
+}
+
+func (s Seed{{.Timestamp}}{{.StructName}}) Seed(ctx context.Context, db *sql.DB) error {
+{{if .Transactional}}	return amigo.Tx(ctx, db, func(tx *sql.Tx) error {
+		// TODO: implement seed
+		return nil
+	})
+{{else}}	// TODO: implement seed
+	return nil
+{{end}}}
+`
@@ -29,6 +29,25 @@ func Migrations(cfg amigo.Configuration) []amigo.Migration {
 }
 `
 
+const seedsListTemplate = `package {{.PackageName}}
+
+import (
+	"embed"
+
+	"github.com/alexisvisco/amigo"
+)
+
+//go:embed *.sql
+var sqlFiles embed.FS
+
+func Seeds(cfg amigo.Configuration) []amigo.Seeder {
+	return []amigo.Seeder{
+{{range .SQLSeeds}}		amigo.SQLFileToSeed(sqlFiles, "{{.}}", cfg),
+{{end}}{{range .GoSeeds}}		&Seed{{.}}{},
+{{end}}	}
+}
+`
+
 // generateMigrationsList creates the migrations.go file with all migrations
 func (c *CLI) generateMigrationsList() error {
 	// Read directory
@@ -59,7 +78,13 @@ func (c *CLI) generateMigrationsList() error {
 		} else if ext == ".go" {
 			// Extract struct name from filename
 			// Format: YYYYMMDDHHMMSS_name.go -> Migration20231224120000Name
+			// Format: R_name.go -> MigrationRepeatableName
 			baseName := strings.TrimSuffix(name, ".go")
+			if repeatableName, ok := parseRepeatableFileName(name); ok {
+				goStructNames = append(goStructNames, "Repeatable"+sanitizeName(repeatableName))
+				continue
+			}
+
 			parts := strings.SplitN(baseName, "_", 2)
 			if len(parts) == 2 {
 				timestamp := parts[0]
@@ -99,3 +124,57 @@ func (c *CLI) generateMigrationsList() error {
 
 	return nil
 }
+
+// generateSeedsList creates the seeds.go file with all seeds
+func (c *CLI) generateSeedsList() error {
+	entries, err := os.ReadDir(c.seedDirectory)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var sqlFiles []string
+	var goStructNames []string
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == "seeds.go" {
+			continue
+		}
+
+		switch filepath.Ext(name) {
+		case ".sql":
+			sqlFiles = append(sqlFiles, name)
+		case ".go":
+			// Format: YYYYMMDDHHMMSS_name.go -> Seed20231224120000Name
+			parts := strings.SplitN(strings.TrimSuffix(name, ".go"), "_", 2)
+			if len(parts) == 2 {
+				goStructNames = append(goStructNames, parts[0]+sanitizeName(parts[1]))
+			}
+		}
+	}
+
+	sort.Strings(sqlFiles)
+	sort.Strings(goStructNames)
+
+	tmpl, err := template.New("seeds_list").Parse(seedsListTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"PackageName": filepath.Base(c.seedDirectory),
+		"SQLSeeds":    sqlFiles,
+		"GoSeeds":     goStructNames,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(c.seedDirectory, "seeds.go"), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write seeds.go: %w", err)
+	}
+
+	return nil
+}
@@ -6,3 +6,25 @@ const sqlTemplate = `{{.UpAnnotation}}{{if .Transactional}} tx=true{{else}} tx=f
 {{.DownAnnotation}}{{if .Transactional}} tx=true{{else}} tx=false{{end}}
 
 `
+
+// sqlRepeatableTemplate is the template of repeatable migrations, which are never reverted and have no down section
+const sqlRepeatableTemplate = `{{.UpAnnotation}}{{if .Transactional}} tx=true{{else}} tx=false{{end}}
+
+`
+
+// sqlSeedTemplate is the template of SQL seeds, see parseSQLSeedFile
+const sqlSeedTemplate = `-- seed{{if .Transactional}} tx=true{{else}} tx=false{{end}}
+
+`
+
+// sqlSquashTemplate is the template of the migration written by the squash command, see Squashed
+const sqlSquashTemplate = `-- Squashed from {{.Count}} migration(s) up to {{.UpTo}}
+{{range .Replaced}}-- amigo:squashed {{.}}
+{{end}}
+{{.UpAnnotation}}{{if .Transactional}} tx=true{{else}} tx=false{{end}}
+{{range .Migrations}}
+-- {{.Version}}
+{{.Up}}
+{{end}}
+{{.DownAnnotation}} irreversible
+`
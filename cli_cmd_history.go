@@ -0,0 +1,100 @@
+package amigo
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// cliHistory displays the history of migration runs, including reverts and failures
+func (c *CLI) cliHistory(ctx context.Context, args []string) int {
+	// Show help if requested (before parsing to avoid flag.Parse handling it)
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		c.cliHistoryHelp()
+		return 0
+	}
+
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(c.errorOutput)
+
+	var filter HistoryFilter
+	fs.IntVar(&filter.Limit, "limit", 50, "Maximum number of entries to show (0 for all)")
+	fs.Int64Var(&filter.Date, "version", 0, "Only show entries of the migration with this date")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	history, err := c.runner.GetHistory(ctx, filter)
+	if errors.Is(err, ErrHistoryNotSupported) {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: the %s driver does not keep a migration history", c.config.Driver.Name())))
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+		return 1
+	}
+
+	if len(history) == 0 {
+		fmt.Fprintln(c.output, "No migration history")
+		return 0
+	}
+
+	w := tabwriter.NewWriter(c.output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Started At\tBatch\tDirection\tStatus\tDate\tName\tDuration\tEnvironment\tRun By\tError")
+
+	for _, h := range history {
+		status := string(h.Status)
+		if h.Status == HistoryStatusFailure {
+			status = c.cliOutput.error(status)
+		}
+
+		runBy := h.OSUser
+		if h.Hostname != "" {
+			runBy += "@" + h.Hostname
+		}
+
+		// Keep the table readable: only show the first line of multi-line errors
+		errorMessage, _, _ := strings.Cut(h.Error, "\n")
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.cliOutput.timestamp(h.StartedAt.Local()),
+			h.Batch,
+			h.Direction,
+			status,
+			c.cliOutput.date(h.Date),
+			h.Name,
+			c.cliOutput.duration(h.Duration),
+			h.Environment,
+			runBy,
+			errorMessage,
+		)
+	}
+
+	w.Flush()
+
+	return 0
+}
+
+// cliHistoryHelp displays help for the history command
+func (c *CLI) cliHistoryHelp() {
+	help := `Usage: history [options]
+
+Show the history of migration runs, most recent first. Unlike status, the
+history also keeps reverted and failed migrations.
+
+Options:
+  --limit int      Maximum number of entries to show, 0 for all (default: 50)
+  --version int    Only show entries of the migration with this date
+  -h, --help       Show this help message
+
+Examples:
+  history                           Show the 50 most recent entries
+  history --limit=0                 Show the whole history
+  history --version=20240101120000  Show what happened to one migration
+`
+	fmt.Fprint(c.output, help)
+}
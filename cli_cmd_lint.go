@@ -0,0 +1,152 @@
+package amigo
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// lintStatementMaxLength is the length after which the lint command truncates statements in text output
+const lintStatementMaxLength = 120
+
+// lintReport is the JSON output of the lint command
+type lintReport struct {
+	Findings []LintFinding `json:"findings"`
+	Errors   int           `json:"errors"`
+	Warnings int           `json:"warnings"`
+}
+
+// cliLint checks the SQL migrations for dangerous operations
+func (c *CLI) cliLint(ctx context.Context, args []string) int {
+	// Show help if requested (before parsing to avoid flag.Parse handling it)
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		c.cliLintHelp()
+		return 0
+	}
+
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(c.errorOutput)
+
+	var format, failOn string
+	fs.StringVar(&format, "format", "text", "Output format: text or json")
+	fs.StringVar(&failOn, "fail-on", string(LintSeverityError), "Exit non-zero on findings of this severity or higher: error or warning")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if format != "text" && format != "json" {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: invalid --format %q, want text or json", format)))
+		return 1
+	}
+	if failOn != string(LintSeverityError) && failOn != string(LintSeverityWarning) {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: invalid --fail-on %q, want error or warning", failOn)))
+		return 1
+	}
+
+	report := lintReport{Findings: c.runner.Lint(c.migrations)}
+	if report.Findings == nil {
+		report.Findings = []LintFinding{}
+	}
+
+	failed := false
+	for _, finding := range report.Findings {
+		if finding.Severity == LintSeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+		failed = failed || finding.Severity == LintSeverityError || LintSeverity(failOn) == LintSeverityWarning
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(c.output)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+			return 1
+		}
+	} else {
+		c.printLintFindings(report)
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// printLintFindings displays the findings of the lint command as text
+func (c *CLI) printLintFindings(report lintReport) {
+	if len(report.Findings) == 0 {
+		fmt.Fprintln(c.output, "No lint findings")
+		return
+	}
+
+	for _, finding := range report.Findings {
+		severity := c.cliOutput.warning(string(finding.Severity))
+		if finding.Severity == LintSeverityError {
+			severity = c.cliOutput.error(string(finding.Severity))
+		}
+
+		location := finding.Migration
+		if finding.Direction != "" {
+			location += " (" + string(finding.Direction) + ")"
+		}
+
+		fmt.Fprintf(c.output, "%s: %s [%s] %s\n", location, severity, finding.Rule, finding.Message)
+		if finding.Statement != "" {
+			statement := finding.Statement
+			if len(statement) > lintStatementMaxLength {
+				statement = statement[:lintStatementMaxLength] + "..."
+			}
+			fmt.Fprintf(c.output, "    %s\n", statement)
+		}
+	}
+
+	fmt.Fprintln(c.output, "")
+	fmt.Fprintf(c.output, "Found %d error(s) and %d warning(s)\n", report.Errors, report.Warnings)
+}
+
+// cliLintHelp displays help for the lint command
+func (c *CLI) cliLintHelp() {
+	help := `Usage: lint [options]
+
+Check the up and down sections of the SQL migrations for dangerous operations,
+such as indexes built without CONCURRENTLY or columns added with a default on
+large tables. Go migrations are not checked. Exits non-zero when a finding has
+the --fail-on severity or higher, so that CI can fail on it.
+
+Statements creating or changing a table created in the same section are not
+reported. Suppress a rule for a statement with a comment in it, on the line
+before the statement, or for the whole migration for rules checking the whole
+migration:
+
+  -- amigo:lint:ignore index-without-concurrently
+  CREATE INDEX idx_users_email ON users(email);
+
+Options:
+  --format string   Output format: text or json (default: text)
+  --fail-on string  Exit non-zero on findings of this severity or higher:
+                    error or warning (default: error)
+  -h, --help        Show this help message
+
+Rules:
+`
+	fmt.Fprint(c.output, help)
+	for _, rule := range DefaultLintRules() {
+		description := rule.Description
+		if len(rule.Drivers) > 0 {
+			description += " (" + strings.Join(rule.Drivers, ", ") + ")"
+		}
+		fmt.Fprintf(c.output, "  %-32s %-8s %s\n", rule.ID, rule.Severity, description)
+	}
+	fmt.Fprint(c.output, `
+Examples:
+  lint
+  lint --format=json
+  lint --fail-on=warning
+`)
+}
@@ -0,0 +1,188 @@
+package amigo
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// cliSchema runs the schema subcommands
+func (c *CLI) cliSchema(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		c.cliSchemaHelp()
+		return 0
+	}
+
+	switch args[0] {
+	case "dump":
+		return c.cliSchemaDump(ctx, args[1:])
+	case "load":
+		return c.cliSchemaLoad(ctx, args[1:])
+	default:
+		fmt.Fprintf(c.errorOutput, "Unknown schema command: %s\n\n", args[0])
+		c.cliSchemaHelp()
+		return 1
+	}
+}
+
+// cliSchemaDump writes the schema of the database to the schema file, or to the standard output
+func (c *CLI) cliSchemaDump(ctx context.Context, args []string) int {
+	// Show help if requested (before parsing to avoid flag.Parse handling it)
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		c.cliSchemaHelp()
+		return 0
+	}
+
+	fs := flag.NewFlagSet("schema dump", flag.ContinueOnError)
+	fs.SetOutput(c.errorOutput)
+
+	var output string
+	fs.StringVar(&output, "output", c.config.SchemaFile, "File to write the schema to, - for the standard output")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	schema, err := c.runner.DumpSchema(ctx, c.migrations)
+	if errors.Is(err, ErrSchemaDumpNotSupported) {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: the %s driver does not support schema dumps", c.config.Driver.Name())))
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+		return 1
+	}
+
+	if output == "" || output == "-" {
+		fmt.Fprint(c.output, schema)
+		return 0
+	}
+
+	if err := os.WriteFile(output, []byte(schema), 0644); err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to write schema file: %v", err)))
+		return 1
+	}
+
+	fmt.Fprintf(c.output, "Dumped schema: %s\n", c.cliOutput.path(output))
+	return 0
+}
+
+// cliSchemaLoad bootstraps an empty database from the schema file
+func (c *CLI) cliSchemaLoad(ctx context.Context, args []string) int {
+	// Show help if requested (before parsing to avoid flag.Parse handling it)
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		c.cliSchemaHelp()
+		return 0
+	}
+
+	fs := flag.NewFlagSet("schema load", flag.ContinueOnError)
+	fs.SetOutput(c.errorOutput)
+
+	var input string
+	var force, autoConfirm, override bool
+	fs.StringVar(&input, "input", c.config.SchemaFile, "Schema file to load")
+	fs.BoolVar(&force, "force", false, "Load even if the database has tables or applied migrations")
+	fs.BoolVar(&autoConfirm, "yes", false, "Skip confirmation prompt")
+	fs.BoolVar(&autoConfirm, "y", false, "Skip confirmation prompt (shorthand)")
+	fs.BoolVar(&override, overrideProtectionFlag, false, "Allow a forced load in a protected environment")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if input == "" {
+		fmt.Fprintln(c.errorOutput, "Error: --input is required when Configuration.SchemaFile is not set")
+		fmt.Fprintln(c.errorOutput, "")
+		c.cliSchemaHelp()
+		return 1
+	}
+
+	if force && !c.guardProtectedEnvironment("schema load --force", override) {
+		return 1
+	}
+
+	schema, err := os.ReadFile(input)
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to read schema file: %v", err)))
+		return 1
+	}
+
+	fmt.Fprintf(c.output, "The schema %s will be loaded into the database.\n\n", c.cliOutput.path(input))
+
+	if !autoConfirm {
+		confirmed, err := c.confirm(ctx, ConfirmRequest{Message: c.confirmMessage()})
+		if err != nil && c.interrupted() {
+			fmt.Fprintln(c.output, "Schema load cancelled")
+			return exitCodeInterrupted
+		}
+		if err != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to read input: %v", err)))
+			return 1
+		}
+
+		if !confirmed {
+			fmt.Fprintln(c.output, "Schema load cancelled")
+			return 0
+		}
+	}
+
+	var opts []RunnerLoadSchemaOptsFunc
+	if force {
+		opts = append(opts, RunnerLoadSchemaOptionForce())
+	}
+
+	records, err := c.runner.LoadSchema(ctx, string(schema), c.migrations, opts...)
+	switch {
+	case errors.Is(err, ErrSchemaLoadNotSupported):
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: the %s driver does not support schema loads", c.config.Driver.Name())))
+		return 1
+	case errors.Is(err, ErrDatabaseNotEmpty):
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v, pass --force to load the schema anyway", err)))
+		return 1
+	case err != nil:
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+		return 1
+	}
+
+	fmt.Fprintf(c.output, "Loaded schema, recorded %d migration(s) as applied\n", len(records))
+	return 0
+}
+
+// cliSchemaHelp displays help for the schema command
+func (c *CLI) cliSchemaHelp() {
+	help := `Usage: schema <command> [options]
+
+Manage the schema file, a dump of the database schema followed by the
+migrations it reflects. When Configuration.SchemaFile is set, up and down
+rewrite it after every run that applied or reverted a migration, so that it can
+be committed and reviewed with the migrations.
+
+Commands:
+  dump           Dump the schema of the database
+  load           Bootstrap an empty database from the schema file: run it and
+                 record the migrations it reflects as applied, in one
+                 transaction, so that up only runs the newer migrations
+
+Options of dump:
+  --output string  File to write the schema to, - for the standard output
+                   (default: Configuration.SchemaFile, or the standard output)
+
+Options of load:
+  --input string   Schema file to load (default: Configuration.SchemaFile)
+  --force          Load even if the database has tables or applied migrations
+  -y, --yes        Skip confirmation prompt
+  --i-know-what-im-doing
+                   Allow a forced load in a protected environment
+
+  -h, --help       Show this help message
+
+Examples:
+  schema dump
+  schema dump --output=db/structure.sql
+  schema dump --output=- > structure.sql
+  schema load --input=db/structure.sql
+`
+	fmt.Fprint(c.output, help)
+}
@@ -0,0 +1,181 @@
+package amigo
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// cliSeed runs the pending seeds, or lists them
+func (c *CLI) cliSeed(ctx context.Context, args []string) int {
+	// Show help if requested (before parsing to avoid flag.Parse handling it)
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		c.cliSeedHelp()
+		return 0
+	}
+
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.SetOutput(c.errorOutput)
+
+	var rerun, list, autoConfirm bool
+	fs.BoolVar(&rerun, "rerun", false, "Run seeds that already ran again")
+	fs.BoolVar(&list, "list", false, "List the seeds and whether they ran, without running them")
+	fs.BoolVar(&autoConfirm, "yes", false, "Skip confirmation prompt")
+	fs.BoolVar(&autoConfirm, "y", false, "Skip confirmation prompt (shorthand)")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(c.seeds) == 0 {
+		fmt.Fprintln(c.output, "No seeds found")
+		return 0
+	}
+
+	if list {
+		return c.cliSeedList(ctx)
+	}
+
+	opts := []RunnerSeedOptsFunc{RunnerSeedOptionNames(fs.Args()...)}
+	if rerun {
+		opts = append(opts, RunnerSeedOptionRerun())
+	}
+
+	var options runnerSeedOpts
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	_, plan, err := c.runner.planSeeds(ctx, c.seeds, options)
+	if errors.Is(err, ErrSeedNotSupported) {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: the %s driver does not support seeds", c.config.Driver.Name())))
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+		return 1
+	}
+
+	if len(plan) == 0 {
+		fmt.Fprintln(c.output, "No pending seeds to run")
+		return 0
+	}
+
+	fmt.Fprintf(c.output, "The following %d seed(s) will be run:\n\n", len(plan))
+
+	w := tabwriter.NewWriter(c.output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tEnvironments")
+	for _, s := range plan {
+		fmt.Fprintf(w, "%s\t%s\n", s.Name(), formatSeedEnvironments(seedEnvironments(s)))
+	}
+	w.Flush()
+
+	fmt.Fprintln(c.output, "")
+
+	if !autoConfirm {
+		confirmed, err := c.confirm(ctx, ConfirmRequest{Message: c.confirmMessage()})
+		if err != nil && c.interrupted() {
+			fmt.Fprintln(c.output, "Seeding cancelled")
+			return exitCodeInterrupted
+		}
+		if err != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to read input: %v", err)))
+			return 1
+		}
+
+		if !confirmed {
+			fmt.Fprintln(c.output, "Seeding cancelled")
+			return 0
+		}
+	}
+
+	// Run seeds using iterator to show progress, stopping before the next one when interrupted
+	fmt.Fprintln(c.output, "")
+	done := 0
+	for result := range c.runner.SeedIterator(ctx, c.seeds, opts...) {
+		if result.Error != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", result.Error)))
+			if c.interrupted() {
+				return exitCodeInterrupted
+			}
+			return 1
+		}
+
+		done++
+		fmt.Fprintf(c.output, "== %s: seeding (%s)\n\n", result.Seed.Name(), c.cliOutput.duration(result.Duration))
+
+		if c.interrupted() {
+			fmt.Fprintf(c.output, "Interrupted: ran %d of %d seed(s)\n", done, len(plan))
+			return exitCodeInterrupted
+		}
+	}
+
+	fmt.Fprintln(c.output, "")
+	fmt.Fprintf(c.output, "Successfully ran %d seed(s)\n", done)
+	return 0
+}
+
+// cliSeedList displays the status of every seed
+func (c *CLI) cliSeedList(ctx context.Context) int {
+	statuses, err := c.runner.GetSeedStatuses(ctx, c.seeds)
+	if errors.Is(err, ErrSeedNotSupported) {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: the %s driver does not support seeds", c.config.Driver.Name())))
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to get seed statuses: %v", err)))
+		return 1
+	}
+
+	w := tabwriter.NewWriter(c.output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Status\tName\tEnvironments\tApplied At")
+	for _, status := range statuses {
+		appliedAt := ""
+		if !status.Record.AppliedAt.IsZero() {
+			appliedAt = c.cliOutput.timestamp(status.Record.AppliedAt)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.State, status.Record.Name, formatSeedEnvironments(status.Environments), appliedAt)
+	}
+	w.Flush()
+
+	return 0
+}
+
+// formatSeedEnvironments returns the environments a seed is restricted to, or "all"
+func formatSeedEnvironments(environments []string) string {
+	if len(environments) == 0 {
+		return "all"
+	}
+	return strings.Join(environments, ",")
+}
+
+// cliSeedHelp displays help for the seed command
+func (c *CLI) cliSeedHelp() {
+	help := `Usage: seed [options] [name...]
+
+Run the seeds that never ran, in name order. Seeds insert reference data or
+fixtures separately from the migrations and are tracked in their own table.
+Seeds restricted to other environments (env= annotation in SQL seeds, or the
+Environments method of Go seeds) are skipped.
+
+Options:
+  --rerun        Run seeds that already ran again, they must be idempotent
+  --list         List the seeds and whether they ran, without running them
+  -y, --yes      Skip confirmation prompt
+  -h, --help     Show this help message
+
+Arguments:
+  name           Only run the seeds with these names
+
+Examples:
+  seed                     Run all pending seeds
+  seed --list              Show which seeds ran
+  seed --rerun 20240101120000_countries
+                           Run the countries seed again
+`
+	fmt.Fprint(c.output, help)
+}
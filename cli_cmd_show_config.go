@@ -1,13 +1,15 @@
 package amigo
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"strings"
 	"text/tabwriter"
 )
 
 // cliShowConfig displays the current configuration
-func (c *CLI) cliShowConfig(args []string) int {
+func (c *CLI) cliShowConfig(ctx context.Context, args []string) int {
 	// Show help if requested (before parsing to avoid flag.Parse handling it)
 	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
 		c.cliShowConfigHelp()
@@ -32,12 +34,26 @@ func (c *CLI) cliShowConfig(args []string) int {
 	fmt.Fprintln(w, "Setting\tValue")
 	fmt.Fprintf(w, "Driver\t%+v\n", driverName)
 	fmt.Fprintf(w, "DatabaseConnected\t%v\n", c.config.DB != nil)
+	if versioner, ok := c.config.Driver.(SchemaLayoutVersioner); ok && c.config.DB != nil {
+		current, latest, err := versioner.SchemaLayoutVersion(ctx, c.config.DB)
+		if err != nil {
+			fmt.Fprintf(w, "SchemaLayoutVersion\tunknown (%v)\n", err)
+		} else {
+			fmt.Fprintf(w, "SchemaLayoutVersion\t%d (latest %d)\n", current, latest)
+		}
+	}
 	fmt.Fprintf(w, "Migrations\t%d\n", len(c.migrations))
 	fmt.Fprintf(w, "SQLFileUpAnnotation\t%s\n", c.config.SQLFileUpAnnotation)
 	fmt.Fprintf(w, "SQLFileDownAnnotation\t%s\n", c.config.SQLFileDownAnnotation)
 	fmt.Fprintf(w, "SplitStatements\t%v\n", c.config.SplitStatements)
+	fmt.Fprintf(w, "Environment\t%s\n", c.config.Environment)
+	fmt.Fprintf(w, "AppVersion\t%s\n", c.config.AppVersion)
+	fmt.Fprintf(w, "SchemaFile\t%s\n", c.cliOutput.path(c.config.SchemaFile))
+	fmt.Fprintf(w, "AmigoVersion\t%s\n", Version())
 	fmt.Fprintf(w, "CLI.Directory\t%s\n", c.cliOutput.path(c.directory))
 	fmt.Fprintf(w, "CLI.DefaultTransactional\t%v\n", c.defaultTransactional)
+	fmt.Fprintf(w, "CLI.ProtectedEnvironments\t%s\n", strings.Join(c.protectedEnvs, ","))
+	fmt.Fprintf(w, "CLI.ProtectedEnvironment\t%v\n", c.isProtectedEnvironment())
 
 	return 0
 }
@@ -47,7 +63,8 @@ func (c *CLI) cliShowConfigHelp() {
 	help := `Usage: show-config [options]
 
 Display the current migration configuration including directory, annotations,
-and loaded migrations count.
+loaded migrations count and the layout version of amigo's own tables. The
+layout is upgraded automatically by commands that write to the database.
 
 Options:
   -h, --help    Show this help message
@@ -0,0 +1,300 @@
+package amigo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// squashSection is the up section of a migration combined by the squash command
+type squashSection struct {
+	Version string
+	Up      string
+}
+
+// cliSquash combines the SQL migrations up to a version into a single migration
+func (c *CLI) cliSquash(ctx context.Context, args []string) int {
+	// Show help if requested (before parsing to avoid flag.Parse handling it)
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		c.cliSquashHelp()
+		return 0
+	}
+
+	fs := flag.NewFlagSet("squash", flag.ContinueOnError)
+	fs.SetOutput(c.errorOutput)
+
+	var upTo, name string
+	fs.StringVar(&upTo, "up-to", "", "Version of the newest migration to squash")
+	fs.StringVar(&name, "name", "squashed", "Name of the squashed migration")
+
+	var fromSchema bool
+	fs.BoolVar(&fromSchema, "schema", false, "Dump the schema of the database instead of combining the up sections")
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if upTo == "" {
+		fmt.Fprintln(c.errorOutput, "Error: --up-to is required")
+		fmt.Fprintln(c.errorOutput, "")
+		c.cliSquashHelp()
+		return 1
+	}
+
+	upToDate, err := parseVersionToDate(upTo)
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: invalid --up-to version: %v", err)))
+		return 1
+	}
+
+	files, migrations, problems, err := c.readSquashRange(upToDate)
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+		return 1
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: cannot squash the migrations up to %d:", upToDate)))
+		for _, problem := range problems {
+			fmt.Fprintf(c.errorOutput, "  %s\n", problem)
+		}
+		return 1
+	}
+
+	if !slices.ContainsFunc(migrations, func(m SQLMigration) bool { return m.date == upToDate }) {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: no SQL migration with version %d", upToDate)))
+		return 1
+	}
+
+	var schema string
+	if fromSchema {
+		schema, err = c.squashSchema(ctx, upToDate, migrations)
+		if errors.Is(err, ErrSchemaDumpNotSupported) {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: the %s driver does not support schema dumps", c.config.Driver.Name())))
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+			return 1
+		}
+	}
+
+	content, err := c.generateSquashedMigration(upToDate, migrations, schema)
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+		return 1
+	}
+
+	// The squashed migration has the version of the newest one it replaces, which may share its name
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to delete %s: %v", file, err)))
+			return 1
+		}
+	}
+
+	path := filepath.Join(c.directory, fmt.Sprintf("%d_%s.sql", upToDate, name))
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to write file: %v", err)))
+		return 1
+	}
+
+	fmt.Fprintf(c.output, "Squashed %d migration(s) into: %s\n", len(migrations), c.cliOutput.path(path))
+
+	if err := c.generateMigrationsList(); err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to regenerate migrations.go: %v", err)))
+		return 1
+	}
+
+	fmt.Fprintf(c.output, "Updated migrations list: %s\n", c.cliOutput.path(c.directory+"/migrations.go"))
+
+	return 0
+}
+
+// readSquashRange reads the versioned migrations of the directory up to a version. It returns their files and
+// the SQL migrations, oldest first, or the reasons they cannot be squashed: Go migrations, and SQL migrations
+// restricted to environments or tags, which would then run everywhere.
+func (c *CLI) readSquashRange(upTo int64) (files []string, migrations []SQLMigration, problems []string, err error) {
+	entries, err := os.ReadDir(c.directory)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		fileName := entry.Name()
+		ext := filepath.Ext(fileName)
+		if entry.IsDir() || fileName == "migrations.go" || (ext != ".sql" && ext != ".go") {
+			continue
+		}
+		if _, repeatable := parseRepeatableFileName(fileName); repeatable {
+			continue
+		}
+
+		version, _, _ := strings.Cut(fileName, "_")
+		date, err := strconv.ParseInt(version, 10, 64)
+		if err != nil || date > upTo {
+			continue
+		}
+
+		if ext == ".go" {
+			problems = append(problems, fmt.Sprintf("%s is a Go migration, only SQL migrations can be squashed", fileName))
+			continue
+		}
+
+		path := filepath.Join(c.directory, fileName)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+
+		migration, err := parseSQLFile(content, c.config)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to parse %s: %w", fileName, err)
+		}
+		migration.name, migration.date, err = parseFileName(fileName)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if len(migration.environments) > 0 || len(migration.tags) > 0 {
+			problems = append(problems, fmt.Sprintf("%s is restricted with env= or tags=, it would run everywhere once squashed", fileName))
+			continue
+		}
+
+		files = append(files, path)
+		migrations = append(migrations, migration)
+	}
+
+	if len(problems) > 0 {
+		return nil, nil, problems, nil
+	}
+
+	// Keep the order migrations were applied in, including their dependencies
+	list := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		list = append(list, m)
+	}
+	sorted, err := sortByDependencies(list, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	migrations = migrations[:0]
+	for _, m := range sorted {
+		migrations = append(migrations, m.(SQLMigration))
+	}
+
+	return files, migrations, nil, nil
+}
+
+// squashSchema returns the schema of the database to use as the squashed migration, which must have exactly the
+// migrations to squash applied
+func (c *CLI) squashSchema(ctx context.Context, upTo int64, migrations []SQLMigration) (string, error) {
+	applied, err := c.runner.getAppliedMigrationsReadOnly(ctx, c.migrations)
+	if err != nil {
+		return "", err
+	}
+
+	appliedDates := appliedDateSet(applied)
+	for _, m := range migrations {
+		if _, ok := appliedDates[m.date]; !ok {
+			return "", fmt.Errorf("migration %d_%s is not applied, the schema must be dumped from a database migrated up to %d", m.date, m.name, upTo)
+		}
+	}
+	for _, record := range applied {
+		if record.Date > upTo {
+			return "", fmt.Errorf("migration %d_%s is applied, the schema must be dumped from a database migrated up to %d only", record.Date, record.Name, upTo)
+		}
+	}
+
+	return c.runner.dumpSchemaDDL(ctx)
+}
+
+// generateSquashedMigration returns the content of the migration replacing the given ones, see Squashed: their
+// up sections, or the schema when not empty. It runs outside a transaction when one of them does, and cannot be
+// reverted.
+func (c *CLI) generateSquashedMigration(upTo int64, migrations []SQLMigration, schema string) (string, error) {
+	var replaced []string
+	var sections []squashSection
+	transactional := true
+	for _, m := range migrations {
+		version := fmt.Sprintf("%d_%s", m.date, m.name)
+
+		// A squashed migration squashed again keeps replacing the migrations it replaced
+		for _, date := range m.squashes {
+			if date != m.date {
+				replaced = append(replaced, strconv.FormatInt(date, 10))
+			}
+		}
+		replaced = append(replaced, version)
+
+		sections = append(sections, squashSection{Version: version, Up: strings.TrimSpace(m.up)})
+		transactional = transactional && m.txUp
+	}
+
+	if schema != "" {
+		sections = []squashSection{{Version: fmt.Sprintf("schema of the database at %d", upTo), Up: strings.TrimSpace(schema)}}
+		transactional = true
+	}
+
+	tmpl, err := template.New("squash").Parse(sqlSquashTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]interface{}{
+		"UpAnnotation":   c.config.SQLFileUpAnnotation,
+		"DownAnnotation": c.config.SQLFileDownAnnotation,
+		"Transactional":  transactional,
+		"Count":          len(migrations),
+		"UpTo":           upTo,
+		"Replaced":       replaced,
+		"Migrations":     sections,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// cliSquashHelp displays help for the squash command
+func (c *CLI) cliSquashHelp() {
+	help := `Usage: squash --up-to <version> [options]
+
+Combine the up sections of the SQL migrations up to a version into a single
+migration with that version, delete the originals and regenerate
+migrations.go. Fresh databases run the squashed migration; databases where the
+migration with that version is applied consider it applied already.
+
+With --schema, the squashed migration is the schema dumped from the database
+instead, which must have exactly the migrations to squash applied.
+
+Go migrations, and SQL migrations restricted with env= or tags=, cannot be
+squashed: they are reported and nothing is changed. The squashed migration is
+irreversible, and runs outside a transaction if one of the originals does.
+
+Options:
+  --up-to string   Version of the newest migration to squash (required)
+  --name string    Name of the squashed migration (default: squashed)
+  --schema         Dump the schema of the database instead of combining the
+                   up sections (the driver must support schema dumps)
+  -h, --help       Show this help message
+
+Examples:
+  squash --up-to 20240101120000
+  squash --up-to 20240101120000 --name baseline_2023
+  squash --up-to 20240101120000 --schema
+`
+	fmt.Fprint(c.output, help)
+}
@@ -8,7 +8,7 @@ import (
 )
 
 // cliStatus displays the status of all migrations
-func (c *CLI) cliStatus(args []string) int {
+func (c *CLI) cliStatus(ctx context.Context, args []string) int {
 	// Show help if requested (before parsing to avoid flag.Parse handling it)
 	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
 		c.cliStatusHelp()
@@ -18,75 +18,175 @@ func (c *CLI) cliStatus(args []string) int {
 	fs := flag.NewFlagSet("status", flag.ContinueOnError)
 	fs.SetOutput(c.errorOutput)
 
+	var verbose bool
+	fs.BoolVar(&verbose, "verbose", false, "Show who applied each migration, where and how long it took")
+	fs.BoolVar(&verbose, "v", false, "Show who applied each migration, where and how long it took (shorthand)")
+	selection := c.addSelectionFlags(fs)
+
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
 
-	ctx := context.Background()
-
 	// Get migration statuses
-	statuses, err := c.runner.GetMigrationsStatuses(ctx, c.migrations)
+	statuses, err := c.runner.GetMigrationsStatuses(ctx, c.migrations, selection.options()...)
 	if err != nil {
 		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to get migration statuses: %v", err)))
 		return 1
 	}
 
-	if len(statuses) == 0 {
+	repeatables, err := c.runner.GetRepeatableStatuses(ctx, c.migrations, selection.options()...)
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to get repeatable migration statuses: %v", err)))
+		return 1
+	}
+
+	if len(statuses) == 0 && len(repeatables) == 0 {
 		fmt.Fprintln(c.output, "No migrations found")
 		return 0
 	}
 
-	// Count applied and pending
+	// Count applied, pending and skipped
 	appliedCount := 0
 	pendingCount := 0
+	skippedCount := 0
 	for _, status := range statuses {
-		if status.Applied {
+		switch {
+		case status.Applied:
 			appliedCount++
-		} else {
+		case status.Skipped:
+			skippedCount++
+		default:
 			pendingCount++
 		}
 	}
 
 	// Display summary
-	fmt.Fprintf(c.output, "Migration Status: %d applied, %d pending\n\n", appliedCount, pendingCount)
+	summary := fmt.Sprintf("Migration Status: %d applied, %d pending", appliedCount, pendingCount)
+	if skippedCount > 0 {
+		summary += fmt.Sprintf(", %d skipped", skippedCount)
+	}
+	fmt.Fprintf(c.output, "%s\n\n", summary)
 
 	// Display migrations table
 	w := tabwriter.NewWriter(c.output, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "Status\tDate\tName\tApplied At")
+	if verbose {
+		fmt.Fprintln(w, "Status\tDate\tName\tApplied At\tDuration\tKind\tEnvironment\tApplied By\tApp Version\tAmigo Version")
+	} else {
+		fmt.Fprintln(w, "Status\tDate\tName\tApplied At")
+	}
 
 	for _, status := range statuses {
 		statusStr := "pending"
 		appliedAt := ""
 
+		if status.Skipped {
+			statusStr = "skipped"
+		}
 		if status.Applied {
 			statusStr = "applied"
 			appliedAt = c.cliOutput.timestamp(status.Migration.AppliedAt)
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s",
 			statusStr,
 			c.cliOutput.date(status.Migration.Date),
 			status.Migration.Name,
 			appliedAt,
 		)
+
+		if verbose {
+			var duration, appliedBy string
+			if status.Applied {
+				duration = c.cliOutput.duration(status.Migration.Duration)
+				appliedBy = status.Migration.OSUser
+				if status.Migration.Hostname != "" {
+					appliedBy += "@" + status.Migration.Hostname
+				}
+			}
+
+			fmt.Fprintf(w, "\t%s\t%s\t%s\t%s\t%s\t%s",
+				duration,
+				status.Migration.Kind,
+				status.Migration.Environment,
+				appliedBy,
+				status.Migration.AppVersion,
+				status.Migration.AmigoVersion,
+			)
+		}
+
+		fmt.Fprintln(w, "")
 	}
 
 	w.Flush()
 
+	if len(repeatables) > 0 {
+		c.printRepeatableStatuses(repeatables)
+	}
+
 	return 0
 }
 
+// printRepeatableStatuses displays the repeatable migrations section of the status command
+func (c *CLI) printRepeatableStatuses(repeatables []RepeatableStatus) {
+	upToDate := 0
+	toRun := 0
+	for _, status := range repeatables {
+		switch status.State {
+		case RepeatableStateUpToDate:
+			upToDate++
+		case RepeatableStatePending, RepeatableStateChanged:
+			toRun++
+		}
+	}
+
+	summary := fmt.Sprintf("Repeatable Migrations: %d up-to-date, %d to run", upToDate, toRun)
+	if skipped := len(repeatables) - upToDate - toRun; skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	fmt.Fprintf(c.output, "\n%s\n\n", summary)
+
+	w := tabwriter.NewWriter(c.output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Status\tName\tApplied At\tChecksum")
+	for _, status := range repeatables {
+		appliedAt := ""
+		if !status.Migration.AppliedAt.IsZero() {
+			appliedAt = c.cliOutput.timestamp(status.Migration.AppliedAt)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.State, status.Migration.Name, appliedAt, shortChecksum(status.Checksum))
+	}
+	w.Flush()
+}
+
+// shortChecksum returns the first characters of a checksum, enough to tell versions apart
+func shortChecksum(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
+
 // cliStatusHelp displays help for the status command
 func (c *CLI) cliStatusHelp() {
 	help := `Usage: status [options]
 
-Show the status of all migrations.
+Show the status of all migrations. Repeatable migrations are listed in their
+own section: pending (never ran), changed (ran with another checksum) or
+up-to-date.
 
 Options:
-  -h, --help    Show this help message
+  -v, --verbose    Show duration, kind, environment, user, host and versions
+                   recorded for applied migrations
+  --env string     Environment selecting the migrations, pending migrations
+                   of other environments are shown as skipped
+                   (default: the configured environment)
+  --tags string    Comma separated tags selecting the migrations, pending
+                   migrations with other tags are shown as skipped
+  -h, --help       Show this help message
 
 Examples:
-  status        Display migration status
+  status           Display migration status
+  status -v        Display migration status with audit columns
 `
 	fmt.Fprint(c.output, help)
 }
@@ -0,0 +1,134 @@
+package amigo
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// cliTestRollback applies the pending migrations one by one, checking that the down of each one reverts its up
+func (c *CLI) cliTestRollback(ctx context.Context, args []string) int {
+	// Show help if requested (before parsing to avoid flag.Parse handling it)
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		c.cliTestRollbackHelp()
+		return 0
+	}
+
+	fs := flag.NewFlagSet("test-rollback", flag.ContinueOnError)
+	fs.SetOutput(c.errorOutput)
+
+	var autoConfirm, override bool
+	fs.BoolVar(&autoConfirm, "yes", false, "Skip confirmation prompt")
+	fs.BoolVar(&autoConfirm, "y", false, "Skip confirmation prompt (shorthand)")
+	fs.BoolVar(&override, overrideProtectionFlag, false, "Allow test-rollback in a protected environment")
+	selection := c.addSelectionFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if !c.guardProtectedEnvironment("test-rollback", override) {
+		return 1
+	}
+
+	opts := selection.options()
+
+	plan, err := c.runner.PlanUp(ctx, c.migrations, opts...)
+	if err != nil {
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
+		return 1
+	}
+
+	if len(plan) == 0 {
+		fmt.Fprintln(c.output, "No pending migrations to test")
+		return 0
+	}
+
+	fmt.Fprintf(c.output, "The following %d migration(s) will be applied, reverted and applied again:\n\n", len(plan))
+	for _, m := range plan {
+		fmt.Fprintf(c.output, "  %s  %s\n", c.cliOutput.date(m.Date()), m.Name())
+	}
+	fmt.Fprintln(c.output, "")
+
+	if !autoConfirm {
+		confirmed, err := c.confirm(ctx, ConfirmRequest{Message: c.confirmMessage()})
+		if err != nil && c.interrupted() {
+			fmt.Fprintln(c.output, "Rollback test cancelled")
+			return exitCodeInterrupted
+		}
+		if err != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to read input: %v", err)))
+			return 1
+		}
+
+		if !confirmed {
+			fmt.Fprintln(c.output, "Rollback test cancelled")
+			return 0
+		}
+	}
+
+	fmt.Fprintln(c.output, "")
+	tested := 0
+	for result := range c.runner.RoundtripIterator(ctx, c.migrations, opts...) {
+		if errors.Is(result.Error, ErrRoundtripMismatch) {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf(
+				"Error: the down of %s does not revert its up, the schema differs after the down:", result.Migration.Name())))
+			fmt.Fprintln(c.errorOutput, "")
+			for _, line := range strings.Split(strings.TrimSuffix(result.Diff, "\n"), "\n") {
+				fmt.Fprintf(c.errorOutput, "  %s\n", line)
+			}
+			return 1
+		}
+		if result.Error != nil {
+			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", result.Error)))
+			if c.interrupted() {
+				return exitCodeInterrupted
+			}
+			return 1
+		}
+
+		tested++
+		if result.Reverted {
+			fmt.Fprintf(c.output, "== %s: up, down, up\n", result.Migration.Name())
+		} else {
+			fmt.Fprintf(c.output, "== %s: up only (irreversible or repeatable)\n", result.Migration.Name())
+		}
+	}
+
+	fmt.Fprintln(c.output, "")
+	fmt.Fprintf(c.output, "Successfully tested %d migration(s)\n", tested)
+	return 0
+}
+
+// cliTestRollbackHelp displays help for the test-rollback command
+func (c *CLI) cliTestRollbackHelp() {
+	help := `Usage: test-rollback [options]
+
+Check that every pending migration can be reverted: for each one, in the order
+up runs them, dump the schema, apply the migration, revert it, compare the
+schema with the dump and apply the migration again. Stop at the first
+migration whose down leaves the schema different, printing the difference
+(lines starting with - were there before the up, lines starting with + remain
+after the down).
+
+Irreversible and repeatable migrations are only applied. The migrations stay
+applied: run it against a disposable database, e.g. in CI. The driver must
+support schema dumps.
+
+Options:
+  --env string   Select the migrations of this environment
+                 (default: the configured environment)
+  --tags string  Comma separated tags: migrations annotated with tags= run
+                 only if one of their tags is selected (default: all run)
+  -y, --yes      Skip confirmation prompt
+  --i-know-what-im-doing
+                 Allow test-rollback in a protected environment
+  -h, --help     Show this help message
+
+Examples:
+  test-rollback --yes
+`
+	fmt.Fprint(c.output, help)
+}
@@ -1,17 +1,14 @@
 package amigo
 
 import (
-	"bufio"
 	"context"
 	"flag"
 	"fmt"
-	"os"
-	"strings"
 	"text/tabwriter"
 )
 
 // cliUp runs pending migrations
-func (c *CLI) cliUp(args []string) int {
+func (c *CLI) cliUp(ctx context.Context, args []string) int {
 	// Show help if requested (before parsing to avoid flag.Parse handling it)
 	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
 		c.cliUpHelp()
@@ -26,39 +23,35 @@ func (c *CLI) cliUp(args []string) int {
 	fs.IntVar(&steps, "steps", -1, "Number of migrations to run (default: all)")
 	fs.BoolVar(&autoConfirm, "yes", false, "Skip confirmation prompt")
 	fs.BoolVar(&autoConfirm, "y", false, "Skip confirmation prompt (shorthand)")
+	selection := c.addSelectionFlags(fs)
 
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
 
-	ctx := context.Background()
+	// Build options
+	opts := selection.options()
+	if steps >= 0 {
+		opts = append(opts, RunnerUpOptionSteps(steps))
+	}
 
-	// Get migration statuses to show what will be applied
-	statuses, err := c.runner.GetMigrationsStatuses(ctx, c.migrations)
+	// Plan with the runner so the order shown is the order migrations are applied in
+	plan, err := c.runner.PlanUp(ctx, c.migrations, opts...)
 	if err != nil {
-		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to get migration statuses: %v", err)))
+		fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", err)))
 		return 1
 	}
 
-	// Filter pending migrations
-	var pendingMigrations []MigrationStatus
-	for _, status := range statuses {
-		if !status.Applied {
-			pendingMigrations = append(pendingMigrations, status)
-		}
+	migrationsToApply := make([]MigrationStatus, 0, len(plan))
+	for _, m := range plan {
+		migrationsToApply = append(migrationsToApply, MigrationStatus{Migration: MigrationRecord{Date: m.Date(), Name: m.Name()}})
 	}
 
-	if len(pendingMigrations) == 0 {
+	if len(migrationsToApply) == 0 {
 		fmt.Fprintln(c.output, "No pending migrations to apply")
 		return 0
 	}
 
-	// Determine how many migrations will be applied
-	migrationsToApply := pendingMigrations
-	if steps > 0 && steps < len(pendingMigrations) {
-		migrationsToApply = pendingMigrations[:steps]
-	}
-
 	// Display migrations to apply
 	fmt.Fprintf(c.output, "The following %d migration(s) will be applied:\n\n", len(migrationsToApply))
 
@@ -73,42 +66,59 @@ func (c *CLI) cliUp(args []string) int {
 
 	// Prompt for confirmation unless --yes flag is set
 	if !autoConfirm {
-		fmt.Fprint(c.output, "Do you want to continue? (yes/no): ")
-		reader := bufio.NewReader(os.Stdin)
-		response, err := reader.ReadString('\n')
+		confirmed, err := c.confirm(ctx, ConfirmRequest{Message: c.confirmMessage()})
+		if err != nil && c.interrupted() {
+			fmt.Fprintln(c.output, "Migration cancelled")
+			return exitCodeInterrupted
+		}
 		if err != nil {
 			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: failed to read input: %v", err)))
 			return 1
 		}
 
-		response = strings.TrimSpace(strings.ToLower(response))
-		if response != "yes" && response != "y" {
+		if !confirmed {
 			fmt.Fprintln(c.output, "Migration cancelled")
 			return 0
 		}
 	}
 
-	// Build options
-	var opts []RunnerUpOptsFunc
-	if steps >= 0 {
-		opts = append(opts, RunnerUpOptionSteps(steps))
-	}
-
-	// Run migrations using iterator to show progress
+	// Run migrations using iterator to show progress, stopping before the next one when interrupted
 	fmt.Fprintln(c.output, "")
-	migrationCount := 0
-	for result := range c.runner.UpIterator(ctx, c.migrations, opts...) {
+	var done []Migration
+	for result := range c.runner.UpIterator(ctx, c.migrations, append(opts, RunnerUpOptionStatementProgress())...) {
+		if result.Progress != nil {
+			c.printStatementProgress(result.Progress)
+			continue
+		}
+
 		if result.Error != nil {
 			fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf("Error: %v", result.Error)))
+			c.printStatementError(result.Error)
+			if c.interrupted() {
+				c.printInterruptSummary("applied", done, migrationsToApply)
+				return exitCodeInterrupted
+			}
 			return 1
 		}
 
-		migrationCount++
+		done = append(done, result.Migration)
 		fmt.Fprintf(c.output, "== %s: migrating (%s)\n\n", result.Migration.Name(), c.cliOutput.duration(result.Duration))
+
+		if c.interrupted() {
+			break
+		}
+	}
+
+	if c.interrupted() && len(done) < len(migrationsToApply) {
+		c.printInterruptSummary("applied", done, migrationsToApply)
+		return exitCodeInterrupted
 	}
 
 	fmt.Fprintln(c.output, "")
-	fmt.Fprintf(c.output, "Successfully applied %d migration(s)\n", migrationCount)
+	fmt.Fprintf(c.output, "Successfully applied %d migration(s)\n", len(done))
+	if c.config.SchemaFile != "" {
+		fmt.Fprintf(c.output, "Updated schema file: %s\n", c.cliOutput.path(c.config.SchemaFile))
+	}
 	return 0
 }
 
@@ -116,10 +126,18 @@ func (c *CLI) cliUp(args []string) int {
 func (c *CLI) cliUpHelp() {
 	help := `Usage: up [options]
 
-Run pending migrations.
+Run pending migrations. Repeatable migrations (R_<name>.sql) that never ran
+or changed since their last run are run after all the versioned ones. A
+migration always runs after the migrations it depends on (depends= annotation
+in SQL migrations, or the DependsOn method of Go migrations).
 
 Options:
   --steps int    Number of migrations to run (default: all pending migrations)
+  --env string   Select the migrations of this environment: migrations
+                 annotated with env= for other environments are skipped
+                 (default: the configured environment)
+  --tags string  Comma separated tags: migrations annotated with tags= run
+                 only if one of their tags is selected (default: all run)
   -y, --yes      Skip confirmation prompt
   -h, --help     Show this help message
 
@@ -128,6 +146,7 @@ Examples:
   up --steps=1    Run only the next pending migration
   up --steps=3    Run the next 3 pending migrations
   up --yes        Run all pending migrations without confirmation
+  up --tags=eu    Run untagged migrations and those tagged eu
 `
 	fmt.Fprint(c.output, help)
 }
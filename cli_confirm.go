@@ -0,0 +1,142 @@
+package amigo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alexisvisco/amigo/pkg/termcolor"
+)
+
+// NonInteractiveEnv is the environment variable that enables the non-interactive mode, like --non-interactive
+const NonInteractiveEnv = "AMIGO_NON_INTERACTIVE"
+
+// errConfirmationNameRequired is returned in non-interactive mode when a confirmation requires a name that was
+// not given with --confirm-name
+var errConfirmationNameRequired = errors.New("confirmation name required in non-interactive mode")
+
+// ConfirmRequest describes a confirmation asked to the operator before running a command
+type ConfirmRequest struct {
+	// Message is the question displayed to the operator
+	Message string
+
+	// Expected, when not empty, is the exact value the operator must type to confirm instead of answering yes.
+	// It is used for destructive operations, see CLIConfig.ConfirmationName.
+	Expected string
+}
+
+// Confirmer asks the operator to confirm an operation.
+// Confirm must return as soon as ctx is done.
+type Confirmer interface {
+	Confirm(ctx context.Context, req ConfirmRequest) (bool, error)
+}
+
+// promptConfirmer is the default Confirmer, reading answers line by line from an input
+type promptConfirmer struct {
+	input  *bufio.Reader
+	output io.Writer
+
+	// answers receives the lines read by a single goroutine started by the first Confirm, so that a Confirm
+	// abandoned when its context is done leaves the next line to the next Confirm. It is closed on the first
+	// read error, kept in readErr.
+	readOnce sync.Once
+	answers  chan promptAnswer
+	readErr  error
+}
+
+// promptAnswer is a line read by promptConfirmer
+type promptAnswer struct {
+	response string
+	err      error
+}
+
+// NewPromptConfirmer returns a Confirmer that prints the question on out and reads the answer from in.
+// Answers are read by a goroutine running until in returns an error, such as io.EOF.
+func NewPromptConfirmer(in io.Reader, out io.Writer) Confirmer {
+	return &promptConfirmer{
+		input:   bufio.NewReader(in),
+		output:  out,
+		answers: make(chan promptAnswer),
+	}
+}
+
+// read sends the lines of the input to answers until the input fails
+func (p *promptConfirmer) read() {
+	for {
+		response, err := p.input.ReadString('\n')
+		p.answers <- promptAnswer{response: response, err: err}
+		if err != nil {
+			p.readErr = err
+			close(p.answers)
+			return
+		}
+	}
+}
+
+func (p *promptConfirmer) Confirm(ctx context.Context, req ConfirmRequest) (bool, error) {
+	if req.Expected != "" {
+		fmt.Fprintf(p.output, "%s Type '%s' to confirm: ", req.Message, req.Expected)
+	} else {
+		fmt.Fprintf(p.output, "%s (yes/no): ", req.Message)
+	}
+
+	p.readOnce.Do(func() { go p.read() })
+
+	select {
+	case a, ok := <-p.answers:
+		if !ok {
+			return false, p.readErr
+		}
+		if a.err != nil && (!errors.Is(a.err, io.EOF) || a.response == "") {
+			return false, a.err
+		}
+		response := strings.TrimSpace(a.response)
+		if req.Expected != "" {
+			return response == req.Expected, nil
+		}
+		response = strings.ToLower(response)
+		return response == "yes" || response == "y", nil
+	case <-ctx.Done():
+		fmt.Fprintln(p.output, "")
+		return false, ctx.Err()
+	}
+}
+
+// nonInteractiveFromEnv reports whether NonInteractiveEnv is set to a true value
+func nonInteractiveFromEnv() bool {
+	enabled, err := strconv.ParseBool(os.Getenv(NonInteractiveEnv))
+	return err == nil && enabled
+}
+
+// confirm asks the operator for confirmation through the configured Confirmer.
+// In non-interactive mode, confirmation is granted without asking when the input is not a terminal, or when
+// --confirm-name matches the expected name for confirmations requiring one.
+// The question is abandoned as soon as the command is interrupted.
+func (c *CLI) confirm(ctx context.Context, req ConfirmRequest) (bool, error) {
+	if c.nonInteractive && !termcolor.IsTerminal(c.input) {
+		if req.Expected != "" && c.confirmedName != req.Expected {
+			return false, fmt.Errorf("%w: pass --confirm-name=%s", errConfirmationNameRequired, req.Expected)
+		}
+		fmt.Fprintln(c.output, "Non-interactive mode: confirmation granted")
+		return true, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-c.interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return c.confirmer.Confirm(ctx, req)
+}
@@ -0,0 +1,115 @@
+package amigo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_promptConfirmer(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		req   ConfirmRequest
+		want  bool
+	}{
+		{name: "yes", input: "yes\n", req: ConfirmRequest{Message: "Continue?"}, want: true},
+		{name: "short yes with spaces", input: "  Y \n", req: ConfirmRequest{Message: "Continue?"}, want: true},
+		{name: "no", input: "no\n", req: ConfirmRequest{Message: "Continue?"}, want: false},
+		{name: "yes without trailing newline", input: "yes", req: ConfirmRequest{Message: "Continue?"}, want: true},
+		{name: "expected name typed", input: "mydb\n", req: ConfirmRequest{Message: "Sure?", Expected: "mydb"}, want: true},
+		{name: "yes is not the expected name", input: "yes\n", req: ConfirmRequest{Message: "Sure?", Expected: "mydb"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			confirmer := NewPromptConfirmer(strings.NewReader(tt.input), &out)
+
+			got, err := confirmer.Confirm(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("confirmed: got %v, want %v", got, tt.want)
+			}
+			if !strings.HasPrefix(out.String(), tt.req.Message) {
+				t.Errorf("prompt %q does not start with the message %q", out.String(), tt.req.Message)
+			}
+		})
+	}
+}
+
+func Test_promptConfirmer_emptyInput(t *testing.T) {
+	confirmer := NewPromptConfirmer(strings.NewReader(""), &bytes.Buffer{})
+
+	if _, err := confirmer.Confirm(context.Background(), ConfirmRequest{Message: "Continue?"}); err == nil {
+		t.Fatal("expected an error when the input is closed without an answer")
+	}
+}
+
+func Test_promptConfirmer_canceled(t *testing.T) {
+	in, answer := io.Pipe()
+	confirmer := NewPromptConfirmer(in, io.Discard)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := confirmer.Confirm(canceled, ConfirmRequest{Message: "Continue?"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Confirm() error = %v, want %v", err, context.Canceled)
+	}
+
+	// The answer typed after the cancellation goes to the next question
+	go answer.Write([]byte("yes\n"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, err := confirmer.Confirm(ctx, ConfirmRequest{Message: "Continue?"})
+	if err != nil {
+		t.Fatalf("Confirm() after a cancellation error = %v", err)
+	}
+	if !got {
+		t.Error("Confirm() after a cancellation = false, want the answer yes")
+	}
+
+	answer.Close()
+	if _, err := confirmer.Confirm(ctx, ConfirmRequest{Message: "Continue?"}); !errors.Is(err, io.EOF) {
+		t.Errorf("Confirm() on a closed input error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestCLI_confirm_nonInteractive(t *testing.T) {
+	tests := []struct {
+		name          string
+		confirmedName string
+		req           ConfirmRequest
+		want          bool
+		wantErr       error
+	}{
+		{name: "yes", req: ConfirmRequest{Message: "Continue?"}, want: true},
+		{name: "name given", confirmedName: "mydb", req: ConfirmRequest{Message: "Sure?", Expected: "mydb"}, want: true},
+		{name: "name missing", req: ConfirmRequest{Message: "Sure?", Expected: "mydb"}, wantErr: errConfirmationNameRequired},
+		{name: "other name", confirmedName: "otherdb", req: ConfirmRequest{Message: "Sure?", Expected: "mydb"}, wantErr: errConfirmationNameRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &CLI{
+				input:          strings.NewReader(""),
+				output:         &bytes.Buffer{},
+				nonInteractive: true,
+				confirmedName:  tt.confirmedName,
+			}
+
+			got, err := c.confirm(context.Background(), tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("confirmed: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package amigo
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// overrideProtectionFlag is the flag allowing destructive commands in protected environments
+const overrideProtectionFlag = "i-know-what-im-doing"
+
+// isProtectedEnvironment reports whether the configured environment is listed in CLIConfig.ProtectedEnvironments
+func (c *CLI) isProtectedEnvironment() bool {
+	return c.config.Environment != "" && slices.Contains(c.protectedEnvs, c.config.Environment)
+}
+
+// guardProtectedEnvironment refuses destructive commands in a protected environment unless overridden.
+// It returns false, after printing why, when the command must not run.
+func (c *CLI) guardProtectedEnvironment(command string, override bool) bool {
+	if !c.isProtectedEnvironment() || override {
+		return true
+	}
+
+	fmt.Fprintf(c.errorOutput, "%s\n", c.cliOutput.error(fmt.Sprintf(
+		"Error: '%s' is refused in the protected environment '%s', pass --%s to run it anyway",
+		command, c.config.Environment, overrideProtectionFlag)))
+	return false
+}
+
+// confirmMessage returns the confirmation question, naming the environment when one is configured
+func (c *CLI) confirmMessage() string {
+	if c.config.Environment == "" {
+		return "Do you want to continue?"
+	}
+	return fmt.Sprintf("Do you want to continue on %s?", c.cliOutput.environment(c.config.Environment))
+}
+
+// selectionFlags are the --env and --tags flags selecting the migrations of a run, see EnvironmentScoped and Tagged
+type selectionFlags struct {
+	environment string
+	tags        string
+}
+
+// addSelectionFlags registers the --env and --tags flags on fs
+func (c *CLI) addSelectionFlags(fs *flag.FlagSet) *selectionFlags {
+	var s selectionFlags
+	fs.StringVar(&s.environment, "env", c.config.Environment, "Select the migrations of this environment")
+	fs.StringVar(&s.tags, "tags", "", "Select the migrations with one of these comma separated tags, and untagged ones")
+	return &s
+}
+
+// options returns the runner options selecting the migrations
+func (s *selectionFlags) options() []RunnerUpOptsFunc {
+	opts := []RunnerUpOptsFunc{RunnerUpOptionEnvironment(s.environment)}
+	if s.tags != "" {
+		opts = append(opts, RunnerUpOptionTags(strings.Split(s.tags, ",")...))
+	}
+	return opts
+}
@@ -12,35 +12,52 @@ const (
 	colorGreen  = "\033[32m"
 	colorYellow = "\033[33m"
 	colorCyan   = "\033[36m"
+	colorBold   = "\033[1m"
 )
 
 // cliOutput provides helper methods for formatted CLI output
-type cliOutput struct{}
+type cliOutput struct {
+	// color enables ANSI color codes, see termcolor.Enabled for the policy deciding it
+	color bool
+}
 
 // newCLIOutput creates a new cliOutput helper
-func newCLIOutput() *cliOutput {
-	return &cliOutput{}
+func newCLIOutput(color bool) *cliOutput {
+	return &cliOutput{color: color}
+}
+
+// paint wraps s with the given color code when colors are enabled
+func (o *cliOutput) paint(color, s string) string {
+	if !o.color {
+		return s
+	}
+	return color + s + colorReset
 }
 
 // path formats a file path in cyan
 func (o *cliOutput) path(path string) string {
-	return colorCyan + path + colorReset
+	return o.paint(colorCyan, path)
 }
 
 // duration formats a duration in yellow (in milliseconds)
 func (o *cliOutput) duration(d time.Duration) string {
 	ms := d.Milliseconds()
-	return colorYellow + fmt.Sprintf("%dms", ms) + colorReset
+	return o.paint(colorYellow, fmt.Sprintf("%dms", ms))
 }
 
 // error formats an error message in red
 func (o *cliOutput) error(msg string) string {
-	return colorRed + msg + colorReset
+	return o.paint(colorRed, msg)
+}
+
+// warning formats a warning message in yellow
+func (o *cliOutput) warning(msg string) string {
+	return o.paint(colorYellow, msg)
 }
 
 // timestamp formats a timestamp in green
 func (o *cliOutput) timestamp(t time.Time) string {
-	return colorGreen + t.Format("2006-01-02 15:04:05") + colorReset
+	return o.paint(colorGreen, t.Format("2006-01-02 15:04:05"))
 }
 
 // timestampNow formats the current time in green
@@ -50,5 +67,14 @@ func (o *cliOutput) timestampNow() string {
 
 // date formats a date/timestamp integer (YYYYMMDDHHMMSS) in green
 func (o *cliOutput) date(date int64) string {
-	return colorGreen + fmt.Sprintf("%d", date) + colorReset
+	// repeatable migrations have no date
+	if date == 0 {
+		return o.paint(colorGreen, "repeatable")
+	}
+	return o.paint(colorGreen, fmt.Sprintf("%d", date))
+}
+
+// environment formats an environment name in bold
+func (o *cliOutput) environment(env string) string {
+	return o.paint(colorBold, env)
 }
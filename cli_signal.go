@@ -0,0 +1,107 @@
+package amigo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exitCodeInterrupted is returned when a command stopped early because of SIGINT/SIGTERM (128 + SIGINT)
+const exitCodeInterrupted = 130
+
+// gracefulCommands are the commands that finish the migration in flight on the first signal
+// instead of canceling the context right away
+var gracefulCommands = map[string]bool{
+	"up":   true,
+	"down": true,
+	"seed": true,
+}
+
+// signalContext returns a context canceled on SIGINT/SIGTERM.
+//
+// When graceful is true, the first signal only marks the CLI as interrupted: the migration in flight
+// and its bookkeeping are allowed to finish, and commands stop before starting the next one. A second
+// signal cancels the context. The returned function must be called to release the signal handler.
+func (c *CLI) signalContext(parent context.Context, graceful bool) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	c.interrupt = make(chan struct{})
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			close(c.interrupt)
+			if !graceful {
+				cancel()
+				return
+			}
+			fmt.Fprintf(c.errorOutput, "\n%s\n", c.cliOutput.error(fmt.Sprintf(
+				"Received %s, finishing the current migration (send it again to abort immediately)", sig)))
+		case <-done:
+			return
+		}
+
+		select {
+		case <-signals:
+			cancel()
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
+
+// interrupted reports whether a SIGINT/SIGTERM has been received
+func (c *CLI) interrupted() bool {
+	if c.interrupt == nil {
+		return false
+	}
+	select {
+	case <-c.interrupt:
+		return true
+	default:
+		return false
+	}
+}
+
+// printInterruptSummary lists what has been done and what has not before an interruption
+func (c *CLI) printInterruptSummary(verb string, done []Migration, planned []MigrationStatus) {
+	// repeatable migrations all have the date 0, the name tells them apart
+	type migrationKey struct {
+		date int64
+		name string
+	}
+
+	doneKeys := make(map[migrationKey]struct{}, len(done))
+	for _, m := range done {
+		doneKeys[migrationKey{m.Date(), m.Name()}] = struct{}{}
+	}
+
+	fmt.Fprintln(c.output, "")
+	fmt.Fprintf(c.output, "Interrupted: %s %d migration(s)\n", verb, len(done))
+
+	var remaining []MigrationStatus
+	for _, m := range planned {
+		if _, ok := doneKeys[migrationKey{m.Migration.Date, m.Migration.Name}]; !ok {
+			remaining = append(remaining, m)
+		}
+	}
+
+	if len(remaining) == 0 {
+		return
+	}
+
+	fmt.Fprintf(c.output, "The following %d migration(s) were not %s:\n\n", len(remaining), verb)
+	for _, m := range remaining {
+		fmt.Fprintf(c.output, "  %s  %s\n", c.cliOutput.date(m.Migration.Date), m.Migration.Name)
+	}
+}
@@ -0,0 +1,30 @@
+package amigo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// printStatementProgress displays the statement a migration is running, for migrations of several statements
+func (c *CLI) printStatementProgress(progress *StatementProgress) {
+	if progress.Count < 2 {
+		return
+	}
+
+	first, _, _ := strings.Cut(progress.Statement, "\n")
+	fmt.Fprintf(c.output, "   -> statement %d/%d (line %d): %s\n", progress.Index, progress.Count, progress.Line, first)
+}
+
+// printStatementError displays the first lines of the statement a migration failed on, if any
+func (c *CLI) printStatementError(err error) {
+	var statementErr *StatementError
+	if !errors.As(err, &statementErr) {
+		return
+	}
+
+	fmt.Fprintf(c.errorOutput, "\nFailed statement %d/%d at line %d:\n", statementErr.Index, statementErr.Count, statementErr.Line)
+	for _, line := range strings.Split(statementErr.Statement, "\n") {
+		fmt.Fprintf(c.errorOutput, "    %s\n", line)
+	}
+}
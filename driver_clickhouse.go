@@ -4,61 +4,349 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type ClickHouseDriver struct {
 	tableName string
 	cluster   string // empty string means no cluster
+	opts      clickHouseDriverOpts
 }
 
-func NewClickHouseDriver(tableName, cluster string) *ClickHouseDriver {
+// NewClickHouseDriver returns a driver tracking migrations in tableName, created on cluster when not empty.
+// See ClickHouseDriverOptionOnCluster to run the migrations themselves on the cluster.
+func NewClickHouseDriver(tableName, cluster string, opts ...ClickHouseDriverOptsFunc) *ClickHouseDriver {
 	if tableName == "" {
 		tableName = "schema_migrations"
 	}
-	return &ClickHouseDriver{
+	d := &ClickHouseDriver{
 		tableName: tableName,
 		cluster:   cluster,
 	}
+	for _, opt := range opts {
+		opt(&d.opts)
+	}
+	return d
+}
+
+// Cluster returns the name of the cluster the driver runs DDL on, or an empty string without cluster
+func (d *ClickHouseDriver) Cluster() string {
+	return d.cluster
 }
 
+// CreateSchemaMigrationsTableIfNotExists creates amigo's tables and upgrades them to the latest layout.
+// ClickHouse has no lock to hold, the upgrade creates a lock table instead, see lockLayout.
 func (d *ClickHouseDriver) CreateSchemaMigrationsTableIfNotExists(ctx context.Context, db *sql.DB) error {
-	var query string
+	return upgradeLayout(ctx, db, d, d.layoutSteps())
+}
+
+// layoutSteps returns the upgrades of amigo's tables, see layoutStep
+func (d *ClickHouseDriver) layoutSteps() []layoutStep {
+	return []layoutStep{
+		{
+			version:     1,
+			description: "create schema migrations table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				_, err := db.ExecContext(ctx, fmt.Sprintf(`
+					CREATE TABLE IF NOT EXISTS %s%s (
+						date Int64,
+						name String,
+						applied_at DateTime DEFAULT now(),
+						applied UInt8 DEFAULT 1
+					) ENGINE = %s
+					ORDER BY date
+				`, d.tableName, d.onCluster(), d.engine("ReplacingMergeTree", d.tableName, "applied_at")))
+				return err
+			},
+		},
+		{
+			version:     2,
+			description: "add environment column",
+			apply: d.addColumns(
+				"environment String DEFAULT ''",
+			),
+		},
+		{
+			version:     3,
+			description: "add audit columns",
+			apply: d.addColumns(
+				"duration_ms Int64 DEFAULT 0",
+				"os_user String DEFAULT ''",
+				"hostname String DEFAULT ''",
+				"amigo_version String DEFAULT ''",
+				"app_version String DEFAULT ''",
+				"kind String DEFAULT ''",
+			),
+		},
+		{
+			version:     4,
+			description: "create history table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				_, err := db.ExecContext(ctx, fmt.Sprintf(`
+					CREATE TABLE IF NOT EXISTS %s%s (
+						batch Int64,
+						date Int64,
+						name String,
+						direction String,
+						status String,
+						error String DEFAULT '',
+						started_at DateTime64(6),
+						duration_ms Int64 DEFAULT 0,
+						environment String DEFAULT '',
+						os_user String DEFAULT '',
+						hostname String DEFAULT '',
+						amigo_version String DEFAULT '',
+						app_version String DEFAULT '',
+						kind String DEFAULT ''
+					) ENGINE = %s
+					ORDER BY (started_at, date)
+				`, d.historyTableName(), d.onCluster(), d.engine("MergeTree", d.historyTableName(), "")))
+				return err
+			},
+		},
+		{
+			version:     5,
+			description: "convert schema migrations table to ReplacingMergeTree",
+			apply:       d.convertToReplacingMergeTree,
+		},
+		{
+			version:     6,
+			description: "add checksum column",
+			apply: d.addColumns(
+				"checksum String DEFAULT ''",
+			),
+		},
+		{
+			version:     7,
+			description: "create repeatable migrations table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				return d.createNamedRecordsTable(ctx, db, d.repeatableTableName())
+			},
+		},
+		{
+			version:     8,
+			description: "create seeds table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				return d.createNamedRecordsTable(ctx, db, d.seedsTableName())
+			},
+		},
+	}
+}
+
+// createNamedRecordsTable creates a table tracking the last run of repeatable migrations or seeds, by name
+func (d *ClickHouseDriver) createNamedRecordsTable(ctx context.Context, db sqlExecutor, table string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s%s (
+			name String,
+			applied_at DateTime64(6) DEFAULT now64(6),
+			environment String DEFAULT '',
+			duration_ms Int64 DEFAULT 0,
+			os_user String DEFAULT '',
+			hostname String DEFAULT '',
+			amigo_version String DEFAULT '',
+			app_version String DEFAULT '',
+			kind String DEFAULT '',
+			checksum String DEFAULT ''
+		) ENGINE = %s
+		ORDER BY name
+	`, table, d.onCluster(), d.engine("ReplacingMergeTree", table, "applied_at")))
+	return err
+}
+
+// engine returns the table engine clause, replicated when running on a cluster
+func (d *ClickHouseDriver) engine(family, table, version string) string {
+	if d.cluster == "" {
+		return fmt.Sprintf("%s(%s)", family, version)
+	}
+
+	args := fmt.Sprintf("'/clickhouse/tables/{shard}/%s', '{replica}'", table)
+	if version != "" {
+		args += ", " + version
+	}
+	return fmt.Sprintf("Replicated%s(%s)", family, args)
+}
+
+// addColumns returns a layout step function adding the given column definitions to the schema migrations table
+func (d *ClickHouseDriver) addColumns(definitions ...string) func(ctx context.Context, db sqlExecutor) error {
+	return func(ctx context.Context, db sqlExecutor) error {
+		additions := make([]string, len(definitions))
+		for i, definition := range definitions {
+			additions[i] = "ADD COLUMN IF NOT EXISTS " + definition
+		}
+
+		query := fmt.Sprintf(`ALTER TABLE %s%s %s`, d.tableName, d.onCluster(), strings.Join(additions, ", "))
+		_, err := db.ExecContext(ctx, query)
+		return err
+	}
+}
 
+// convertToReplacingMergeTree moves a schema migrations table created with the MergeTree engine, which
+// required mutations to delete rows, to a ReplacingMergeTree where rollbacks are recorded with applied = 0.
+// Tables created on a cluster always used ReplicatedReplacingMergeTree and are left untouched.
+//
+// The rows are copied to a table named after the start of the upgrade, so that concurrent upgrades do not drop
+// each other's copy, which then takes the place of the table with EXCHANGE TABLES in one atomic step: the
+// database must use the Atomic engine, the default. Rows recorded in the meantime are copied again after the
+// exchange, rows deleted are not: older amigo versions must not revert migrations during the upgrade. A copy
+// left by an interrupted upgrade can be dropped.
+func (d *ClickHouseDriver) convertToReplacingMergeTree(ctx context.Context, db sqlExecutor) error {
 	if d.cluster != "" {
-		query = fmt.Sprintf(`
-			CREATE TABLE IF NOT EXISTS %s ON CLUSTER '%s' (
-				date Int64,
-				name String,
-				applied_at DateTime DEFAULT now(),
-				applied UInt8 DEFAULT 1
-			) ENGINE = ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/%s', '{replica}', applied_at)
-			PRIMARY KEY date
-			ORDER BY date
-		`, d.tableName, d.cluster, d.tableName)
-	} else {
-		query = fmt.Sprintf(`
-			CREATE TABLE IF NOT EXISTS %s (
-				date Int64,
-				name String,
-				applied_at DateTime DEFAULT now()
-			) ENGINE = MergeTree()
-			ORDER BY date
-		`, d.tableName)
+		return nil
+	}
+
+	var engine string
+	query := `SELECT engine FROM system.tables WHERE database = currentDatabase() AND name = ?`
+	if err := db.QueryRowContext(ctx, query, d.tableName).Scan(&engine); err != nil {
+		return fmt.Errorf("failed to get engine of %s: %w", d.tableName, err)
+	}
+	if strings.Contains(engine, "ReplacingMergeTree") {
+		return nil
+	}
+
+	// After the exchange, upgradeTable holds the MergeTree table
+	upgradeTable := fmt.Sprintf("%s_upgrade_%d", d.tableName, time.Now().UnixNano())
+
+	statements := []string{
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS applied UInt8 DEFAULT 1`, d.tableName),
+		fmt.Sprintf(`CREATE TABLE %s AS %s ENGINE = ReplacingMergeTree(applied_at) ORDER BY date`, upgradeTable, d.tableName),
+		fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s`, upgradeTable, d.tableName),
+		fmt.Sprintf(`EXCHANGE TABLES %s AND %s`, d.tableName, upgradeTable),
+		fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s WHERE date NOT IN (SELECT date FROM %s)`, d.tableName, upgradeTable, d.tableName),
+		fmt.Sprintf(`DROP TABLE %s`, upgradeTable),
 	}
 
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// historyTableName returns the name of the append-only history table
+func (d *ClickHouseDriver) historyTableName() string {
+	return d.tableName + "_history"
+}
+
+// repeatableTableName returns the name of the table tracking the last run of repeatable migrations
+func (d *ClickHouseDriver) repeatableTableName() string {
+	return d.tableName + "_repeatable"
+}
+
+// seedsTableName returns the name of the table tracking the last run of seeds
+func (d *ClickHouseDriver) seedsTableName() string {
+	return d.tableName + "_seeds"
+}
+
+// metaTableName returns the name of the table storing metadata such as the layout version
+func (d *ClickHouseDriver) metaTableName() string {
+	return d.tableName + "_meta"
+}
+
+func (d *ClickHouseDriver) createMetaTable(ctx context.Context, db sqlExecutor) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s%s (
+			key String,
+			value String,
+			updated_at DateTime64(6) DEFAULT now64(6)
+		) ENGINE = %s
+		ORDER BY key
+	`, d.metaTableName(), d.onCluster(), d.engine("ReplacingMergeTree", d.metaTableName(), "updated_at"))
+
 	_, err := db.ExecContext(ctx, query)
 	return err
 }
 
-func (d *ClickHouseDriver) GetAppliedMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
-	var query string
+// lockTableName returns the name of the table existing while a run upgrades the layout, see lockLayout
+func (d *ClickHouseDriver) lockTableName() string {
+	return d.tableName + "_lock"
+}
 
-	if d.cluster != "" {
+// lockLayout creates the lock table, without IF NOT EXISTS so that the creation fails when another run holds the
+// lock, and returns the function dropping it. See layoutLocker.
+// A run killed during the upgrade leaves the table behind, it must then be dropped by hand.
+func (d *ClickHouseDriver) lockLayout(ctx context.Context, db sqlExecutor) (func(), error) {
+	query := fmt.Sprintf(`CREATE TABLE %s%s (locked_at DateTime DEFAULT now()) ENGINE = Memory`,
+		d.lockTableName(), d.onCluster())
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		if exists, existsErr := d.tableExists(ctx, db, d.lockTableName()); existsErr == nil && exists {
+			return nil, fmt.Errorf("%w: %s exists, drop it if no other run is upgrading: %v", ErrLayoutUpgradeLocked, d.lockTableName(), err)
+		}
+		return nil, err
+	}
+
+	return func() {
+		query := fmt.Sprintf(`DROP TABLE IF EXISTS %s%s SYNC`, d.lockTableName(), d.onCluster())
+		db.ExecContext(context.WithoutCancel(ctx), query)
+	}, nil
+}
+
+func (d *ClickHouseDriver) getLayoutVersion(ctx context.Context, db sqlExecutor) (int, error) {
+	query := fmt.Sprintf(`SELECT value FROM %s FINAL WHERE key = ?`, d.metaTableName())
+	return scanLayoutVersion(db.QueryRowContext(ctx, query, layoutVersionKey))
+}
+
+func (d *ClickHouseDriver) setLayoutVersion(ctx context.Context, db sqlExecutor, version int) error {
+	query := fmt.Sprintf(`INSERT INTO %s (key, value) VALUES (?, ?)`, d.metaTableName())
+	_, err := db.ExecContext(ctx, query, layoutVersionKey, strconv.Itoa(version))
+	return err
+}
+
+func (d *ClickHouseDriver) SchemaLayoutVersion(ctx context.Context, db *sql.DB) (current, latest int, err error) {
+	latest = latestLayoutVersion(d.layoutSteps())
+
+	exists, err := d.tableExists(ctx, db, d.metaTableName())
+	if err != nil || !exists {
+		return 0, latest, err
+	}
+
+	current, err = d.getLayoutVersion(ctx, db)
+	return current, latest, err
+}
+
+// tableExists reports whether the given table exists in the current database
+func (d *ClickHouseDriver) tableExists(ctx context.Context, db sqlExecutor, table string) (bool, error) {
+	var count uint64
+	query := `SELECT count() FROM system.tables WHERE database = currentDatabase() AND name = ?`
+	err := db.QueryRowContext(ctx, query, table).Scan(&count)
+	return count > 0, err
+}
+
+// onCluster returns the ON CLUSTER clause for DDL statements, or an empty string without cluster
+func (d *ClickHouseDriver) onCluster() string {
+	if d.cluster == "" {
+		return ""
+	}
+	return fmt.Sprintf(" ON CLUSTER '%s'", d.cluster)
+}
+
+func (d *ClickHouseDriver) SchemaMigrationsTableExists(ctx context.Context, db *sql.DB) (bool, error) {
+	return d.tableExists(ctx, db, d.tableName)
+}
+
+// getBaselineAppliedMigrations returns the applied migrations with the columns of the first layout, see
+// baselineRecordsReader. Tables created before rollbacks were soft deletes use MergeTree, where every row is
+// an applied migration.
+func (d *ClickHouseDriver) getBaselineAppliedMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	var engine string
+	query := `SELECT engine FROM system.tables WHERE database = currentDatabase() AND name = ?`
+	if err := db.QueryRowContext(ctx, query, d.tableName).Scan(&engine); err != nil {
+		return nil, fmt.Errorf("failed to get engine of %s: %w", d.tableName, err)
+	}
+
+	query = fmt.Sprintf(`SELECT date, name, applied_at FROM %s ORDER BY date ASC`, d.tableName)
+	if strings.Contains(engine, "ReplacingMergeTree") {
 		query = fmt.Sprintf(`SELECT date, name, applied_at FROM %s FINAL WHERE applied = 1 ORDER BY date ASC`, d.tableName)
-	} else {
-		query = fmt.Sprintf(`SELECT date, name, applied_at FROM %s ORDER BY date ASC`, d.tableName)
 	}
+	return queryBaselineRecords(ctx, db, query)
+}
+
+func (d *ClickHouseDriver) GetAppliedMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	query := fmt.Sprintf(`SELECT date, name, applied_at, %s FROM %s FINAL WHERE applied = 1 ORDER BY date ASC`,
+		strings.Join(trackingColumnNames, ", "), d.tableName)
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
@@ -68,8 +356,8 @@ func (d *ClickHouseDriver) GetAppliedMigrations(ctx context.Context, db *sql.DB)
 
 	var migrations []MigrationRecord
 	for rows.Next() {
-		var m MigrationRecord
-		if err := rows.Scan(&m.Date, &m.Name, &m.AppliedAt); err != nil {
+		m, err := scanMigrationRecord(rows.Scan)
+		if err != nil {
 			return nil, err
 		}
 		migrations = append(migrations, m)
@@ -83,14 +371,18 @@ func (d *ClickHouseDriver) InsertMigrations(ctx context.Context, db *sql.DB, lis
 		return nil
 	}
 
+	columnCount := 2 + len(trackingColumnNames)
+	rowPlaceholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", columnCount), ", ") + ")"
 	placeholders := make([]string, len(list))
-	args := make([]any, 0, len(list)*2)
+	args := make([]any, 0, len(list)*columnCount)
 	for i, m := range list {
-		placeholders[i] = "(?, ?)"
+		placeholders[i] = rowPlaceholder
 		args = append(args, m.Date, m.Name)
+		args = append(args, trackingValues(m)...)
 	}
 
-	query := fmt.Sprintf(`INSERT INTO %s (date, name) VALUES %s`, d.tableName, strings.Join(placeholders, ", "))
+	query := fmt.Sprintf(`INSERT INTO %s (date, name, %s) VALUES %s`,
+		d.tableName, strings.Join(trackingColumnNames, ", "), strings.Join(placeholders, ", "))
 	_, err := db.ExecContext(ctx, query, args...)
 	return err
 }
@@ -100,33 +392,158 @@ func (d *ClickHouseDriver) DeleteMigrations(ctx context.Context, db *sql.DB, dat
 		return nil
 	}
 
-	if d.cluster != "" {
-		// Soft delete: réinsère avec applied = 0
-		placeholders := make([]string, len(dates))
-		args := make([]any, len(dates))
-		for i, date := range dates {
-			placeholders[i] = "(?, '', now(), 0)"
-			args[i] = date
-		}
-
-		query := fmt.Sprintf(`INSERT INTO %s (date, name, applied_at, applied) VALUES %s`, d.tableName, strings.Join(placeholders, ", "))
-		_, err := db.ExecContext(ctx, query, args...)
-		return err
-	}
-
-	// Hard delete pour setup simple
+	// Soft delete: the row is inserted again with applied = 0 and replaces the applied one
 	placeholders := make([]string, len(dates))
 	args := make([]any, len(dates))
 	for i, date := range dates {
-		placeholders[i] = "?"
+		placeholders[i] = "(?, '', now(), 0)"
 		args[i] = date
 	}
 
-	query := fmt.Sprintf(`ALTER TABLE %s DELETE WHERE date IN (%s)`, d.tableName, strings.Join(placeholders, ", "))
+	query := fmt.Sprintf(`INSERT INTO %s (date, name, applied_at, applied) VALUES %s`, d.tableName, strings.Join(placeholders, ", "))
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (d *ClickHouseDriver) GetRepeatableMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	return d.getNamedRecords(ctx, db, d.repeatableTableName())
+}
+
+func (d *ClickHouseDriver) UpsertRepeatableMigration(ctx context.Context, db *sql.DB, record MigrationRecord) error {
+	return d.upsertNamedRecord(ctx, db, d.repeatableTableName(), record)
+}
+
+func (d *ClickHouseDriver) GetAppliedSeeds(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	return d.getNamedRecords(ctx, db, d.seedsTableName())
+}
+
+func (d *ClickHouseDriver) UpsertSeed(ctx context.Context, db *sql.DB, record MigrationRecord) error {
+	return d.upsertNamedRecord(ctx, db, d.seedsTableName(), record)
+}
+
+// getNamedRecords returns the records of a table keyed by name, see createNamedRecordsTable
+func (d *ClickHouseDriver) getNamedRecords(ctx context.Context, db *sql.DB, table string) ([]MigrationRecord, error) {
+	query := fmt.Sprintf(`SELECT name, applied_at, %s FROM %s FINAL ORDER BY name ASC`,
+		strings.Join(trackingColumnNames, ", "), table)
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []MigrationRecord
+	for rows.Next() {
+		m, err := scanNamedRecord(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, m)
+	}
+
+	return records, rows.Err()
+}
+
+// upsertNamedRecord inserts a new record, which replaces the previous one of the same name once merged
+func (d *ClickHouseDriver) upsertNamedRecord(ctx context.Context, db *sql.DB, table string, record MigrationRecord) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", 1+len(trackingColumnNames)), ", ")
+	query := fmt.Sprintf(`INSERT INTO %s (name, %s) VALUES (%s)`,
+		table, strings.Join(trackingColumnNames, ", "), placeholders)
+
+	args := append([]any{record.Name}, trackingValues(record)...)
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (d *ClickHouseDriver) InsertHistory(ctx context.Context, db *sql.DB, list []HistoryRecord) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	query, args := historyInsertQuery(d.historyTableName(), list, questionPlaceholder)
 	_, err := db.ExecContext(ctx, query, args...)
 	return err
 }
 
+func (d *ClickHouseDriver) GetHistory(ctx context.Context, db *sql.DB, filter HistoryFilter) ([]HistoryRecord, error) {
+	query, args := historySelectQuery(d.historyTableName(), "started_at DESC", filter, questionPlaceholder)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoryRecord
+	for rows.Next() {
+		h, err := scanHistoryRecord(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
+
+func (d *ClickHouseDriver) NextHistoryBatch(ctx context.Context, db *sql.DB) (int64, error) {
+	var batch int64
+	query := fmt.Sprintf(`SELECT max(batch) + 1 FROM %s`, d.historyTableName())
+	err := db.QueryRowContext(ctx, query).Scan(&batch)
+	return batch, err
+}
+
 func (d *ClickHouseDriver) Name() string {
 	return "clickhouse"
 }
+
+// amigoTableNames returns the names of amigo's own tables, left out of schema dumps
+func (d *ClickHouseDriver) amigoTableNames() []string {
+	return []string{d.tableName, d.historyTableName(), d.repeatableTableName(), d.seedsTableName(), d.metaTableName(), d.lockTableName()}
+}
+
+// DumpSchema returns the SHOW CREATE statement of every table of the current database, tables first then
+// dictionaries and views, in name order. See SchemaDumper.
+func (d *ClickHouseDriver) DumpSchema(ctx context.Context, db *sql.DB) (string, error) {
+	query := fmt.Sprintf(`
+		SELECT name, engine FROM system.tables
+		WHERE database = currentDatabase() AND NOT is_temporary AND name NOT LIKE '.inner%%' AND name NOT IN (%s)
+		ORDER BY multiIf(engine = 'Dictionary', 1, engine IN ('View', 'MaterializedView', 'LiveView', 'WindowView'), 2, 0), name
+	`, sqlStringList(d.amigoTableNames()))
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+
+	type table struct{ name, engine string }
+	var tables []table
+	for rows.Next() {
+		var t table
+		if err := rows.Scan(&t.name, &t.engine); err != nil {
+			rows.Close()
+			return "", err
+		}
+		tables = append(tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	statements := make([]string, 0, len(tables))
+	for _, t := range tables {
+		kind := "TABLE"
+		if t.engine == "Dictionary" {
+			kind = "DICTIONARY"
+		}
+
+		var statement string
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("SHOW CREATE %s `%s`", kind, t.name)).Scan(&statement); err != nil {
+			return "", fmt.Errorf("failed to show create %s: %w", t.name, err)
+		}
+		statements = append(statements, statement)
+	}
+
+	return joinSchemaStatements(statements), nil
+}
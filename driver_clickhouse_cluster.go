@@ -0,0 +1,237 @@
+package amigo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrDistributedDDLFailed is returned when a host of the cluster failed or did not finish the distributed DDL of
+// a migration, see ClickHouseDriverOptionCheckDDLQueue
+var ErrDistributedDDLFailed = errors.New("distributed DDL did not finish on every host")
+
+// distributedDDLClockSkew widens the window of distributed DDL checked for a migration, so that a clock of the
+// client ahead of the server does not hide entries
+const distributedDDLClockSkew = 5 * time.Second
+
+type clickHouseDriverOpts struct {
+	OnCluster      bool
+	DDLTaskTimeout time.Duration
+	CheckDDLQueue  bool
+}
+
+type ClickHouseDriverOptsFunc func(*clickHouseDriverOpts)
+
+// ClickHouseDriverOptionOnCluster adds ON CLUSTER to the DDL statements of SQL migrations and seeds that do not
+// have it, so they run on every host of the cluster of the driver. Statements are rewritten one by one: set
+// Configuration.SplitStatements when sections have more than one. No-op without cluster.
+func ClickHouseDriverOptionOnCluster() ClickHouseDriverOptsFunc {
+	return func(opts *clickHouseDriverOpts) {
+		opts.OnCluster = true
+	}
+}
+
+// ClickHouseDriverOptionDDLTaskTimeout sets distributed_ddl_task_timeout, the time ON CLUSTER statements wait
+// for every host, before the statements of SQL migrations and seeds. The section then runs on a single
+// connection, so the database/sql driver must keep SET statements for the connection, else set it in the
+// profile of the user. No-op without cluster.
+func ClickHouseDriverOptionDDLTaskTimeout(timeout time.Duration) ClickHouseDriverOptsFunc {
+	return func(opts *clickHouseDriverOpts) {
+		opts.DDLTaskTimeout = timeout
+	}
+}
+
+// ClickHouseDriverOptionCheckDDLQueue checks system.distributed_ddl_queue after every migration, up or down,
+// and fails it with ErrDistributedDDLFailed when a host of the cluster failed or did not finish a distributed DDL
+// of the migration, before it is recorded. Only the ON CLUSTER statements of SQL migrations and seeds are
+// checked, matched with the queue entries on their text. No-op without cluster.
+func ClickHouseDriverOptionCheckDDLQueue() ClickHouseDriverOptsFunc {
+	return func(opts *clickHouseDriverOpts) {
+		opts.CheckDDLQueue = true
+	}
+}
+
+// SessionStatements sets distributed_ddl_task_timeout, see ClickHouseDriverOptionDDLTaskTimeout
+func (d *ClickHouseDriver) SessionStatements() []string {
+	if d.cluster == "" || d.opts.DDLTaskTimeout <= 0 {
+		return nil
+	}
+	seconds := int64(d.opts.DDLTaskTimeout.Round(time.Second) / time.Second)
+	return []string{fmt.Sprintf("SET distributed_ddl_task_timeout = %d", max(seconds, 1))}
+}
+
+var (
+	clickHouseIdentifier = "(?:`[^`]*`|\"[^\"]*\"|[\\w$]+)"
+	clickHouseObjectName = clickHouseIdentifier + `(?:\s*\.\s*` + clickHouseIdentifier + `)?`
+
+	// clickHouseDDLRegexp matches DDL statements up to the name of their object, where ON CLUSTER goes
+	clickHouseDDLRegexp = regexp.MustCompile(`(?is)^(?:` +
+		`CREATE(?:\s+OR\s+REPLACE)?\s+(?:TABLE|DATABASE|VIEW|MATERIALIZED\s+VIEW|DICTIONARY|FUNCTION)` +
+		`|ALTER\s+TABLE` +
+		`|DROP\s+(?:TABLE|DATABASE|VIEW|DICTIONARY|FUNCTION)` +
+		`|TRUNCATE(?:\s+TABLE)?` +
+		`|OPTIMIZE\s+TABLE` +
+		`|ATTACH\s+(?:TABLE|DATABASE|DICTIONARY)` +
+		`|DETACH\s+(?:TABLE|DATABASE|VIEW|DICTIONARY)` +
+		`)(?:\s+IF\s+(?:NOT\s+)?EXISTS)?\s+` + clickHouseObjectName)
+
+	// clickHouseRenameRegexp matches RENAME and EXCHANGE statements, which take ON CLUSTER after every object
+	clickHouseRenameRegexp = regexp.MustCompile(`(?is)^(?:RENAME\s+(?:TABLE|DATABASE|DICTIONARY)|EXCHANGE\s+(?:TABLES|DICTIONARIES))\b`)
+
+	clickHouseOnClusterRegexp = regexp.MustCompile(`(?i)\bON\s+CLUSTER\b`)
+	clickHouseTemporaryRegexp = regexp.MustCompile(`(?i)^CREATE\s+(?:OR\s+REPLACE\s+)?TEMPORARY\b`)
+)
+
+// RewriteStatement adds ON CLUSTER to a DDL statement, see ClickHouseDriverOptionOnCluster. Statements already
+// running on a cluster, temporary tables and other statements are left as is.
+func (d *ClickHouseDriver) RewriteStatement(statement string) string {
+	if d.cluster == "" || !d.opts.OnCluster {
+		return statement
+	}
+
+	normalized := normalizeStatement(statement)
+	if clickHouseOnClusterRegexp.MatchString(normalized) || clickHouseTemporaryRegexp.MatchString(normalized) {
+		return statement
+	}
+
+	start := sqlCodeStart(statement)
+	code := statement[start:]
+
+	if loc := clickHouseDDLRegexp.FindStringIndex(code); loc != nil {
+		at := start + loc[1]
+		return statement[:at] + d.onCluster() + statement[at:]
+	}
+
+	if clickHouseRenameRegexp.MatchString(code) {
+		end := len(strings.TrimRight(statement, "; \t\r\n"))
+		return statement[:end] + d.onCluster() + statement[end:]
+	}
+
+	return statement
+}
+
+// sqlCodeStart returns the offset of the first character of a statement that is not whitespace or a comment
+func sqlCodeStart(statement string) int {
+	i := 0
+	for i < len(statement) {
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(statement[i])):
+			i++
+		case strings.HasPrefix(statement[i:], "--"):
+			end := strings.IndexByte(statement[i:], '\n')
+			if end < 0 {
+				return len(statement)
+			}
+			i += end + 1
+		case strings.HasPrefix(statement[i:], "/*"):
+			end := strings.Index(statement[i:], "*/")
+			if end < 0 {
+				return len(statement)
+			}
+			i += end + 2
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// distributedDDL returns the statements running on a cluster executed for the migration of ctx, normalized, see
+// contextWithExecutedStatements
+func distributedDDL(ctx context.Context) []string {
+	var statements []string
+	for _, statement := range executedStatementsFromContext(ctx) {
+		if clickHouseOnClusterRegexp.MatchString(normalizeStatement(statement)) {
+			statements = append(statements, normalizeDistributedDDL(statement))
+		}
+	}
+	return statements
+}
+
+var (
+	clickHouseClusterRegexp    = regexp.MustCompile("(?i)\\bON\\s+CLUSTER\\s+(?:'[^']*'|\"[^\"]*\"|`[^`]*`|\\S+)")
+	clickHouseUUIDRegexp       = regexp.MustCompile(`(?i)\buuid\s*'[0-9a-f-]+'`)
+	clickHouseQualifierRegexp  = regexp.MustCompile(clickHouseIdentifier + `\s*\.\s*`)
+	clickHouseQueryNoiseRegexp = regexp.MustCompile("[\\s`\"';]+")
+)
+
+// normalizeDistributedDDL returns a statement in a form comparable with the query of its entry in
+// system.distributed_ddl_queue, which the server formats again with the database of the tables, their UUID and
+// the cluster with its macros expanded
+func normalizeDistributedDDL(statement string) string {
+	statement = strings.ToLower(normalizeStatement(statement))
+	statement = clickHouseClusterRegexp.ReplaceAllString(statement, "")
+	statement = clickHouseUUIDRegexp.ReplaceAllString(statement, "")
+	statement = clickHouseQualifierRegexp.ReplaceAllString(statement, "")
+	return clickHouseQueryNoiseRegexp.ReplaceAllString(statement, "")
+}
+
+var clickHouseMacroRegexp = regexp.MustCompile(`^\{(\w+)\}$`)
+
+// VerifyMigration fails with ErrDistributedDDLFailed when a host of the cluster has a failed or unfinished
+// entry in system.distributed_ddl_queue for an ON CLUSTER statement of the migration, see
+// ClickHouseDriverOptionCheckDDLQueue. Entries of other clients and migrations are left out.
+func (d *ClickHouseDriver) VerifyMigration(ctx context.Context, db *sql.DB, start time.Time) error {
+	if d.cluster == "" || !d.opts.CheckDDLQueue {
+		return nil
+	}
+	statements := distributedDDL(ctx)
+	if len(statements) == 0 {
+		return nil
+	}
+
+	// The queue holds the cluster name, a cluster given as a macro is expanded by the server
+	cluster, args := "?", []any{d.cluster}
+	if match := clickHouseMacroRegexp.FindStringSubmatch(d.cluster); match != nil {
+		cluster, args = fmt.Sprintf("getMacro('%s')", match[1]), nil
+	}
+	args = append(args, start.Add(-distributedDDLClockSkew).Unix())
+
+	query := fmt.Sprintf(`
+		SELECT
+			coalesce(query, ''),
+			concat(coalesce(host, ''), ':', toString(coalesce(port, 0))),
+			coalesce(toString(status), 'Unknown'),
+			coalesce(exception_text, '')
+		FROM system.distributed_ddl_queue
+		WHERE cluster = %s AND query_create_time >= toDateTime(?)
+			AND (coalesce(toString(status), 'Unknown') != 'Finished' OR coalesce(exception_code, 0) != 0)
+		ORDER BY entry, host
+	`, cluster)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to check distributed DDL queue: %w", err)
+	}
+	defer rows.Close()
+
+	var failures []string
+	for rows.Next() {
+		var entryQuery, host, status, exception string
+		if err := rows.Scan(&entryQuery, &host, &status, &exception); err != nil {
+			return fmt.Errorf("failed to check distributed DDL queue: %w", err)
+		}
+		if !slices.Contains(statements, normalizeDistributedDDL(entryQuery)) {
+			continue
+		}
+
+		failure := fmt.Sprintf("%s %s", host, status)
+		if exception != "" {
+			failure += ": " + exception
+		}
+		failures = append(failures, failure)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check distributed DDL queue: %w", err)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%w on cluster %s: %s", ErrDistributedDDLFailed, d.cluster, strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package amigo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClickHouseDriver_RewriteStatement(t *testing.T) {
+	driver := NewClickHouseDriver("", "main", ClickHouseDriverOptionOnCluster())
+
+	tests := []struct {
+		name      string
+		statement string
+		want      string
+	}{
+		{
+			name:      "create table",
+			statement: "CREATE TABLE IF NOT EXISTS db.events (id UInt64) ENGINE = MergeTree ORDER BY id",
+			want:      "CREATE TABLE IF NOT EXISTS db.events ON CLUSTER 'main' (id UInt64) ENGINE = MergeTree ORDER BY id",
+		},
+		{
+			name:      "leading comment and quoted name",
+			statement: "-- events\ncreate materialized view `events_mv`\nTO events AS SELECT 1",
+			want:      "-- events\ncreate materialized view `events_mv` ON CLUSTER 'main'\nTO events AS SELECT 1",
+		},
+		{
+			name:      "alter table",
+			statement: "ALTER TABLE events ADD COLUMN name String",
+			want:      "ALTER TABLE events ON CLUSTER 'main' ADD COLUMN name String",
+		},
+		{
+			name:      "drop table",
+			statement: "DROP TABLE IF EXISTS events SYNC",
+			want:      "DROP TABLE IF EXISTS events ON CLUSTER 'main' SYNC",
+		},
+		{
+			name:      "rename table",
+			statement: "RENAME TABLE a TO b, c TO d;",
+			want:      "RENAME TABLE a TO b, c TO d ON CLUSTER 'main';",
+		},
+		{
+			name:      "exchange tables",
+			statement: "EXCHANGE TABLES events AND events_new",
+			want:      "EXCHANGE TABLES events AND events_new ON CLUSTER 'main'",
+		},
+		{
+			name:      "attach table",
+			statement: "ATTACH TABLE IF NOT EXISTS events",
+			want:      "ATTACH TABLE IF NOT EXISTS events ON CLUSTER 'main'",
+		},
+		{
+			name:      "detach table",
+			statement: "DETACH TABLE db.events PERMANENTLY",
+			want:      "DETACH TABLE db.events ON CLUSTER 'main' PERMANENTLY",
+		},
+		{
+			name:      "already on cluster",
+			statement: "CREATE TABLE events ON CLUSTER other (id UInt64) ENGINE = MergeTree ORDER BY id",
+			want:      "CREATE TABLE events ON CLUSTER other (id UInt64) ENGINE = MergeTree ORDER BY id",
+		},
+		{
+			name:      "temporary table",
+			statement: "CREATE TEMPORARY TABLE tmp (id UInt64)",
+			want:      "CREATE TEMPORARY TABLE tmp (id UInt64)",
+		},
+		{
+			name:      "not ddl",
+			statement: "INSERT INTO events VALUES (1)",
+			want:      "INSERT INTO events VALUES (1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := driver.RewriteStatement(tt.statement); got != tt.want {
+				t.Errorf("RewriteStatement() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("without cluster", func(t *testing.T) {
+		driver := NewClickHouseDriver("", "", ClickHouseDriverOptionOnCluster(), ClickHouseDriverOptionDDLTaskTimeout(time.Minute))
+		const statement = "ALTER TABLE events ADD COLUMN name String"
+		if got := driver.RewriteStatement(statement); got != statement {
+			t.Errorf("RewriteStatement() = %q, want %q", got, statement)
+		}
+		if got := driver.SessionStatements(); got != nil {
+			t.Errorf("SessionStatements() = %q, want nil", got)
+		}
+	})
+}
+
+func Test_distributedDDL(t *testing.T) {
+	ctx := contextWithExecutedStatements(t.Context())
+	driver := NewClickHouseDriver("", "{cluster}", ClickHouseDriverOptionOnCluster(), ClickHouseDriverOptionCheckDDLQueue())
+	for _, statement := range []string{
+		"CREATE TABLE events (id UInt64) ENGINE = MergeTree ORDER BY id;",
+		"ALTER TABLE logs ON CLUSTER other DROP COLUMN name",
+		"INSERT INTO events VALUES (1)",
+	} {
+		recordExecutedStatement(ctx, driver.RewriteStatement(statement))
+	}
+
+	got := distributedDDL(ctx)
+	if len(got) != 2 {
+		t.Fatalf("distributedDDL() = %q, want the 2 statements running on a cluster", got)
+	}
+	if other := distributedDDL(contextWithExecutedStatements(t.Context())); other != nil {
+		t.Errorf("distributedDDL() of another migration = %q, want nil", other)
+	}
+
+	// The queue holds the statement formatted by the server
+	entries := []string{
+		"CREATE TABLE default.events UUID '1f3b7c2e-9a4d-4e8f-b6a1-0c5d2e7f8a9b' ON CLUSTER main (`id` UInt64) ENGINE = MergeTree ORDER BY id",
+		"ALTER TABLE default.logs ON CLUSTER other DROP COLUMN name",
+	}
+	for i, entry := range entries {
+		if normalized := normalizeDistributedDDL(entry); normalized != got[i] {
+			t.Errorf("normalizeDistributedDDL(%q) = %q, want %q", entry, normalized, got[i])
+		}
+	}
+	if other := normalizeDistributedDDL("CREATE TABLE default.users ON CLUSTER main (`id` UInt64) ENGINE = MergeTree ORDER BY id"); other == got[0] {
+		t.Errorf("normalizeDistributedDDL() of another table = %q, want it to differ", other)
+	}
+
+	t.Run("without queue check", func(t *testing.T) {
+		driver := NewClickHouseDriver("", "main", ClickHouseDriverOptionOnCluster())
+		// No query is made, the database is not needed
+		if err := driver.VerifyMigration(ctx, nil, time.Now()); err != nil {
+			t.Errorf("VerifyMigration() error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,301 @@
+package amigo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlExecutor is the subset of *sql.DB, *sql.Conn and *sql.Tx used to manage amigo's own tables
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ErrLayoutUpgradeLocked is returned when amigo's tables need a layout upgrade while another run holds the lock of
+// the upgrade, on drivers without a database lock, see layoutLocker
+var ErrLayoutUpgradeLocked = errors.New("layout upgrade locked by another run")
+
+// layoutVersionKey is the key of the layout version in the metadata table of the drivers
+const layoutVersionKey = "layout_version"
+
+// layoutStep is an internal upgrade of amigo's own tables (schema_migrations and its companions).
+// Steps are applied once, in order of version, and must be idempotent: tables created before layouts were
+// versioned start from version 0 and replay every step.
+type layoutStep struct {
+	version     int
+	description string
+	apply       func(ctx context.Context, db sqlExecutor) error
+}
+
+// layoutStore is implemented by drivers to persist the layout version of their tables
+type layoutStore interface {
+	createMetaTable(ctx context.Context, db sqlExecutor) error
+	getLayoutVersion(ctx context.Context, db sqlExecutor) (int, error)
+	setLayoutVersion(ctx context.Context, db sqlExecutor, version int) error
+}
+
+// layoutLocker is implemented by layout stores whose driver runs CreateSchemaMigrationsTableIfNotExists without a
+// lock of the database, to lock the upgrade itself
+type layoutLocker interface {
+	// lockLayout takes the lock of the upgrade, failing with ErrLayoutUpgradeLocked when another run holds it,
+	// and returns the function releasing it
+	lockLayout(ctx context.Context, db sqlExecutor) (unlock func(), err error)
+}
+
+// upgradeLayout applies the steps newer than the stored layout version, recording the version after each one.
+// When the store is a layoutLocker and steps are missing, they are applied with its lock held.
+func upgradeLayout(ctx context.Context, db sqlExecutor, store layoutStore, steps []layoutStep) error {
+	if err := store.createMetaTable(ctx, db); err != nil {
+		return fmt.Errorf("failed to create metadata table: %w", err)
+	}
+
+	current, err := store.getLayoutVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to get layout version: %w", err)
+	}
+
+	if locker, ok := store.(layoutLocker); ok && current < latestLayoutVersion(steps) {
+		unlock, err := locker.lockLayout(ctx, db)
+		if err != nil {
+			return fmt.Errorf("failed to lock layout upgrade: %w", err)
+		}
+		defer unlock()
+
+		// Another run may have upgraded the layout before the lock was taken
+		if current, err = store.getLayoutVersion(ctx, db); err != nil {
+			return fmt.Errorf("failed to get layout version: %w", err)
+		}
+	}
+
+	for _, step := range steps {
+		if step.version <= current {
+			continue
+		}
+
+		if err := step.apply(ctx, db); err != nil {
+			return fmt.Errorf("failed to upgrade layout to version %d (%s): %w", step.version, step.description, err)
+		}
+
+		if err := store.setLayoutVersion(ctx, db, step.version); err != nil {
+			return fmt.Errorf("failed to set layout version %d: %w", step.version, err)
+		}
+	}
+
+	return nil
+}
+
+// latestLayoutVersion returns the version of the last step
+func latestLayoutVersion(steps []layoutStep) int {
+	if len(steps) == 0 {
+		return 0
+	}
+	return steps[len(steps)-1].version
+}
+
+// scanLayoutVersion reads the layout version stored as a string in a metadata table, 0 when not stored yet
+func scanLayoutVersion(row *sql.Row) (int, error) {
+	var value string
+	err := row.Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(value)
+}
+
+// baselineRecordsReader is implemented by drivers that can read the applied migrations from tables whose layout
+// is not upgraded yet, selecting only date, name and applied_at, so that read-only commands work before the next
+// command writing to the database upgrades the layout
+type baselineRecordsReader interface {
+	getBaselineAppliedMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error)
+}
+
+// queryBaselineRecords returns the records of a query selecting date, name and applied_at
+func queryBaselineRecords(ctx context.Context, db *sql.DB, query string) ([]MigrationRecord, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var migrations []MigrationRecord
+	for rows.Next() {
+		var m MigrationRecord
+		if err := rows.Scan(&m.Date, &m.Name, &m.AppliedAt); err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+
+	return migrations, rows.Err()
+}
+
+// trackingColumnNames are the names of the columns, in the order drivers select and insert them
+var trackingColumnNames = []string{
+	"environment",
+	"duration_ms",
+	"os_user",
+	"hostname",
+	"amigo_version",
+	"app_version",
+	"kind",
+	"checksum",
+}
+
+// trackingValues returns the values of the tracking columns of a record, in trackingColumnNames order
+func trackingValues(m MigrationRecord) []any {
+	return []any{
+		m.Environment,
+		m.Duration.Milliseconds(),
+		m.OSUser,
+		m.Hostname,
+		m.AmigoVersion,
+		m.AppVersion,
+		string(m.Kind),
+		m.Checksum,
+	}
+}
+
+// scanMigrationRecord scans a row selected with date, name, applied_at followed by the tracking columns
+func scanMigrationRecord(scan func(dest ...any) error) (MigrationRecord, error) {
+	var m MigrationRecord
+	var durationMs int64
+	var kind string
+
+	err := scan(&m.Date, &m.Name, &m.AppliedAt,
+		&m.Environment, &durationMs, &m.OSUser, &m.Hostname, &m.AmigoVersion, &m.AppVersion, &kind, &m.Checksum)
+	if err != nil {
+		return m, err
+	}
+
+	m.Duration = time.Duration(durationMs) * time.Millisecond
+	m.Kind = MigrationKind(kind)
+
+	return m, nil
+}
+
+// scanNamedRecord scans a row of the tables keyed by name (repeatable migrations and seeds), selected with
+// name, applied_at followed by the tracking columns
+func scanNamedRecord(scan func(dest ...any) error) (MigrationRecord, error) {
+	return scanMigrationRecord(func(dest ...any) error {
+		// records keyed by name have no date
+		return scan(dest[1:]...)
+	})
+}
+
+// historyColumnNames are the columns of the history table, in the order drivers select and insert them
+var historyColumnNames = []string{
+	"batch",
+	"date",
+	"name",
+	"direction",
+	"status",
+	"error",
+	"started_at",
+	"duration_ms",
+	"environment",
+	"os_user",
+	"hostname",
+	"amigo_version",
+	"app_version",
+	"kind",
+}
+
+// historyValues returns the values of a history entry, in historyColumnNames order
+func historyValues(h HistoryRecord) []any {
+	return []any{
+		h.Batch,
+		h.Date,
+		h.Name,
+		string(h.Direction),
+		string(h.Status),
+		h.Error,
+		h.StartedAt.UTC(),
+		h.Duration.Milliseconds(),
+		h.Environment,
+		h.OSUser,
+		h.Hostname,
+		h.AmigoVersion,
+		h.AppVersion,
+		string(h.Kind),
+	}
+}
+
+// scanHistoryRecord scans a row selected with historyColumnNames
+func scanHistoryRecord(scan func(dest ...any) error) (HistoryRecord, error) {
+	var h HistoryRecord
+	var direction, status, kind string
+	var durationMs int64
+
+	err := scan(&h.Batch, &h.Date, &h.Name, &direction, &status, &h.Error, &h.StartedAt, &durationMs,
+		&h.Environment, &h.OSUser, &h.Hostname, &h.AmigoVersion, &h.AppVersion, &kind)
+	if err != nil {
+		return h, err
+	}
+
+	h.Direction = MigrationDirection(direction)
+	h.Status = HistoryStatus(status)
+	h.Duration = time.Duration(durationMs) * time.Millisecond
+	h.Kind = MigrationKind(kind)
+
+	return h, nil
+}
+
+// historySelectQuery builds the query returning the history entries matching the filter.
+// placeholder returns the bind parameter syntax of the driver for the n-th argument (starting at 1).
+func historySelectQuery(table, orderBy string, filter HistoryFilter, placeholder func(n int) string) (string, []any) {
+	var args []any
+
+	query := fmt.Sprintf(`SELECT %s FROM %s`, strings.Join(historyColumnNames, ", "), table)
+	if filter.Date != 0 {
+		args = append(args, filter.Date)
+		query += fmt.Sprintf(` WHERE date = %s`, placeholder(len(args)))
+	}
+
+	query += ` ORDER BY ` + orderBy
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, filter.Limit)
+	}
+
+	return query, args
+}
+
+// historyInsertQuery builds the query appending entries to the history table.
+// placeholder returns the bind parameter syntax of the driver for the n-th argument (starting at 1).
+func historyInsertQuery(table string, list []HistoryRecord, placeholder func(n int) string) (string, []any) {
+	placeholders := make([]string, len(list))
+	args := make([]any, 0, len(list)*len(historyColumnNames))
+	for i, h := range list {
+		rowPlaceholders := make([]string, len(historyColumnNames))
+		for j := range rowPlaceholders {
+			rowPlaceholders[j] = placeholder(i*len(historyColumnNames) + j + 1)
+		}
+		placeholders[i] = "(" + strings.Join(rowPlaceholders, ", ") + ")"
+		args = append(args, historyValues(h)...)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES %s`,
+		table, strings.Join(historyColumnNames, ", "), strings.Join(placeholders, ", "))
+
+	return query, args
+}
+
+// dollarPlaceholder returns the PostgreSQL bind parameter for the n-th argument
+func dollarPlaceholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// questionPlaceholder returns the SQLite and ClickHouse bind parameter
+func questionPlaceholder(int) string {
+	return "?"
+}
@@ -0,0 +1,100 @@
+package amigo
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// lockingLayoutStore is a layout store with a lock, holding the version in memory
+type lockingLayoutStore struct {
+	version int
+	// lockErr fails the lock, upgradedByOther is the version another run upgraded to before the lock was taken
+	lockErr         error
+	upgradedByOther int
+	locked          bool
+	unlocked        bool
+}
+
+func (s *lockingLayoutStore) createMetaTable(ctx context.Context, db sqlExecutor) error { return nil }
+
+func (s *lockingLayoutStore) getLayoutVersion(ctx context.Context, db sqlExecutor) (int, error) {
+	return s.version, nil
+}
+
+func (s *lockingLayoutStore) setLayoutVersion(ctx context.Context, db sqlExecutor, version int) error {
+	s.version = version
+	return nil
+}
+
+func (s *lockingLayoutStore) lockLayout(ctx context.Context, db sqlExecutor) (func(), error) {
+	if s.lockErr != nil {
+		return nil, s.lockErr
+	}
+	s.locked = true
+	if s.upgradedByOther > 0 {
+		s.version = s.upgradedByOther
+	}
+	return func() { s.unlocked = true }, nil
+}
+
+func Test_upgradeLayout_lock(t *testing.T) {
+	var applied []int
+	steps := []layoutStep{{version: 1}, {version: 2}}
+	for i := range steps {
+		version := steps[i].version
+		steps[i].apply = func(ctx context.Context, db sqlExecutor) error {
+			applied = append(applied, version)
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name         string
+		store        *lockingLayoutStore
+		wantErr      error
+		wantApplied  int
+		wantLocked   bool
+		wantUnlocked bool
+	}{
+		{
+			name:         "upgrade",
+			store:        &lockingLayoutStore{},
+			wantApplied:  2,
+			wantLocked:   true,
+			wantUnlocked: true,
+		},
+		{
+			name:    "locked by another run",
+			store:   &lockingLayoutStore{lockErr: ErrLayoutUpgradeLocked},
+			wantErr: ErrLayoutUpgradeLocked,
+		},
+		{
+			name:         "upgraded by another run while waiting",
+			store:        &lockingLayoutStore{upgradedByOther: 2},
+			wantLocked:   true,
+			wantUnlocked: true,
+		},
+		{
+			name:  "up to date",
+			store: &lockingLayoutStore{version: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied = nil
+
+			err := upgradeLayout(t.Context(), nil, tt.store, steps)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("upgradeLayout() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(applied) != tt.wantApplied {
+				t.Errorf("applied steps %v, want %d", applied, tt.wantApplied)
+			}
+			if tt.store.locked != tt.wantLocked || tt.store.unlocked != tt.wantUnlocked {
+				t.Errorf("locked = %v, unlocked = %v, want %v and %v", tt.store.locked, tt.store.unlocked, tt.wantLocked, tt.wantUnlocked)
+			}
+		})
+	}
+}
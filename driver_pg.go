@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"hash/fnv"
+	"strconv"
 	"strings"
 )
 
@@ -18,21 +20,219 @@ func NewPostgresDriver(tableName string) *PostgresDriver {
 	return &PostgresDriver{tableName: tableName}
 }
 
+// CreateSchemaMigrationsTableIfNotExists creates amigo's tables and upgrades them to the latest layout.
+// Concurrent runs are serialized with an advisory lock.
 func (d *PostgresDriver) CreateSchemaMigrationsTableIfNotExists(ctx context.Context, db *sql.DB) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	lockKey := advisoryLockKey(d.tableName)
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	return upgradeLayout(ctx, conn, d, d.layoutSteps())
+}
+
+// layoutSteps returns the upgrades of amigo's tables, see layoutStep
+func (d *PostgresDriver) layoutSteps() []layoutStep {
+	return []layoutStep{
+		{
+			version:     1,
+			description: "create schema migrations table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				_, err := db.ExecContext(ctx, fmt.Sprintf(`
+					CREATE TABLE IF NOT EXISTS %s (
+						date BIGINT PRIMARY KEY,
+						name VARCHAR(255) NOT NULL,
+						applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+					)
+				`, d.tableName))
+				return err
+			},
+		},
+		{
+			version:     2,
+			description: "add environment column",
+			apply: d.addColumns(
+				"environment VARCHAR(255) NOT NULL DEFAULT ''",
+			),
+		},
+		{
+			version:     3,
+			description: "add audit columns",
+			apply: d.addColumns(
+				"duration_ms BIGINT NOT NULL DEFAULT 0",
+				"os_user VARCHAR(255) NOT NULL DEFAULT ''",
+				"hostname VARCHAR(255) NOT NULL DEFAULT ''",
+				"amigo_version VARCHAR(255) NOT NULL DEFAULT ''",
+				"app_version VARCHAR(255) NOT NULL DEFAULT ''",
+				"kind VARCHAR(16) NOT NULL DEFAULT ''",
+			),
+		},
+		{
+			version:     4,
+			description: "create history table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				_, err := db.ExecContext(ctx, fmt.Sprintf(`
+					CREATE TABLE IF NOT EXISTS %s (
+						id BIGSERIAL PRIMARY KEY,
+						batch BIGINT NOT NULL,
+						date BIGINT NOT NULL,
+						name VARCHAR(255) NOT NULL,
+						direction VARCHAR(8) NOT NULL,
+						status VARCHAR(16) NOT NULL,
+						error TEXT NOT NULL DEFAULT '',
+						started_at TIMESTAMPTZ NOT NULL,
+						duration_ms BIGINT NOT NULL DEFAULT 0,
+						environment VARCHAR(255) NOT NULL DEFAULT '',
+						os_user VARCHAR(255) NOT NULL DEFAULT '',
+						hostname VARCHAR(255) NOT NULL DEFAULT '',
+						amigo_version VARCHAR(255) NOT NULL DEFAULT '',
+						app_version VARCHAR(255) NOT NULL DEFAULT '',
+						kind VARCHAR(16) NOT NULL DEFAULT ''
+					)
+				`, d.historyTableName()))
+				return err
+			},
+		},
+		{
+			version:     5,
+			description: "add checksum column",
+			apply: d.addColumns(
+				"checksum VARCHAR(64) NOT NULL DEFAULT ''",
+			),
+		},
+		{
+			version:     6,
+			description: "create repeatable migrations table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				return d.createNamedRecordsTable(ctx, db, d.repeatableTableName())
+			},
+		},
+		{
+			version:     7,
+			description: "create seeds table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				return d.createNamedRecordsTable(ctx, db, d.seedsTableName())
+			},
+		},
+	}
+}
+
+// createNamedRecordsTable creates a table tracking the last run of repeatable migrations or seeds, by name
+func (d *PostgresDriver) createNamedRecordsTable(ctx context.Context, db sqlExecutor, table string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			name VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			environment VARCHAR(255) NOT NULL DEFAULT '',
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			os_user VARCHAR(255) NOT NULL DEFAULT '',
+			hostname VARCHAR(255) NOT NULL DEFAULT '',
+			amigo_version VARCHAR(255) NOT NULL DEFAULT '',
+			app_version VARCHAR(255) NOT NULL DEFAULT '',
+			kind VARCHAR(16) NOT NULL DEFAULT '',
+			checksum VARCHAR(64) NOT NULL DEFAULT ''
+		)
+	`, table))
+	return err
+}
+
+// addColumns returns a layout step function adding the given column definitions to the schema migrations table
+func (d *PostgresDriver) addColumns(definitions ...string) func(ctx context.Context, db sqlExecutor) error {
+	return func(ctx context.Context, db sqlExecutor) error {
+		for _, definition := range definitions {
+			query := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s`, d.tableName, definition)
+			if _, err := db.ExecContext(ctx, query); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// historyTableName returns the name of the append-only history table
+func (d *PostgresDriver) historyTableName() string {
+	return d.tableName + "_history"
+}
+
+// repeatableTableName returns the name of the table tracking the last run of repeatable migrations
+func (d *PostgresDriver) repeatableTableName() string {
+	return d.tableName + "_repeatable"
+}
+
+// seedsTableName returns the name of the table tracking the last run of seeds
+func (d *PostgresDriver) seedsTableName() string {
+	return d.tableName + "_seeds"
+}
+
+// metaTableName returns the name of the table storing metadata such as the layout version
+func (d *PostgresDriver) metaTableName() string {
+	return d.tableName + "_meta"
+}
+
+func (d *PostgresDriver) createMetaTable(ctx context.Context, db sqlExecutor) error {
 	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
-			date BIGINT PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			key VARCHAR(255) PRIMARY KEY,
+			value VARCHAR(255) NOT NULL
 		)
-	`, d.tableName)
+	`, d.metaTableName())
 
 	_, err := db.ExecContext(ctx, query)
 	return err
 }
 
+func (d *PostgresDriver) getLayoutVersion(ctx context.Context, db sqlExecutor) (int, error) {
+	query := fmt.Sprintf(`SELECT value FROM %s WHERE key = $1`, d.metaTableName())
+	return scanLayoutVersion(db.QueryRowContext(ctx, query, layoutVersionKey))
+}
+
+func (d *PostgresDriver) setLayoutVersion(ctx context.Context, db sqlExecutor, version int) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (key, value) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value
+	`, d.metaTableName())
+
+	_, err := db.ExecContext(ctx, query, layoutVersionKey, strconv.Itoa(version))
+	return err
+}
+
+func (d *PostgresDriver) SchemaLayoutVersion(ctx context.Context, db *sql.DB) (current, latest int, err error) {
+	latest = latestLayoutVersion(d.layoutSteps())
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, d.metaTableName()).Scan(&exists); err != nil {
+		return 0, latest, err
+	}
+	if !exists {
+		return 0, latest, nil
+	}
+
+	current, err = d.getLayoutVersion(ctx, db)
+	return current, latest, err
+}
+
+func (d *PostgresDriver) SchemaMigrationsTableExists(ctx context.Context, db *sql.DB) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, d.tableName).Scan(&exists)
+	return exists, err
+}
+
+// getBaselineAppliedMigrations returns the applied migrations with the columns of the first layout, see
+// baselineRecordsReader
+func (d *PostgresDriver) getBaselineAppliedMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	return queryBaselineRecords(ctx, db, fmt.Sprintf(`SELECT date, name, applied_at FROM %s ORDER BY date ASC`, d.tableName))
+}
+
 func (d *PostgresDriver) GetAppliedMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
-	query := fmt.Sprintf(`SELECT date, name, applied_at FROM %s ORDER BY date ASC`, d.tableName)
+	query := fmt.Sprintf(`SELECT date, name, applied_at, %s FROM %s ORDER BY date ASC`,
+		strings.Join(trackingColumnNames, ", "), d.tableName)
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
@@ -42,8 +242,8 @@ func (d *PostgresDriver) GetAppliedMigrations(ctx context.Context, db *sql.DB) (
 
 	var migrations []MigrationRecord
 	for rows.Next() {
-		var m MigrationRecord
-		if err := rows.Scan(&m.Date, &m.Name, &m.AppliedAt); err != nil {
+		m, err := scanMigrationRecord(rows.Scan)
+		if err != nil {
 			return nil, err
 		}
 		migrations = append(migrations, m)
@@ -53,18 +253,34 @@ func (d *PostgresDriver) GetAppliedMigrations(ctx context.Context, db *sql.DB) (
 }
 
 func (d *PostgresDriver) InsertMigrations(ctx context.Context, db *sql.DB, list []MigrationRecord) error {
+	return d.insertMigrations(ctx, db, list)
+}
+
+// InsertMigrationsTx records migrations inside a transaction, see SchemaLoader
+func (d *PostgresDriver) InsertMigrationsTx(ctx context.Context, tx *sql.Tx, list []MigrationRecord) error {
+	return d.insertMigrations(ctx, tx, list)
+}
+
+func (d *PostgresDriver) insertMigrations(ctx context.Context, db sqlExecutor, list []MigrationRecord) error {
 	if len(list) == 0 {
 		return nil
 	}
 
+	columnCount := 2 + len(trackingColumnNames)
 	placeholders := make([]string, len(list))
-	args := make([]any, 0, len(list)*2)
+	args := make([]any, 0, len(list)*columnCount)
 	for i, m := range list {
-		placeholders[i] = fmt.Sprintf("($%d, $%d)", i*2+1, i*2+2)
+		rowPlaceholders := make([]string, columnCount)
+		for j := range rowPlaceholders {
+			rowPlaceholders[j] = fmt.Sprintf("$%d", i*columnCount+j+1)
+		}
+		placeholders[i] = "(" + strings.Join(rowPlaceholders, ", ") + ")"
 		args = append(args, m.Date, m.Name)
+		args = append(args, trackingValues(m)...)
 	}
 
-	query := fmt.Sprintf(`INSERT INTO %s (date, name) VALUES %s`, d.tableName, strings.Join(placeholders, ", "))
+	query := fmt.Sprintf(`INSERT INTO %s (date, name, %s) VALUES %s`,
+		d.tableName, strings.Join(trackingColumnNames, ", "), strings.Join(placeholders, ", "))
 	_, err := db.ExecContext(ctx, query, args...)
 	return err
 }
@@ -86,6 +302,275 @@ func (d *PostgresDriver) DeleteMigrations(ctx context.Context, db *sql.DB, dates
 	return err
 }
 
+func (d *PostgresDriver) GetRepeatableMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	return d.getNamedRecords(ctx, db, d.repeatableTableName())
+}
+
+func (d *PostgresDriver) UpsertRepeatableMigration(ctx context.Context, db *sql.DB, record MigrationRecord) error {
+	return d.upsertNamedRecord(ctx, db, d.repeatableTableName(), record)
+}
+
+func (d *PostgresDriver) GetAppliedSeeds(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	return d.getNamedRecords(ctx, db, d.seedsTableName())
+}
+
+func (d *PostgresDriver) UpsertSeed(ctx context.Context, db *sql.DB, record MigrationRecord) error {
+	return d.upsertNamedRecord(ctx, db, d.seedsTableName(), record)
+}
+
+// getNamedRecords returns the records of a table keyed by name, see createNamedRecordsTable
+func (d *PostgresDriver) getNamedRecords(ctx context.Context, db *sql.DB, table string) ([]MigrationRecord, error) {
+	query := fmt.Sprintf(`SELECT name, applied_at, %s FROM %s ORDER BY name ASC`,
+		strings.Join(trackingColumnNames, ", "), table)
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []MigrationRecord
+	for rows.Next() {
+		m, err := scanNamedRecord(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, m)
+	}
+
+	return records, rows.Err()
+}
+
+// upsertNamedRecord inserts a record in a table keyed by name, replacing the previous record of the same name
+func (d *PostgresDriver) upsertNamedRecord(ctx context.Context, db *sql.DB, table string, record MigrationRecord) error {
+	placeholders := make([]string, 1+len(trackingColumnNames))
+	updates := []string{"applied_at = EXCLUDED.applied_at"}
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	for _, column := range trackingColumnNames {
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (name, applied_at, %s) VALUES ($1, NOW(), %s)
+		ON CONFLICT (name) DO UPDATE SET %s
+	`, table, strings.Join(trackingColumnNames, ", "),
+		strings.Join(placeholders[1:], ", "), strings.Join(updates, ", "))
+
+	args := append([]any{record.Name}, trackingValues(record)...)
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (d *PostgresDriver) InsertHistory(ctx context.Context, db *sql.DB, list []HistoryRecord) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	query, args := historyInsertQuery(d.historyTableName(), list, dollarPlaceholder)
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (d *PostgresDriver) GetHistory(ctx context.Context, db *sql.DB, filter HistoryFilter) ([]HistoryRecord, error) {
+	query, args := historySelectQuery(d.historyTableName(), "id DESC", filter, dollarPlaceholder)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoryRecord
+	for rows.Next() {
+		h, err := scanHistoryRecord(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
+
+func (d *PostgresDriver) NextHistoryBatch(ctx context.Context, db *sql.DB) (int64, error) {
+	var batch int64
+	query := fmt.Sprintf(`SELECT COALESCE(MAX(batch), 0) + 1 FROM %s`, d.historyTableName())
+	err := db.QueryRowContext(ctx, query).Scan(&batch)
+	return batch, err
+}
+
+// advisoryLockKey returns the key of the advisory lock protecting amigo's tables named after tableName
+func advisoryLockKey(tableName string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("amigo:" + tableName))
+	return int64(h.Sum64())
+}
+
 func (d *PostgresDriver) Name() string {
 	return "postgres"
 }
+
+// amigoTableNames returns the names of amigo's own tables, left out of schema dumps
+func (d *PostgresDriver) amigoTableNames() []string {
+	return []string{d.tableName, d.historyTableName(), d.repeatableTableName(), d.seedsTableName(), d.metaTableName()}
+}
+
+// pgNotAmigoTable filters out amigo's own tables, given as a list of names, from the tables of an alias with an
+// oid column. Names are resolved like the driver's queries do, with the search path when not qualified, so that
+// tables of the same name in other schemas are kept.
+const pgNotAmigoTable = `NOT EXISTS (SELECT 1 FROM unnest(ARRAY[%s]::text[]) AS amigo(name) WHERE to_regclass(amigo.name) = %s.oid)`
+
+// pgUserNamespace filters the namespace n on the schemas created by users
+const pgUserNamespace = `n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'`
+
+// pgNotFromExtension filters out the objects created by an extension, object being an alias with an oid column
+const pgNotFromExtension = `NOT EXISTS (SELECT 1 FROM pg_depend e WHERE e.objid = %s.oid AND e.deptype = 'e')`
+
+// DumpSchema returns the schema of the user schemas built from the catalog: schemas, extensions, enum types,
+// functions, sequences, tables, constraints, indexes, views and triggers, each kind in name order except views
+// which come after the views they select from. See SchemaDumper.
+func (d *PostgresDriver) DumpSchema(ctx context.Context, db *sql.DB) (string, error) {
+	amigoTables := sqlStringList(d.amigoTableNames())
+	notAmigoTable := func(alias string) string {
+		return fmt.Sprintf(pgNotAmigoTable, amigoTables, alias)
+	}
+
+	queries := []struct {
+		kind  string
+		query string
+	}{
+		{"schemas", `
+			SELECT format('CREATE SCHEMA IF NOT EXISTS %I', n.nspname) FROM pg_namespace n
+			WHERE ` + pgUserNamespace + ` AND n.nspname <> 'public'
+			ORDER BY n.nspname`},
+		{"extensions", `
+			SELECT format('CREATE EXTENSION IF NOT EXISTS %I', extname) FROM pg_extension
+			WHERE extname <> 'plpgsql'
+			ORDER BY extname`},
+		{"enum types", `
+			SELECT format('CREATE TYPE %I.%I AS ENUM (%s)', n.nspname, t.typname,
+				string_agg(quote_literal(en.enumlabel), ', ' ORDER BY en.enumsortorder))
+			FROM pg_type t
+			JOIN pg_namespace n ON n.oid = t.typnamespace
+			JOIN pg_enum en ON en.enumtypid = t.oid
+			WHERE ` + pgUserNamespace + ` AND ` + fmt.Sprintf(pgNotFromExtension, "t") + `
+			GROUP BY n.nspname, t.typname
+			ORDER BY n.nspname, t.typname`},
+		{"functions", `
+			SELECT pg_get_functiondef(p.oid) FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE p.prokind IN ('f', 'p') AND ` + pgUserNamespace + ` AND ` + fmt.Sprintf(pgNotFromExtension, "p") + `
+			ORDER BY n.nspname, p.proname, pg_get_function_identity_arguments(p.oid)`},
+		{"sequences", `
+			SELECT format('CREATE SEQUENCE %I.%I AS %s INCREMENT BY %s MINVALUE %s MAXVALUE %s START WITH %s%s',
+				n.nspname, c.relname, format_type(s.seqtypid, NULL), s.seqincrement, s.seqmin, s.seqmax, s.seqstart,
+				CASE WHEN s.seqcycle THEN ' CYCLE' ELSE '' END)
+			FROM pg_sequence s
+			JOIN pg_class c ON c.oid = s.seqrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE ` + pgUserNamespace + ` AND ` + fmt.Sprintf(pgNotFromExtension, "c") + `
+			AND NOT EXISTS (SELECT 1 FROM pg_depend i WHERE i.objid = c.oid AND i.deptype = 'i')
+			ORDER BY n.nspname, c.relname`},
+		{"tables", `
+			SELECT CASE WHEN c.relispartition THEN
+				format('CREATE TABLE %I.%I PARTITION OF %s %s', n.nspname, c.relname,
+					(SELECT format('%I.%I', pn.nspname, pc.relname) FROM pg_inherits inh
+						JOIN pg_class pc ON pc.oid = inh.inhparent
+						JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+						WHERE inh.inhrelid = c.oid),
+					pg_get_expr(c.relpartbound, c.oid))
+			ELSE
+				format(E'CREATE TABLE %I.%I (\n%s\n)%s', n.nspname, c.relname,
+					(SELECT string_agg(format('    %I %s', a.attname, format_type(a.atttypid, a.atttypmod))
+						|| CASE
+							WHEN a.attidentity = 'a' THEN ' GENERATED ALWAYS AS IDENTITY'
+							WHEN a.attidentity = 'd' THEN ' GENERATED BY DEFAULT AS IDENTITY'
+							WHEN a.attgenerated = 's' THEN format(' GENERATED ALWAYS AS (%s) STORED', pg_get_expr(ad.adbin, ad.adrelid))
+							WHEN ad.adbin IS NOT NULL THEN format(' DEFAULT %s', pg_get_expr(ad.adbin, ad.adrelid))
+							ELSE ''
+						END
+						|| CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END,
+						E',\n' ORDER BY a.attnum)
+					FROM pg_attribute a
+					LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+					WHERE a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped),
+					CASE WHEN c.relkind = 'p' THEN ' PARTITION BY ' || pg_get_partkeydef(c.oid) ELSE '' END)
+			END
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'p') AND ` + pgUserNamespace + ` AND ` + fmt.Sprintf(pgNotFromExtension, "c") + `
+			AND ` + notAmigoTable("c") + `
+			ORDER BY c.relispartition, n.nspname, c.relname`},
+		{"sequence ownerships", `
+			SELECT format('ALTER SEQUENCE %I.%I OWNED BY %I.%I.%I', n.nspname, c.relname, tn.nspname, t.relname, a.attname)
+			FROM pg_depend dep
+			JOIN pg_class c ON c.oid = dep.objid AND c.relkind = 'S'
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_class t ON t.oid = dep.refobjid
+			JOIN pg_namespace tn ON tn.oid = t.relnamespace
+			JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = dep.refobjsubid
+			WHERE dep.deptype = 'a' AND dep.classid = 'pg_class'::regclass AND ` + pgUserNamespace + `
+			AND ` + notAmigoTable("t") + `
+			ORDER BY n.nspname, c.relname`},
+		{"constraints", `
+			SELECT format('ALTER TABLE %I.%I ADD CONSTRAINT %I %s', n.nspname, c.relname, con.conname,
+				pg_get_constraintdef(con.oid))
+			FROM pg_constraint con
+			JOIN pg_class c ON c.oid = con.conrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE con.contype IN ('p', 'u', 'c', 'x', 'f') AND con.conislocal AND c.relkind IN ('r', 'p')
+			AND ` + pgUserNamespace + ` AND ` + notAmigoTable("c") + `
+			ORDER BY con.contype = 'f', n.nspname, c.relname, con.conname`},
+		{"indexes", `
+			SELECT pg_get_indexdef(i.indexrelid) FROM pg_index i
+			JOIN pg_class ic ON ic.oid = i.indexrelid
+			JOIN pg_class c ON c.oid = i.indrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE NOT ic.relispartition AND ` + pgUserNamespace + ` AND ` + notAmigoTable("c") + `
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x'))
+			ORDER BY n.nspname, ic.relname`},
+		// The level of a view is the length of the longest chain of views it selects from, so that views come
+		// after their dependencies, found through the rewrite rules of the views
+		{"views", `
+			WITH RECURSIVE views AS (
+				SELECT c.oid, c.relkind, n.nspname, c.relname FROM pg_class c
+				JOIN pg_namespace n ON n.oid = c.relnamespace
+				WHERE c.relkind IN ('v', 'm') AND ` + pgUserNamespace + ` AND ` + fmt.Sprintf(pgNotFromExtension, "c") + `
+			), dependencies AS (
+				SELECT DISTINCT r.ev_class AS view_oid, dep.refobjid AS dependency_oid FROM pg_rewrite r
+				JOIN pg_depend dep ON dep.classid = 'pg_rewrite'::regclass AND dep.objid = r.oid
+				WHERE dep.refclassid = 'pg_class'::regclass AND dep.refobjid <> r.ev_class
+				AND r.ev_class IN (SELECT oid FROM views) AND dep.refobjid IN (SELECT oid FROM views)
+			), levels (oid, level) AS (
+				SELECT oid, 0 FROM views
+				UNION ALL
+				SELECT dependencies.view_oid, levels.level + 1 FROM levels
+				JOIN dependencies ON dependencies.dependency_oid = levels.oid
+			)
+			SELECT format('CREATE %sVIEW %I.%I AS %s', CASE WHEN v.relkind = 'm' THEN 'MATERIALIZED ' ELSE '' END,
+				v.nspname, v.relname, rtrim(btrim(pg_get_viewdef(v.oid)), ';'))
+			FROM views v
+			JOIN (SELECT oid, max(level) AS level FROM levels GROUP BY oid) l ON l.oid = v.oid
+			ORDER BY l.level, v.nspname, v.relname`},
+		{"triggers", `
+			SELECT pg_get_triggerdef(t.oid) FROM pg_trigger t
+			JOIN pg_class c ON c.oid = t.tgrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE NOT t.tgisinternal AND ` + pgUserNamespace + ` AND ` + notAmigoTable("c") + `
+			ORDER BY n.nspname, c.relname, t.tgname`},
+	}
+
+	// Function bodies may use tables created after them, as pg_dump does
+	statements := []string{"SET LOCAL check_function_bodies = false"}
+	for _, q := range queries {
+		values, err := queryStrings(ctx, db, q.query)
+		if err != nil {
+			return "", fmt.Errorf("failed to dump %s: %w", q.kind, err)
+		}
+		statements = append(statements, values...)
+	}
+
+	return joinSchemaStatements(statements), nil
+}
@@ -0,0 +1,54 @@
+package amigo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// queryStrings returns the first column of every row of a query
+func queryStrings(ctx context.Context, db sqlExecutor, query string, args ...any) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// joinSchemaStatements returns DDL statements as a schema dump: one statement per paragraph, each ending
+// with a semicolon
+func joinSchemaStatements(statements []string) string {
+	var b strings.Builder
+	for _, statement := range statements {
+		statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+		if statement == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(statement)
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+// sqlStringList returns names as a list of SQL string literals, for IN clauses
+func sqlStringList(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf("'%s'", strings.ReplaceAll(name, "'", "''")))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -18,21 +19,234 @@ func NewSQLiteDriver(tableName string) *SQLiteDriver {
 	return &SQLiteDriver{tableName: tableName}
 }
 
+// CreateSchemaMigrationsTableIfNotExists creates amigo's tables and upgrades them to the latest layout.
+// The upgrade runs in a transaction, which SQLite serializes with other writers.
 func (d *SQLiteDriver) CreateSchemaMigrationsTableIfNotExists(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := upgradeLayout(ctx, tx, d, d.layoutSteps()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// layoutSteps returns the upgrades of amigo's tables, see layoutStep
+func (d *SQLiteDriver) layoutSteps() []layoutStep {
+	return []layoutStep{
+		{
+			version:     1,
+			description: "create schema migrations table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				_, err := db.ExecContext(ctx, fmt.Sprintf(`
+					CREATE TABLE IF NOT EXISTS %s (
+						date INTEGER PRIMARY KEY,
+						name TEXT NOT NULL,
+						applied_at DATETIME NOT NULL DEFAULT (DATETIME('now', 'utc'))
+					)
+				`, d.tableName))
+				return err
+			},
+		},
+		{
+			version:     2,
+			description: "add environment column",
+			apply: d.addColumns(
+				[2]string{"environment", "TEXT NOT NULL DEFAULT ''"},
+			),
+		},
+		{
+			version:     3,
+			description: "add audit columns",
+			apply: d.addColumns(
+				[2]string{"duration_ms", "INTEGER NOT NULL DEFAULT 0"},
+				[2]string{"os_user", "TEXT NOT NULL DEFAULT ''"},
+				[2]string{"hostname", "TEXT NOT NULL DEFAULT ''"},
+				[2]string{"amigo_version", "TEXT NOT NULL DEFAULT ''"},
+				[2]string{"app_version", "TEXT NOT NULL DEFAULT ''"},
+				[2]string{"kind", "TEXT NOT NULL DEFAULT ''"},
+			),
+		},
+		{
+			version:     4,
+			description: "create history table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				_, err := db.ExecContext(ctx, fmt.Sprintf(`
+					CREATE TABLE IF NOT EXISTS %s (
+						id INTEGER PRIMARY KEY AUTOINCREMENT,
+						batch INTEGER NOT NULL,
+						date INTEGER NOT NULL,
+						name TEXT NOT NULL,
+						direction TEXT NOT NULL,
+						status TEXT NOT NULL,
+						error TEXT NOT NULL DEFAULT '',
+						started_at DATETIME NOT NULL,
+						duration_ms INTEGER NOT NULL DEFAULT 0,
+						environment TEXT NOT NULL DEFAULT '',
+						os_user TEXT NOT NULL DEFAULT '',
+						hostname TEXT NOT NULL DEFAULT '',
+						amigo_version TEXT NOT NULL DEFAULT '',
+						app_version TEXT NOT NULL DEFAULT '',
+						kind TEXT NOT NULL DEFAULT ''
+					)
+				`, d.historyTableName()))
+				return err
+			},
+		},
+		{
+			version:     5,
+			description: "add checksum column",
+			apply: d.addColumns(
+				[2]string{"checksum", "TEXT NOT NULL DEFAULT ''"},
+			),
+		},
+		{
+			version:     6,
+			description: "create repeatable migrations table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				return d.createNamedRecordsTable(ctx, db, d.repeatableTableName())
+			},
+		},
+		{
+			version:     7,
+			description: "create seeds table",
+			apply: func(ctx context.Context, db sqlExecutor) error {
+				return d.createNamedRecordsTable(ctx, db, d.seedsTableName())
+			},
+		},
+	}
+}
+
+// createNamedRecordsTable creates a table tracking the last run of repeatable migrations or seeds, by name
+func (d *SQLiteDriver) createNamedRecordsTable(ctx context.Context, db sqlExecutor, table string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			name TEXT PRIMARY KEY,
+			applied_at DATETIME NOT NULL DEFAULT (DATETIME('now', 'utc')),
+			environment TEXT NOT NULL DEFAULT '',
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			os_user TEXT NOT NULL DEFAULT '',
+			hostname TEXT NOT NULL DEFAULT '',
+			amigo_version TEXT NOT NULL DEFAULT '',
+			app_version TEXT NOT NULL DEFAULT '',
+			kind TEXT NOT NULL DEFAULT '',
+			checksum TEXT NOT NULL DEFAULT ''
+		)
+	`, table))
+	return err
+}
+
+// addColumns returns a layout step function adding the given (name, definition) columns to the schema
+// migrations table. SQLite does not support ADD COLUMN IF NOT EXISTS, so existing columns are skipped.
+func (d *SQLiteDriver) addColumns(columns ...[2]string) func(ctx context.Context, db sqlExecutor) error {
+	return func(ctx context.Context, db sqlExecutor) error {
+		for _, column := range columns {
+			name, definition := column[0], column[1]
+
+			exists, err := d.columnExists(ctx, db, name)
+			if err != nil {
+				return fmt.Errorf("failed to check column %s: %w", name, err)
+			}
+			if exists {
+				continue
+			}
+
+			query := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, d.tableName, name, definition)
+			if _, err := db.ExecContext(ctx, query); err != nil {
+				return fmt.Errorf("failed to add column %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// historyTableName returns the name of the append-only history table
+func (d *SQLiteDriver) historyTableName() string {
+	return d.tableName + "_history"
+}
+
+// repeatableTableName returns the name of the table tracking the last run of repeatable migrations
+func (d *SQLiteDriver) repeatableTableName() string {
+	return d.tableName + "_repeatable"
+}
+
+// seedsTableName returns the name of the table tracking the last run of seeds
+func (d *SQLiteDriver) seedsTableName() string {
+	return d.tableName + "_seeds"
+}
+
+// metaTableName returns the name of the table storing metadata such as the layout version
+func (d *SQLiteDriver) metaTableName() string {
+	return d.tableName + "_meta"
+}
+
+// columnExists reports whether the schema migrations table has the given column
+func (d *SQLiteDriver) columnExists(ctx context.Context, db sqlExecutor, column string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+	err := db.QueryRowContext(ctx, query, d.tableName, column).Scan(&count)
+	return count > 0, err
+}
+
+// tableExists reports whether the given table exists
+func (d *SQLiteDriver) tableExists(ctx context.Context, db sqlExecutor, table string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	return count > 0, err
+}
+
+func (d *SQLiteDriver) createMetaTable(ctx context.Context, db sqlExecutor) error {
 	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
-			date INTEGER PRIMARY KEY,
-			name TEXT NOT NULL,
-			applied_at DATETIME NOT NULL DEFAULT (DATETIME('now', 'utc'))
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
 		)
-	`, d.tableName)
+	`, d.metaTableName())
 
 	_, err := db.ExecContext(ctx, query)
 	return err
 }
 
+func (d *SQLiteDriver) getLayoutVersion(ctx context.Context, db sqlExecutor) (int, error) {
+	query := fmt.Sprintf(`SELECT value FROM %s WHERE key = ?`, d.metaTableName())
+	return scanLayoutVersion(db.QueryRowContext(ctx, query, layoutVersionKey))
+}
+
+func (d *SQLiteDriver) setLayoutVersion(ctx context.Context, db sqlExecutor, version int) error {
+	query := fmt.Sprintf(`INSERT OR REPLACE INTO %s (key, value) VALUES (?, ?)`, d.metaTableName())
+	_, err := db.ExecContext(ctx, query, layoutVersionKey, strconv.Itoa(version))
+	return err
+}
+
+func (d *SQLiteDriver) SchemaLayoutVersion(ctx context.Context, db *sql.DB) (current, latest int, err error) {
+	latest = latestLayoutVersion(d.layoutSteps())
+
+	exists, err := d.tableExists(ctx, db, d.metaTableName())
+	if err != nil || !exists {
+		return 0, latest, err
+	}
+
+	current, err = d.getLayoutVersion(ctx, db)
+	return current, latest, err
+}
+
+func (d *SQLiteDriver) SchemaMigrationsTableExists(ctx context.Context, db *sql.DB) (bool, error) {
+	return d.tableExists(ctx, db, d.tableName)
+}
+
+// getBaselineAppliedMigrations returns the applied migrations with the columns of the first layout, see
+// baselineRecordsReader
+func (d *SQLiteDriver) getBaselineAppliedMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	return queryBaselineRecords(ctx, db, fmt.Sprintf(`SELECT date, name, applied_at FROM %s ORDER BY date ASC`, d.tableName))
+}
+
 func (d *SQLiteDriver) GetAppliedMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
-	query := fmt.Sprintf(`SELECT date, name, applied_at FROM %s ORDER BY date ASC`, d.tableName)
+	query := fmt.Sprintf(`SELECT date, name, applied_at, %s FROM %s ORDER BY date ASC`,
+		strings.Join(trackingColumnNames, ", "), d.tableName)
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
@@ -42,8 +256,8 @@ func (d *SQLiteDriver) GetAppliedMigrations(ctx context.Context, db *sql.DB) ([]
 
 	var migrations []MigrationRecord
 	for rows.Next() {
-		var m MigrationRecord
-		if err := rows.Scan(&m.Date, &m.Name, &m.AppliedAt); err != nil {
+		m, err := scanMigrationRecord(rows.Scan)
+		if err != nil {
 			return nil, err
 		}
 		migrations = append(migrations, m)
@@ -53,18 +267,31 @@ func (d *SQLiteDriver) GetAppliedMigrations(ctx context.Context, db *sql.DB) ([]
 }
 
 func (d *SQLiteDriver) InsertMigrations(ctx context.Context, db *sql.DB, list []MigrationRecord) error {
+	return d.insertMigrations(ctx, db, list)
+}
+
+// InsertMigrationsTx records migrations inside a transaction, see SchemaLoader
+func (d *SQLiteDriver) InsertMigrationsTx(ctx context.Context, tx *sql.Tx, list []MigrationRecord) error {
+	return d.insertMigrations(ctx, tx, list)
+}
+
+func (d *SQLiteDriver) insertMigrations(ctx context.Context, db sqlExecutor, list []MigrationRecord) error {
 	if len(list) == 0 {
 		return nil
 	}
 
+	columnCount := 2 + len(trackingColumnNames)
+	rowPlaceholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", columnCount), ", ") + ")"
 	placeholders := make([]string, len(list))
-	args := make([]any, 0, len(list)*2)
+	args := make([]any, 0, len(list)*columnCount)
 	for i, m := range list {
-		placeholders[i] = "(?, ?)"
+		placeholders[i] = rowPlaceholder
 		args = append(args, m.Date, m.Name)
+		args = append(args, trackingValues(m)...)
 	}
 
-	query := fmt.Sprintf(`INSERT INTO %s (date, name) VALUES %s`, d.tableName, strings.Join(placeholders, ", "))
+	query := fmt.Sprintf(`INSERT INTO %s (date, name, %s) VALUES %s`,
+		d.tableName, strings.Join(trackingColumnNames, ", "), strings.Join(placeholders, ", "))
 	_, err := db.ExecContext(ctx, query, args...)
 	return err
 }
@@ -86,6 +313,116 @@ func (d *SQLiteDriver) DeleteMigrations(ctx context.Context, db *sql.DB, dates [
 	return err
 }
 
+func (d *SQLiteDriver) GetRepeatableMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	return d.getNamedRecords(ctx, db, d.repeatableTableName())
+}
+
+func (d *SQLiteDriver) UpsertRepeatableMigration(ctx context.Context, db *sql.DB, record MigrationRecord) error {
+	return d.upsertNamedRecord(ctx, db, d.repeatableTableName(), record)
+}
+
+func (d *SQLiteDriver) GetAppliedSeeds(ctx context.Context, db *sql.DB) ([]MigrationRecord, error) {
+	return d.getNamedRecords(ctx, db, d.seedsTableName())
+}
+
+func (d *SQLiteDriver) UpsertSeed(ctx context.Context, db *sql.DB, record MigrationRecord) error {
+	return d.upsertNamedRecord(ctx, db, d.seedsTableName(), record)
+}
+
+// getNamedRecords returns the records of a table keyed by name, see createNamedRecordsTable
+func (d *SQLiteDriver) getNamedRecords(ctx context.Context, db *sql.DB, table string) ([]MigrationRecord, error) {
+	query := fmt.Sprintf(`SELECT name, applied_at, %s FROM %s ORDER BY name ASC`,
+		strings.Join(trackingColumnNames, ", "), table)
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []MigrationRecord
+	for rows.Next() {
+		m, err := scanNamedRecord(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, m)
+	}
+
+	return records, rows.Err()
+}
+
+// upsertNamedRecord inserts a record in a table keyed by name, replacing the previous record of the same name
+func (d *SQLiteDriver) upsertNamedRecord(ctx context.Context, db *sql.DB, table string, record MigrationRecord) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(trackingColumnNames)), ", ")
+	query := fmt.Sprintf(`INSERT OR REPLACE INTO %s (name, applied_at, %s) VALUES (?, DATETIME('now', 'utc'), %s)`,
+		table, strings.Join(trackingColumnNames, ", "), placeholders)
+
+	args := append([]any{record.Name}, trackingValues(record)...)
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (d *SQLiteDriver) InsertHistory(ctx context.Context, db *sql.DB, list []HistoryRecord) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	query, args := historyInsertQuery(d.historyTableName(), list, questionPlaceholder)
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (d *SQLiteDriver) GetHistory(ctx context.Context, db *sql.DB, filter HistoryFilter) ([]HistoryRecord, error) {
+	query, args := historySelectQuery(d.historyTableName(), "id DESC", filter, questionPlaceholder)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoryRecord
+	for rows.Next() {
+		h, err := scanHistoryRecord(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
+
+func (d *SQLiteDriver) NextHistoryBatch(ctx context.Context, db *sql.DB) (int64, error) {
+	var batch int64
+	query := fmt.Sprintf(`SELECT COALESCE(MAX(batch), 0) + 1 FROM %s`, d.historyTableName())
+	err := db.QueryRowContext(ctx, query).Scan(&batch)
+	return batch, err
+}
+
 func (d *SQLiteDriver) Name() string {
 	return "sqlite"
 }
+
+// amigoTableNames returns the names of amigo's own tables, left out of schema dumps
+func (d *SQLiteDriver) amigoTableNames() []string {
+	return []string{d.tableName, d.historyTableName(), d.repeatableTableName(), d.seedsTableName(), d.metaTableName()}
+}
+
+// DumpSchema returns the statements of sqlite_master, tables first then indexes, views and triggers, in name
+// order. See SchemaDumper.
+func (d *SQLiteDriver) DumpSchema(ctx context.Context, db *sql.DB) (string, error) {
+	query := fmt.Sprintf(`
+		SELECT sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%%' AND tbl_name NOT IN (%s)
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'view' THEN 2 ELSE 3 END, name
+	`, sqlStringList(d.amigoTableNames()))
+
+	statements, err := queryStrings(ctx, db, query)
+	if err != nil {
+		return "", err
+	}
+
+	return joinSchemaStatements(statements), nil
+}
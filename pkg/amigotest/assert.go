@@ -0,0 +1,53 @@
+package amigotest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alexisvisco/amigo"
+)
+
+// AssertApplied fails the test unless every migration is applied in the database of cfg
+func AssertApplied(t testing.TB, cfg amigo.Configuration, migrations ...amigo.Migration) {
+	t.Helper()
+
+	applied := appliedDates(t, cfg)
+	for _, m := range migrations {
+		if _, ok := applied[m.Date()]; !ok {
+			t.Errorf("migration %s is pending, want applied", migrationVersion(m))
+		}
+	}
+}
+
+// AssertPending fails the test if one of the migrations is applied in the database of cfg
+func AssertPending(t testing.TB, cfg amigo.Configuration, migrations ...amigo.Migration) {
+	t.Helper()
+
+	applied := appliedDates(t, cfg)
+	for _, m := range migrations {
+		if _, ok := applied[m.Date()]; ok {
+			t.Errorf("migration %s is applied, want pending", migrationVersion(m))
+		}
+	}
+}
+
+// appliedDates returns the dates of the migrations applied in the database of cfg
+func appliedDates(t testing.TB, cfg amigo.Configuration) map[int64]struct{} {
+	t.Helper()
+
+	records, err := cfg.Driver.GetAppliedMigrations(t.Context(), cfg.DB)
+	if err != nil {
+		t.Fatalf("get applied migrations: %v", err)
+	}
+
+	dates := make(map[int64]struct{}, len(records))
+	for _, record := range records {
+		dates[record.Date] = struct{}{}
+	}
+	return dates
+}
+
+// migrationVersion returns the file name prefix of a migration
+func migrationVersion(m amigo.Migration) string {
+	return fmt.Sprintf("%d_%s", m.Date(), m.Name())
+}
@@ -0,0 +1,62 @@
+package amigotest_test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/alexisvisco/amigo"
+	"github.com/alexisvisco/amigo/pkg/amigotest"
+)
+
+// recordingTB is a testing.TB recording failures instead of failing the test
+type recordingTB struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (t *recordingTB) Helper() {}
+
+func (t *recordingTB) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingTB) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+	t.fatal = true
+	runtime.Goexit()
+}
+
+// record runs f with a recordingTB, in its own goroutine so that Fatalf stops f only
+func record(t *testing.T, f func(tb testing.TB)) *recordingTB {
+	tb := &recordingTB{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(tb)
+	}()
+	<-done
+	return tb
+}
+
+func TestAssertAppliedAndPending(t *testing.T) {
+	applied := amigotest.NewMigration(1, "applied", amigotest.Noop, amigotest.Noop)
+	pending := amigotest.NewMigration(2, "pending", amigotest.Noop, amigotest.Noop)
+
+	config := amigo.DefaultConfiguration
+	config.Driver = amigotest.NewDriver(amigo.MigrationRecord{Date: 1, Name: "applied"})
+
+	amigotest.AssertApplied(t, config, applied)
+	amigotest.AssertPending(t, config, pending)
+
+	tb := record(t, func(tb testing.TB) {
+		amigotest.AssertApplied(tb, config, applied, pending)
+		amigotest.AssertPending(tb, config, applied)
+	})
+	want := []string{"migration 2_pending is pending, want applied", "migration 1_applied is applied, want pending"}
+	if strings.Join(tb.errors, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors = %q, want %q", tb.errors, want)
+	}
+}
@@ -0,0 +1,331 @@
+// Package amigotest helps testing migrations, and code running them, with the standard testing package.
+//
+// Driver is an in-memory amigo.Driver with injectable failures, that also tracks repeatable migrations, seeds
+// and history. NewMigration builds migrations from closures, and AssertApplied and AssertPending check the state
+// of a database:
+//
+//	func TestDeploy(t *testing.T) {
+//	    driver := amigotest.NewDriver()
+//	    driver.FailOn(amigotest.MethodInsertMigrations, errors.New("connection lost"))
+//
+//	    config := amigo.DefaultConfiguration
+//	    config.Driver = driver
+//
+//	    migration := amigotest.NewMigration(20240101120000, "create_users", amigotest.Noop, amigotest.Noop)
+//	    err := deploy(t.Context(), config, []amigo.Migration{migration})
+//	    ...
+//	    amigotest.AssertPending(t, config, migration)
+//	}
+//
+// Roundtrip checks against a real, disposable database that the down of every migration reverts its up:
+//
+//	func TestMigrationsRollback(t *testing.T) {
+//	    config := amigo.DefaultConfiguration
+//	    config.DB = openDisposableDatabase(t)
+//	    config.Driver = amigo.NewSQLiteDriver("")
+//
+//	    amigotest.Roundtrip(t, config, migrations.Migrations(config))
+//	}
+package amigotest
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alexisvisco/amigo"
+)
+
+// Method is a method of the amigo.Driver interface, see Driver.FailOn
+type Method string
+
+const (
+	MethodCreateSchemaMigrationsTableIfNotExists Method = "CreateSchemaMigrationsTableIfNotExists"
+	MethodGetAppliedMigrations                   Method = "GetAppliedMigrations"
+	MethodInsertMigrations                       Method = "InsertMigrations"
+	MethodDeleteMigrations                       Method = "DeleteMigrations"
+	MethodSchemaMigrationsTableExists            Method = "SchemaMigrationsTableExists"
+	MethodGetRepeatableMigrations                Method = "GetRepeatableMigrations"
+	MethodUpsertRepeatableMigration              Method = "UpsertRepeatableMigration"
+	MethodGetAppliedSeeds                        Method = "GetAppliedSeeds"
+	MethodUpsertSeed                             Method = "UpsertSeed"
+	MethodInsertHistory                          Method = "InsertHistory"
+	MethodGetHistory                             Method = "GetHistory"
+	MethodNextHistoryBatch                       Method = "NextHistoryBatch"
+	MethodDumpSchema                             Method = "DumpSchema"
+)
+
+// Driver is an in-memory amigo.Driver keeping the applied migrations in a slice, to test code using amigo.Runner
+// without a database. The db argument of its methods is ignored, so it can be nil in the configuration as long
+// as the migrations do not use it, see NewMigration. It is safe for concurrent use.
+//
+// It also implements the optional driver interfaces: repeatable migrations, seeds, history, schema dumps of the
+// schema set with SetSchema, and SchemaMigrationsTableInspector, for which the table exists once created or
+// when migrations are applied.
+type Driver struct {
+	mu          sync.Mutex
+	created     bool
+	records     []amigo.MigrationRecord
+	repeatables []amigo.MigrationRecord
+	seeds       []amigo.MigrationRecord
+	history     []amigo.HistoryRecord
+	schema      string
+	failures    map[Method]error
+	calls       map[Method]int
+}
+
+var (
+	_ amigo.Driver                         = (*Driver)(nil)
+	_ amigo.SchemaMigrationsTableInspector = (*Driver)(nil)
+	_ amigo.RepeatableDriver               = (*Driver)(nil)
+	_ amigo.SeedDriver                     = (*Driver)(nil)
+	_ amigo.HistoryDriver                  = (*Driver)(nil)
+	_ amigo.SchemaDumper                   = (*Driver)(nil)
+)
+
+// NewDriver returns an in-memory driver with the given migrations already applied
+func NewDriver(applied ...amigo.MigrationRecord) *Driver {
+	return &Driver{
+		records:  slices.Clone(applied),
+		failures: make(map[Method]error),
+		calls:    make(map[Method]int),
+	}
+}
+
+// FailOn makes every call of method return err, until FailOn is called again for it with a nil error
+func (d *Driver) FailOn(method Method, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err == nil {
+		delete(d.failures, method)
+		return
+	}
+	d.failures[method] = err
+}
+
+// Calls returns the number of calls of method, including the failed ones
+func (d *Driver) Calls(method Method) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.calls[method]
+}
+
+// Applied returns the records of the applied migrations, in the order they were inserted
+func (d *Driver) Applied() []amigo.MigrationRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.records)
+}
+
+// Repeatables returns the records of the last run of every repeatable migration, in the order they first ran
+func (d *Driver) Repeatables() []amigo.MigrationRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.repeatables)
+}
+
+// Seeds returns the records of the last run of every seed, in the order they first ran
+func (d *Driver) Seeds() []amigo.MigrationRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.seeds)
+}
+
+// History returns the history entries, in the order they were inserted
+func (d *Driver) History() []amigo.HistoryRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.history)
+}
+
+// SetSchema sets the schema returned by DumpSchema, so that migrations can simulate their schema changes
+func (d *Driver) SetSchema(schema string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.schema = schema
+}
+
+// call counts a call of method and returns its injected failure
+func (d *Driver) call(method Method) error {
+	d.calls[method]++
+	return d.failures[method]
+}
+
+func (d *Driver) CreateSchemaMigrationsTableIfNotExists(ctx context.Context, db *sql.DB) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodCreateSchemaMigrationsTableIfNotExists); err != nil {
+		return err
+	}
+	d.created = true
+	return nil
+}
+
+func (d *Driver) SchemaMigrationsTableExists(ctx context.Context, db *sql.DB) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodSchemaMigrationsTableExists); err != nil {
+		return false, err
+	}
+	return d.created || len(d.records) > 0, nil
+}
+
+func (d *Driver) GetAppliedMigrations(ctx context.Context, db *sql.DB) ([]amigo.MigrationRecord, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodGetAppliedMigrations); err != nil {
+		return nil, err
+	}
+	return slices.Clone(d.records), nil
+}
+
+func (d *Driver) InsertMigrations(ctx context.Context, db *sql.DB, list []amigo.MigrationRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodInsertMigrations); err != nil {
+		return err
+	}
+	d.records = append(d.records, list...)
+	return nil
+}
+
+func (d *Driver) DeleteMigrations(ctx context.Context, db *sql.DB, dates []int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodDeleteMigrations); err != nil {
+		return err
+	}
+	d.records = slices.DeleteFunc(d.records, func(record amigo.MigrationRecord) bool {
+		return slices.Contains(dates, record.Date)
+	})
+	return nil
+}
+
+func (d *Driver) GetRepeatableMigrations(ctx context.Context, db *sql.DB) ([]amigo.MigrationRecord, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodGetRepeatableMigrations); err != nil {
+		return nil, err
+	}
+	return slices.Clone(d.repeatables), nil
+}
+
+func (d *Driver) UpsertRepeatableMigration(ctx context.Context, db *sql.DB, record amigo.MigrationRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodUpsertRepeatableMigration); err != nil {
+		return err
+	}
+	d.repeatables = upsertNamedRecord(d.repeatables, record)
+	return nil
+}
+
+func (d *Driver) GetAppliedSeeds(ctx context.Context, db *sql.DB) ([]amigo.MigrationRecord, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodGetAppliedSeeds); err != nil {
+		return nil, err
+	}
+	return slices.Clone(d.seeds), nil
+}
+
+func (d *Driver) UpsertSeed(ctx context.Context, db *sql.DB, record amigo.MigrationRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodUpsertSeed); err != nil {
+		return err
+	}
+	d.seeds = upsertNamedRecord(d.seeds, record)
+	return nil
+}
+
+// upsertNamedRecord replaces the record with the name of record, or appends it
+func upsertNamedRecord(records []amigo.MigrationRecord, record amigo.MigrationRecord) []amigo.MigrationRecord {
+	if record.AppliedAt.IsZero() {
+		record.AppliedAt = time.Now()
+	}
+	if i := slices.IndexFunc(records, func(r amigo.MigrationRecord) bool { return r.Name == record.Name }); i >= 0 {
+		records[i] = record
+		return records
+	}
+	return append(records, record)
+}
+
+func (d *Driver) InsertHistory(ctx context.Context, db *sql.DB, list []amigo.HistoryRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodInsertHistory); err != nil {
+		return err
+	}
+	d.history = append(d.history, list...)
+	return nil
+}
+
+func (d *Driver) GetHistory(ctx context.Context, db *sql.DB, filter amigo.HistoryFilter) ([]amigo.HistoryRecord, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodGetHistory); err != nil {
+		return nil, err
+	}
+
+	var history []amigo.HistoryRecord
+	for _, entry := range slices.Backward(d.history) {
+		if filter.Date != 0 && entry.Date != filter.Date {
+			continue
+		}
+		if filter.Limit > 0 && len(history) == filter.Limit {
+			break
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+func (d *Driver) NextHistoryBatch(ctx context.Context, db *sql.DB) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodNextHistoryBatch); err != nil {
+		return 0, err
+	}
+
+	var batch int64
+	for _, entry := range d.history {
+		batch = max(batch, entry.Batch)
+	}
+	return batch + 1, nil
+}
+
+func (d *Driver) DumpSchema(ctx context.Context, db *sql.DB) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.call(MethodDumpSchema); err != nil {
+		return "", err
+	}
+	return d.schema, nil
+}
+
+func (d *Driver) Name() string {
+	return "amigotest"
+}
@@ -0,0 +1,76 @@
+package amigotest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alexisvisco/amigo"
+	"github.com/alexisvisco/amigo/pkg/amigotest"
+)
+
+func TestDriver_FailOn(t *testing.T) {
+	errInsert := errors.New("insert failed")
+	driver := amigotest.NewDriver()
+	driver.FailOn(amigotest.MethodInsertMigrations, errInsert)
+
+	record := amigo.MigrationRecord{Date: 1, Name: "first"}
+	if err := driver.InsertMigrations(t.Context(), nil, []amigo.MigrationRecord{record}); !errors.Is(err, errInsert) {
+		t.Fatalf("InsertMigrations() error = %v, want %v", err, errInsert)
+	}
+	if got := driver.Applied(); len(got) != 0 {
+		t.Errorf("Applied() = %v after a failed insert, want none", got)
+	}
+
+	driver.FailOn(amigotest.MethodInsertMigrations, nil)
+	if err := driver.InsertMigrations(t.Context(), nil, []amigo.MigrationRecord{record}); err != nil {
+		t.Fatalf("InsertMigrations() error = %v", err)
+	}
+	if got := driver.Applied(); len(got) != 1 || got[0].Date != 1 {
+		t.Errorf("Applied() = %v, want the first migration", got)
+	}
+	if got := driver.Calls(amigotest.MethodInsertMigrations); got != 2 {
+		t.Errorf("Calls(InsertMigrations) = %d, want 2", got)
+	}
+	if got := driver.Calls(amigotest.MethodDeleteMigrations); got != 0 {
+		t.Errorf("Calls(DeleteMigrations) = %d, want 0", got)
+	}
+}
+
+func TestDriver_SchemaMigrationsTableExists(t *testing.T) {
+	driver := amigotest.NewDriver()
+	if exists, err := driver.SchemaMigrationsTableExists(t.Context(), nil); err != nil || exists {
+		t.Fatalf("SchemaMigrationsTableExists() = %v, %v, want false before creation", exists, err)
+	}
+
+	if err := driver.CreateSchemaMigrationsTableIfNotExists(t.Context(), nil); err != nil {
+		t.Fatalf("CreateSchemaMigrationsTableIfNotExists() error = %v", err)
+	}
+	if exists, err := driver.SchemaMigrationsTableExists(t.Context(), nil); err != nil || !exists {
+		t.Fatalf("SchemaMigrationsTableExists() = %v, %v, want true after creation", exists, err)
+	}
+}
+
+func TestDriver_GetHistory(t *testing.T) {
+	driver := amigotest.NewDriver()
+	entries := []amigo.HistoryRecord{
+		{Batch: 1, Date: 1, Direction: amigo.MigrationDirectionUp},
+		{Batch: 1, Date: 2, Direction: amigo.MigrationDirectionUp},
+		{Batch: 2, Date: 2, Direction: amigo.MigrationDirectionDown},
+	}
+	if err := driver.InsertHistory(t.Context(), nil, entries); err != nil {
+		t.Fatalf("InsertHistory() error = %v", err)
+	}
+
+	batch, err := driver.NextHistoryBatch(t.Context(), nil)
+	if err != nil || batch != 3 {
+		t.Errorf("NextHistoryBatch() = %d, %v, want 3", batch, err)
+	}
+
+	got, err := driver.GetHistory(t.Context(), nil, amigo.HistoryFilter{Date: 2, Limit: 1})
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Batch != 2 || got[0].Direction != amigo.MigrationDirectionDown {
+		t.Errorf("GetHistory() = %+v, want the most recent entry of migration 2", got)
+	}
+}
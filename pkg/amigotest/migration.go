@@ -0,0 +1,62 @@
+package amigotest
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/alexisvisco/amigo"
+)
+
+// MigrationFunc is the up or down of a migration built with NewMigration
+type MigrationFunc func(ctx context.Context, db *sql.DB) error
+
+// Noop is a MigrationFunc doing nothing
+func Noop(ctx context.Context, db *sql.DB) error {
+	return nil
+}
+
+// Fail returns a MigrationFunc returning err
+func Fail(err error) MigrationFunc {
+	return func(ctx context.Context, db *sql.DB) error {
+		return err
+	}
+}
+
+// NewMigration returns a migration running up and down. A nil up does nothing, a nil down makes the migration
+// irreversible, see amigo.Irreversible.
+func NewMigration(date int64, name string, up, down MigrationFunc) amigo.Migration {
+	return funcMigration{date: date, name: name, up: up, down: down}
+}
+
+// funcMigration is a migration running closures, see NewMigration
+type funcMigration struct {
+	date     int64
+	name     string
+	up, down MigrationFunc
+}
+
+func (m funcMigration) Up(ctx context.Context, db *sql.DB) error {
+	if m.up == nil {
+		return nil
+	}
+	return m.up(ctx, db)
+}
+
+func (m funcMigration) Down(ctx context.Context, db *sql.DB) error {
+	if m.down == nil {
+		return amigo.ErrIrreversible
+	}
+	return m.down(ctx, db)
+}
+
+func (m funcMigration) Name() string {
+	return m.name
+}
+
+func (m funcMigration) Date() int64 {
+	return m.date
+}
+
+func (m funcMigration) Irreversible() bool {
+	return m.down == nil
+}
@@ -0,0 +1,26 @@
+package amigotest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alexisvisco/amigo"
+)
+
+// Roundtrip applies the pending migrations one by one against the database of cfg, checking that the down of each
+// one reverts its up, see amigo.Runner.RoundtripIterator. It fails the test at the first migration whose down
+// leaves the schema different, with the difference, or at the first error. The migrations stay applied.
+func Roundtrip(t testing.TB, cfg amigo.Configuration, migrations []amigo.Migration) {
+	t.Helper()
+
+	runner := amigo.NewRunner(cfg)
+	for result := range runner.RoundtripIterator(t.Context(), migrations) {
+		if errors.Is(result.Error, amigo.ErrRoundtripMismatch) {
+			t.Fatalf("the down of migration %s does not revert its up, the schema differs after the down:\n%s",
+				migrationVersion(result.Migration), result.Diff)
+		}
+		if result.Error != nil {
+			t.Fatalf("roundtrip: %v", result.Error)
+		}
+	}
+}
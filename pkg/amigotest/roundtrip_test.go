@@ -0,0 +1,51 @@
+package amigotest_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/alexisvisco/amigo"
+	"github.com/alexisvisco/amigo/pkg/amigotest"
+)
+
+func TestRoundtrip(t *testing.T) {
+	const usersTable = "CREATE TABLE users (id INTEGER)"
+
+	setSchema := func(driver *amigotest.Driver, schema string) amigotest.MigrationFunc {
+		return func(ctx context.Context, db *sql.DB) error {
+			driver.SetSchema(schema)
+			return nil
+		}
+	}
+
+	t.Run("down reverts up", func(t *testing.T) {
+		driver := amigotest.NewDriver()
+		config := amigo.DefaultConfiguration
+		config.Driver = driver
+
+		migration := amigotest.NewMigration(1, "create_users", setSchema(driver, usersTable), setSchema(driver, ""))
+		amigotest.Roundtrip(t, config, []amigo.Migration{migration})
+
+		amigotest.AssertApplied(t, config, migration)
+	})
+
+	t.Run("down leaves a table", func(t *testing.T) {
+		driver := amigotest.NewDriver()
+		config := amigo.DefaultConfiguration
+		config.Driver = driver
+
+		migration := amigotest.NewMigration(1, "create_users", setSchema(driver, usersTable), amigotest.Noop)
+		tb := record(t, func(tb testing.TB) {
+			amigotest.Roundtrip(tb, config, []amigo.Migration{migration})
+		})
+
+		if !tb.fatal || len(tb.errors) != 1 {
+			t.Fatalf("Roundtrip() errors = %q, want a fatal error", tb.errors)
+		}
+		if !strings.Contains(tb.errors[0], "1_create_users") || !strings.Contains(tb.errors[0], "+ "+usersTable) {
+			t.Errorf("Roundtrip() error = %q, want the migration and the diff", tb.errors[0])
+		}
+	})
+}
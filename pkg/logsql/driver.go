@@ -9,6 +9,8 @@ import (
 	"os"
 	"sync"
 	"time"
+
+	"github.com/alexisvisco/amigo/pkg/termcolor"
 )
 
 const (
@@ -42,6 +44,7 @@ const (
 type wrapOpts struct {
 	Output      io.Writer
 	Placeholder PlaceholderStyle
+	Color       termcolor.Mode
 }
 
 type WrapOptionFunc func(*wrapOpts)
@@ -60,10 +63,19 @@ func WrapOptionPlaceholder(style PlaceholderStyle) WrapOptionFunc {
 	}
 }
 
+// WrapOptionColor sets when ANSI colors are used in SQL logs.
+// The default (termcolor.ModeAuto) enables them only when the output is a terminal, honoring NO_COLOR and FORCE_COLOR.
+func WrapOptionColor(mode termcolor.Mode) WrapOptionFunc {
+	return func(opts *wrapOpts) {
+		opts.Color = mode
+	}
+}
+
 func defaultWrapOpts() wrapOpts {
 	return wrapOpts{
 		Output:      os.Stdout,
 		Placeholder: PlaceholderAuto,
+		Color:       termcolor.ModeAuto,
 	}
 }
 
@@ -100,6 +112,7 @@ func WrapDriver(driverName string, opts ...WrapOptionFunc) string {
 		driverName:  driverName,
 		output:      options.Output,
 		placeholder: placeholder,
+		color:       termcolor.Enabled(options.Color, options.Output),
 	})
 
 	drivers[wrappedName] = driverName
@@ -120,6 +133,14 @@ func detectPlaceholderStyle(driverName string) PlaceholderStyle {
 	}
 }
 
+// paint wraps s with the given color code when colors are enabled
+func paint(enabled bool, color, s string) string {
+	if !enabled {
+		return s
+	}
+	return color + s + colorReset
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr ||
 		(len(s) > len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr)))
@@ -129,6 +150,7 @@ type loggingDriver struct {
 	driverName  string
 	output      io.Writer
 	placeholder PlaceholderStyle
+	color       bool
 }
 
 func (d *loggingDriver) Open(name string) (driver.Conn, error) {
@@ -148,6 +170,7 @@ func (d *loggingDriver) Open(name string) (driver.Conn, error) {
 		Conn:        conn,
 		output:      d.output,
 		placeholder: d.placeholder,
+		color:       d.color,
 	}, nil
 }
 
@@ -167,12 +190,13 @@ type loggingConn struct {
 	driver.Conn
 	output      io.Writer
 	placeholder PlaceholderStyle
+	color       bool
 	inTx        bool
 }
 
 func (c *loggingConn) logQuery(txPrefix, operation, query string, args interface{}, duration time.Duration, err error) {
-	durationMs := colorYellow + fmt.Sprintf("%dms", duration.Milliseconds()) + colorReset
-	queryStr := colorCyan + query + colorReset
+	durationMs := paint(c.color, colorYellow, fmt.Sprintf("%dms", duration.Milliseconds()))
+	queryStr := paint(c.color, colorCyan, query)
 
 	fmt.Fprintf(c.output, "SQL DEBUG > [%s%s] [%s] %s", txPrefix, operation, durationMs, queryStr)
 
@@ -184,7 +208,7 @@ func (c *loggingConn) logQuery(txPrefix, operation, query string, args interface
 	}
 
 	if err != nil {
-		fmt.Fprintf(c.output, " : %sError %v%s", colorRed, err, colorReset)
+		fmt.Fprintf(c.output, " : %s", paint(c.color, colorRed, fmt.Sprintf("Error %v", err)))
 	}
 
 	fmt.Fprintf(c.output, "\n")
@@ -195,7 +219,7 @@ func (c *loggingConn) Prepare(query string) (driver.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &loggingStmt{Stmt: stmt, query: query, output: c.output, inTx: c.inTx, placeholder: c.placeholder}, nil
+	return &loggingStmt{Stmt: stmt, query: query, output: c.output, inTx: c.inTx, placeholder: c.placeholder, color: c.color}, nil
 }
 
 func (c *loggingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
@@ -204,7 +228,7 @@ func (c *loggingConn) PrepareContext(ctx context.Context, query string) (driver.
 		if err != nil {
 			return nil, err
 		}
-		return &loggingStmt{Stmt: stmt, query: query, output: c.output, inTx: c.inTx, placeholder: c.placeholder}, nil
+		return &loggingStmt{Stmt: stmt, query: query, output: c.output, inTx: c.inTx, placeholder: c.placeholder, color: c.color}, nil
 	}
 	return c.Prepare(query)
 }
@@ -219,7 +243,7 @@ func (c *loggingConn) Begin() (driver.Tx, error) {
 		return nil, err
 	}
 	c.inTx = true
-	return &loggingTx{Tx: tx, output: c.output, placeholder: c.placeholder, conn: c}, nil
+	return &loggingTx{Tx: tx, output: c.output, placeholder: c.placeholder, color: c.color, conn: c}, nil
 }
 
 func (c *loggingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
@@ -233,7 +257,7 @@ func (c *loggingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (drive
 			return nil, err
 		}
 		c.inTx = true
-		return &loggingTx{Tx: tx, output: c.output, placeholder: c.placeholder, conn: c}, nil
+		return &loggingTx{Tx: tx, output: c.output, placeholder: c.placeholder, color: c.color, conn: c}, nil
 	}
 	return c.Begin()
 }
@@ -244,11 +268,12 @@ type loggingStmt struct {
 	output      io.Writer
 	inTx        bool
 	placeholder PlaceholderStyle
+	color       bool
 }
 
 func (s *loggingStmt) logQuery(operation string, args interface{}, duration time.Duration, err error) {
-	durationMs := colorYellow + fmt.Sprintf("%dms", duration.Milliseconds()) + colorReset
-	queryStr := colorCyan + s.query + colorReset
+	durationMs := paint(s.color, colorYellow, fmt.Sprintf("%dms", duration.Milliseconds()))
+	queryStr := paint(s.color, colorCyan, s.query)
 
 	txPrefix := ""
 	if s.inTx {
@@ -265,7 +290,7 @@ func (s *loggingStmt) logQuery(operation string, args interface{}, duration time
 	}
 
 	if err != nil {
-		fmt.Fprintf(s.output, " : %sError %v%s", colorRed, err, colorReset)
+		fmt.Fprintf(s.output, " : %s", paint(s.color, colorRed, fmt.Sprintf("Error %v", err)))
 	}
 
 	fmt.Fprintf(s.output, "\n")
@@ -313,17 +338,18 @@ type loggingTx struct {
 	driver.Tx
 	output      io.Writer
 	placeholder PlaceholderStyle
+	color       bool
 	conn        *loggingConn
 }
 
 func (t *loggingTx) logQuery(operation, query string, duration time.Duration, err error) {
-	durationMs := colorYellow + fmt.Sprintf("%dms", duration.Milliseconds()) + colorReset
-	queryStr := colorCyan + query + colorReset
+	durationMs := paint(t.color, colorYellow, fmt.Sprintf("%dms", duration.Milliseconds()))
+	queryStr := paint(t.color, colorCyan, query)
 
 	fmt.Fprintf(t.output, "SQL DEBUG > [TX %s] [%s] %s", operation, durationMs, queryStr)
 
 	if err != nil {
-		fmt.Fprintf(t.output, " : %sError %v%s", colorRed, err, colorReset)
+		fmt.Fprintf(t.output, " : %s", paint(t.color, colorRed, fmt.Sprintf("Error %v", err)))
 	}
 
 	fmt.Fprintf(t.output, "\n")
@@ -355,7 +381,7 @@ func (t *loggingTx) Prepare(query string) (driver.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &loggingStmt{Stmt: stmt, query: query, output: t.output, inTx: true, placeholder: t.placeholder}, nil
+	return &loggingStmt{Stmt: stmt, query: query, output: t.output, inTx: true, placeholder: t.placeholder, color: t.color}, nil
 }
 
 // PrepareContext creates a prepared statement within the transaction
@@ -365,7 +391,7 @@ func (t *loggingTx) PrepareContext(ctx context.Context, query string) (driver.St
 		if err != nil {
 			return nil, err
 		}
-		return &loggingStmt{Stmt: stmt, query: query, output: t.output, inTx: true, placeholder: t.placeholder}, nil
+		return &loggingStmt{Stmt: stmt, query: query, output: t.output, inTx: true, placeholder: t.placeholder, color: t.color}, nil
 	}
 	return t.Prepare(query)
 }
@@ -377,8 +403,8 @@ func (t *loggingTx) ExecContext(ctx context.Context, query string, args []driver
 		result, err := execer.ExecContext(ctx, query, args)
 		duration := time.Since(start)
 
-		durationMs := colorYellow + fmt.Sprintf("%dms", duration.Milliseconds()) + colorReset
-		queryStr := colorCyan + query + colorReset
+		durationMs := paint(t.color, colorYellow, fmt.Sprintf("%dms", duration.Milliseconds()))
+		queryStr := paint(t.color, colorCyan, query)
 
 		fmt.Fprintf(t.output, "SQL DEBUG > [TX EXEC] [%s] %s", durationMs, queryStr)
 
@@ -390,7 +416,7 @@ func (t *loggingTx) ExecContext(ctx context.Context, query string, args []driver
 		}
 
 		if err != nil {
-			fmt.Fprintf(t.output, " : %sError %v%s", colorRed, err, colorReset)
+			fmt.Fprintf(t.output, " : %s", paint(t.color, colorRed, fmt.Sprintf("Error %v", err)))
 		}
 
 		fmt.Fprintf(t.output, "\n")
@@ -418,8 +444,8 @@ func (t *loggingTx) QueryContext(ctx context.Context, query string, args []drive
 		rows, err := queryer.QueryContext(ctx, query, args)
 		duration := time.Since(start)
 
-		durationMs := colorYellow + fmt.Sprintf("%dms", duration.Milliseconds()) + colorReset
-		queryStr := colorCyan + query + colorReset
+		durationMs := paint(t.color, colorYellow, fmt.Sprintf("%dms", duration.Milliseconds()))
+		queryStr := paint(t.color, colorCyan, query)
 
 		fmt.Fprintf(t.output, "SQL DEBUG > [TX QUERY] [%s] %s", durationMs, queryStr)
 
@@ -431,7 +457,7 @@ func (t *loggingTx) QueryContext(ctx context.Context, query string, args []drive
 		}
 
 		if err != nil {
-			fmt.Fprintf(t.output, " : %sError %v%s", colorRed, err, colorReset)
+			fmt.Fprintf(t.output, " : %s", paint(t.color, colorRed, fmt.Sprintf("Error %v", err)))
 		}
 
 		fmt.Fprintf(t.output, "\n")
@@ -0,0 +1,410 @@
+// Package schema renders schema changes for the dialects supported by amigo, so Go migrations can be written
+// once for PostgreSQL, SQLite and ClickHouse.
+//
+// Example usage:
+//
+//	func (m Migration) Up(ctx context.Context, db *sql.DB) error {
+//	    return amigo.NewSchemaBuilder(ctx, db).
+//	        CreateTable("users", func(t *schema.Table) {
+//	            t.Column(
+//	                schema.BigInt("id").PrimaryKey().AutoIncrement(),
+//	                schema.String("email", 255).NotNull(),
+//	            )
+//	        }).
+//	        AddIndex(schema.Index{Table: "users", Columns: []string{"email"}}).
+//	        Err()
+//	}
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Executor is the subset of *sql.DB, *sql.Conn and *sql.Tx used by a Builder
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Builder executes schema changes rendered for its dialect.
+// Like amigo.ChainExec, errors are accumulated: once an operation fails, the next ones are no-ops.
+type Builder struct {
+	ctx     context.Context
+	exec    Executor
+	dialect Dialect
+	err     error
+}
+
+// New creates a Builder executing statements for the dialect with exec
+func New(ctx context.Context, exec Executor, dialect Dialect) *Builder {
+	b := &Builder{ctx: ctx, exec: exec, dialect: dialect}
+	if dialect == "" {
+		b.err = errors.New("no dialect: the database the migration runs against is unknown")
+	} else if _, err := ParseDialect(string(dialect)); err != nil {
+		b.err = err
+	}
+	return b
+}
+
+// Dialect returns the dialect statements are rendered for
+func (b *Builder) Dialect() Dialect {
+	return b.dialect
+}
+
+// Err returns the first error that occurred, or nil if no errors occurred
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// run executes the statements returned by render, unless a previous operation failed
+func (b *Builder) run(render func() ([]string, error)) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	statements, err := render()
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	for _, statement := range statements {
+		if _, err := b.exec.ExecContext(b.ctx, statement); err != nil {
+			b.err = fmt.Errorf("failed to execute %q: %w", statement, err)
+			return b
+		}
+	}
+	return b
+}
+
+// single wraps a statement renderer returning one statement
+func single(statement string, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []string{statement}, nil
+}
+
+// CreateTable creates a table defined by build
+func (b *Builder) CreateTable(name string, build func(t *Table)) *Builder {
+	return b.run(func() ([]string, error) {
+		t := &Table{name: name}
+		build(t)
+		return single(t.render(b.dialect))
+	})
+}
+
+// DropTable drops a table
+func (b *Builder) DropTable(name string) *Builder {
+	return b.run(func() ([]string, error) {
+		return single(DropTableSQL(b.dialect, name))
+	})
+}
+
+// RenameTable renames a table
+func (b *Builder) RenameTable(from, to string) *Builder {
+	return b.run(func() ([]string, error) {
+		return single(RenameTableSQL(b.dialect, from, to))
+	})
+}
+
+// AddColumn adds a column to a table
+func (b *Builder) AddColumn(table string, column *Column) *Builder {
+	return b.run(func() ([]string, error) {
+		return single(AddColumnSQL(b.dialect, table, column))
+	})
+}
+
+// DropColumn drops a column from a table
+func (b *Builder) DropColumn(table, column string) *Builder {
+	return b.run(func() ([]string, error) {
+		return single(DropColumnSQL(b.dialect, table, column))
+	})
+}
+
+// RenameColumn renames a column
+func (b *Builder) RenameColumn(table, from, to string) *Builder {
+	return b.run(func() ([]string, error) {
+		return single(RenameColumnSQL(b.dialect, table, from, to))
+	})
+}
+
+// AddIndex creates an index, a data skipping index on ClickHouse
+func (b *Builder) AddIndex(index Index) *Builder {
+	return b.run(func() ([]string, error) {
+		return single(index.render(b.dialect))
+	})
+}
+
+// DropIndex drops an index of a table
+func (b *Builder) DropIndex(table, name string) *Builder {
+	return b.run(func() ([]string, error) {
+		return single(DropIndexSQL(b.dialect, table, name))
+	})
+}
+
+// AddForeignKey adds a foreign key constraint to a table.
+// SQLite cannot alter constraints, the table is rebuilt with the constraint. ClickHouse has no foreign keys.
+func (b *Builder) AddForeignKey(table string, fk ForeignKey) *Builder {
+	return b.run(func() ([]string, error) {
+		if b.dialect == DialectClickHouse {
+			return nil, fmt.Errorf("table %s: clickhouse does not support foreign keys", table)
+		}
+
+		clause, err := fk.constraint(table)
+		if err != nil {
+			return nil, err
+		}
+		return b.addConstraint(table, clause)
+	})
+}
+
+// AddCheckConstraint adds a check constraint to a table.
+// SQLite cannot alter constraints, the table is rebuilt with the constraint.
+func (b *Builder) AddCheckConstraint(table, name, expr string) *Builder {
+	return b.run(func() ([]string, error) {
+		clause, err := checkConstraint(name, expr)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", table, err)
+		}
+		return b.addConstraint(table, clause)
+	})
+}
+
+// DropConstraint drops a named constraint from a table. SQLite cannot drop constraints.
+func (b *Builder) DropConstraint(table, name string) *Builder {
+	return b.run(func() ([]string, error) {
+		if b.dialect == DialectSQLite {
+			return nil, fmt.Errorf("table %s: sqlite cannot drop constraint %s, recreate the table instead", table, name)
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, name)}, nil
+	})
+}
+
+// Exec executes a raw statement, for changes the builder does not cover
+func (b *Builder) Exec(query string) *Builder {
+	return b.run(func() ([]string, error) {
+		return []string{query}, nil
+	})
+}
+
+// addConstraint returns the statements adding a table constraint clause
+func (b *Builder) addConstraint(table, clause string) ([]string, error) {
+	if b.dialect == DialectSQLite {
+		return b.sqliteRebuildStatements(table, clause)
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s ADD %s", table, clause)}, nil
+}
+
+// DropTableSQL returns the statement dropping a table
+func DropTableSQL(d Dialect, table string) (string, error) {
+	if table == "" {
+		return "", errors.New("table name is required")
+	}
+	return fmt.Sprintf("DROP TABLE %s", table), nil
+}
+
+// RenameTableSQL returns the statement renaming a table
+func RenameTableSQL(d Dialect, from, to string) (string, error) {
+	if from == "" || to == "" {
+		return "", errors.New("table names are required")
+	}
+	if d == DialectClickHouse {
+		return fmt.Sprintf("RENAME TABLE %s TO %s", from, to), nil
+	}
+	return fmt.Sprintf("ALTER TABLE %s RENAME TO %s", from, to), nil
+}
+
+// AddColumnSQL returns the statement adding a column to a table
+func AddColumnSQL(d Dialect, table string, column *Column) (string, error) {
+	if table == "" {
+		return "", errors.New("table name is required")
+	}
+
+	if d == DialectSQLite {
+		// https://www.sqlite.org/lang_altertable.html#altertabaddcol
+		switch {
+		case column.primaryKey || column.unique:
+			return "", fmt.Errorf("column %s: sqlite cannot add a PRIMARY KEY or UNIQUE column", column.name)
+		case column.notNull && column.defaultValue == "":
+			return "", fmt.Errorf("column %s: sqlite cannot add a NOT NULL column without default", column.name)
+		}
+	}
+
+	definition, err := column.render(d)
+	if err != nil {
+		return "", fmt.Errorf("table %s: %w", table, err)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, definition), nil
+}
+
+// DropColumnSQL returns the statement dropping a column from a table
+func DropColumnSQL(d Dialect, table, column string) (string, error) {
+	if table == "" || column == "" {
+		return "", errors.New("table and column names are required")
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column), nil
+}
+
+// RenameColumnSQL returns the statement renaming a column
+func RenameColumnSQL(d Dialect, table, from, to string) (string, error) {
+	if table == "" || from == "" || to == "" {
+		return "", errors.New("table and column names are required")
+	}
+	return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, from, to), nil
+}
+
+// CreateIndexSQL returns the statement creating an index
+func CreateIndexSQL(d Dialect, index Index) (string, error) {
+	return index.render(d)
+}
+
+// DropIndexSQL returns the statement dropping an index of a table
+func DropIndexSQL(d Dialect, table, name string) (string, error) {
+	if name == "" {
+		return "", errors.New("index name is required")
+	}
+	if d == DialectClickHouse {
+		if table == "" {
+			return "", fmt.Errorf("index %s: clickhouse requires the table of the index", name)
+		}
+		return fmt.Sprintf("ALTER TABLE %s DROP INDEX %s", table, name), nil
+	}
+	return fmt.Sprintf("DROP INDEX %s", name), nil
+}
+
+// CreateTableSQL returns the statement creating the table defined by build
+func CreateTableSQL(d Dialect, name string, build func(t *Table)) (string, error) {
+	t := &Table{name: name}
+	build(t)
+	return t.render(d)
+}
+
+// sqliteRebuildStatements returns the statements recreating a SQLite table with an extra table constraint,
+// following https://www.sqlite.org/lang_altertable.html#otheralter: the new table is created under a temporary
+// name, the rows are copied, the old table is dropped, the new one renamed, and its indexes and triggers
+// recreated. Foreign key checks are deferred to the end of the transaction.
+func (b *Builder) sqliteRebuildStatements(table, clause string) ([]string, error) {
+	objects, err := b.querySQLiteObjects(table)
+	if err != nil {
+		return nil, err
+	}
+
+	var createSQL string
+	var others []string
+	for _, o := range objects {
+		if o.typ == "table" {
+			createSQL = o.sql
+		} else {
+			others = append(others, o.sql)
+		}
+	}
+	if createSQL == "" {
+		return nil, fmt.Errorf("table %s does not exist", table)
+	}
+
+	open := strings.Index(createSQL, "(")
+	end := strings.LastIndex(createSQL, ")")
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("table %s: unexpected definition %q", table, createSQL)
+	}
+
+	if err := b.checkSQLiteNotReferenced(table); err != nil {
+		return nil, err
+	}
+
+	columns, err := b.querySQLiteColumns(table)
+	if err != nil {
+		return nil, err
+	}
+
+	rebuilt := "amigo_rebuild_" + table
+	statements := []string{
+		"PRAGMA defer_foreign_keys = ON",
+		fmt.Sprintf("CREATE TABLE %s %s, %s%s", rebuilt, strings.TrimRight(createSQL[open:end], " \n\t"), clause, createSQL[end:]),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", rebuilt, columns, columns, table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", rebuilt, table),
+	}
+	return append(statements, others...), nil
+}
+
+type sqliteObject struct {
+	typ string
+	sql string
+}
+
+// querySQLiteObjects returns the definition of a table followed by its indexes and triggers
+func (b *Builder) querySQLiteObjects(table string) ([]sqliteObject, error) {
+	query := `SELECT type, sql FROM sqlite_master WHERE tbl_name = ? AND sql IS NOT NULL
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END, name`
+	rows, err := b.exec.QueryContext(b.ctx, query, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read definition of table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var objects []sqliteObject
+	for rows.Next() {
+		var o sqliteObject
+		if err := rows.Scan(&o.typ, &o.sql); err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
+
+// checkSQLiteNotReferenced refuses to rebuild a table referenced by foreign keys while they are enforced:
+// dropping it would run the ON DELETE actions of the referencing rows. Foreign keys cannot be switched off
+// inside a transaction, so the migration must run without one and disable them first.
+func (b *Builder) checkSQLiteNotReferenced(table string) error {
+	query := `SELECT m.name FROM sqlite_master m, pragma_foreign_key_list(m.name) f
+		WHERE m.type = 'table' AND f."table" = ? AND m.name <> ? AND (SELECT foreign_keys FROM pragma_foreign_keys) = 1`
+	rows, err := b.exec.QueryContext(b.ctx, query, table, table)
+	if err != nil {
+		return fmt.Errorf("failed to read foreign keys referencing table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var referencing []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		referencing = append(referencing, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(referencing) > 0 {
+		return fmt.Errorf("table %s is referenced by %s: run the migration without transaction after PRAGMA foreign_keys = OFF",
+			table, strings.Join(referencing, ", "))
+	}
+	return nil
+}
+
+// querySQLiteColumns returns the comma separated columns of a table
+func (b *Builder) querySQLiteColumns(table string) (string, error) {
+	rows, err := b.exec.QueryContext(b.ctx, `SELECT name FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return "", fmt.Errorf("failed to read columns of table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return "", err
+		}
+		columns = append(columns, name)
+	}
+	return strings.Join(columns, ", "), rows.Err()
+}
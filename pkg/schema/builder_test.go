@@ -0,0 +1,192 @@
+package schema
+
+import (
+	"testing"
+)
+
+func TestCreateTableSQL(t *testing.T) {
+	users := func(t *Table) {
+		t.Column(
+			BigInt("id").PrimaryKey(),
+			String("email", 0).NotNull(),
+			Boolean("active").NotNull().Default("true"),
+			Timestamp("created_at"),
+		)
+		t.Check("email_not_empty", "email <> ''")
+	}
+
+	tests := []struct {
+		name    string
+		dialect Dialect
+		build   func(t *Table)
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "postgres",
+			dialect: DialectPostgres,
+			build:   users,
+			want: "CREATE TABLE users (id BIGINT PRIMARY KEY, email VARCHAR(255) NOT NULL, active BOOLEAN NOT NULL DEFAULT true, " +
+				"created_at TIMESTAMPTZ, CONSTRAINT email_not_empty CHECK (email <> ''))",
+		},
+		{
+			name:    "sqlite",
+			dialect: DialectSQLite,
+			build:   users,
+			want: "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL, active INTEGER NOT NULL DEFAULT true, " +
+				"created_at DATETIME, CONSTRAINT email_not_empty CHECK (email <> ''))",
+		},
+		{
+			name:    "clickhouse",
+			dialect: DialectClickHouse,
+			build:   users,
+			want: "CREATE TABLE users (id Int64, email String, active Bool DEFAULT true, " +
+				"created_at Nullable(DateTime64(3)), CONSTRAINT email_not_empty CHECK (email <> '')) ENGINE = MergeTree() ORDER BY (id)",
+		},
+		{
+			name:    "auto increment on postgres",
+			dialect: DialectPostgres,
+			build: func(t *Table) {
+				t.Column(BigInt("id").PrimaryKey().AutoIncrement())
+			},
+			want: "CREATE TABLE users (id BIGSERIAL PRIMARY KEY)",
+		},
+		{
+			name:    "auto increment on sqlite",
+			dialect: DialectSQLite,
+			build: func(t *Table) {
+				t.Column(BigInt("id").PrimaryKey().AutoIncrement())
+			},
+			want: "CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT)",
+		},
+		{
+			name:    "auto increment on clickhouse",
+			dialect: DialectClickHouse,
+			build: func(t *Table) {
+				t.Column(BigInt("id").PrimaryKey().AutoIncrement())
+			},
+			wantErr: true,
+		},
+		{
+			name:    "clickhouse engine and sorting key",
+			dialect: DialectClickHouse,
+			build: func(t *Table) {
+				t.Column(UUID("id"), Date("day"), NewColumn("kind", "LowCardinality(String)").NotNull())
+				t.Engine("ReplacingMergeTree()").OrderBy("day", "id")
+			},
+			want: "CREATE TABLE users (id UUID, day Date, kind LowCardinality(String)) ENGINE = ReplacingMergeTree() ORDER BY (day, id)",
+		},
+		{
+			name:    "composite primary key and foreign key",
+			dialect: DialectPostgres,
+			build: func(t *Table) {
+				t.Column(BigInt("org_id").NotNull(), BigInt("user_id").NotNull())
+				t.PrimaryKey("org_id", "user_id")
+				t.ForeignKey(ForeignKey{Columns: []string{"org_id"}, RefTable: "orgs", RefColumns: []string{"id"}, OnDelete: "CASCADE"})
+			},
+			want: "CREATE TABLE users (org_id BIGINT NOT NULL, user_id BIGINT NOT NULL, PRIMARY KEY (org_id, user_id), " +
+				"CONSTRAINT fk_users_org_id FOREIGN KEY (org_id) REFERENCES orgs (id) ON DELETE CASCADE)",
+		},
+		{
+			name:    "foreign key on clickhouse",
+			dialect: DialectClickHouse,
+			build: func(t *Table) {
+				t.Column(BigInt("org_id"))
+				t.ForeignKey(ForeignKey{Columns: []string{"org_id"}, RefTable: "orgs", RefColumns: []string{"id"}})
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CreateTableSQL(tt.dialect, "users", tt.build)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlterSQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		render  func() (string, error)
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "rename table on postgres",
+			render: func() (string, error) { return RenameTableSQL(DialectPostgres, "a", "b") },
+			want:   "ALTER TABLE a RENAME TO b",
+		},
+		{
+			name:   "rename table on clickhouse",
+			render: func() (string, error) { return RenameTableSQL(DialectClickHouse, "a", "b") },
+			want:   "RENAME TABLE a TO b",
+		},
+		{
+			name:   "add column on clickhouse",
+			render: func() (string, error) { return AddColumnSQL(DialectClickHouse, "users", Integer("age")) },
+			want:   "ALTER TABLE users ADD COLUMN age Nullable(Int32)",
+		},
+		{
+			name: "add not null column without default on sqlite",
+			render: func() (string, error) {
+				return AddColumnSQL(DialectSQLite, "users", Integer("age").NotNull())
+			},
+			wantErr: true,
+		},
+		{
+			name: "unique index on postgres",
+			render: func() (string, error) {
+				return CreateIndexSQL(DialectPostgres, Index{Table: "users", Columns: []string{"email"}, Unique: true})
+			},
+			want: "CREATE UNIQUE INDEX idx_users_email ON users (email)",
+		},
+		{
+			name: "index on clickhouse",
+			render: func() (string, error) {
+				return CreateIndexSQL(DialectClickHouse, Index{Table: "users", Columns: []string{"email"}, Type: "bloom_filter"})
+			},
+			want: "ALTER TABLE users ADD INDEX idx_users_email (email) TYPE bloom_filter GRANULARITY 1",
+		},
+		{
+			name:   "drop index on clickhouse",
+			render: func() (string, error) { return DropIndexSQL(DialectClickHouse, "users", "idx_users_email") },
+			want:   "ALTER TABLE users DROP INDEX idx_users_email",
+		},
+		{
+			name:   "drop index on sqlite",
+			render: func() (string, error) { return DropIndexSQL(DialectSQLite, "users", "idx_users_email") },
+			want:   "DROP INDEX idx_users_email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.render()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("\ngot:  %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
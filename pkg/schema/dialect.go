@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect is the SQL flavor statements are rendered for. Its values match the Name of amigo's drivers.
+type Dialect string
+
+const (
+	DialectPostgres   Dialect = "postgres"
+	DialectSQLite     Dialect = "sqlite"
+	DialectClickHouse Dialect = "clickhouse"
+)
+
+// ParseDialect returns the dialect of a driver name, as returned by amigo's Driver.Name
+func ParseDialect(name string) (Dialect, error) {
+	switch d := Dialect(name); d {
+	case DialectPostgres, DialectSQLite, DialectClickHouse:
+		return d, nil
+	default:
+		return "", fmt.Errorf("unsupported dialect '%s', must be 'postgres', 'sqlite' or 'clickhouse'", name)
+	}
+}
+
+// ColumnType is the type of a column. The lowercase types declared below are rendered for each dialect,
+// any other value (e.g. "CITEXT" or "LowCardinality(String)") is written as is.
+type ColumnType string
+
+const (
+	TypeSmallInt  ColumnType = "smallint"
+	TypeInteger   ColumnType = "integer"
+	TypeBigInt    ColumnType = "bigint"
+	TypeFloat     ColumnType = "float"
+	TypeDouble    ColumnType = "double"
+	TypeDecimal   ColumnType = "decimal"
+	TypeBoolean   ColumnType = "boolean"
+	TypeString    ColumnType = "string"
+	TypeText      ColumnType = "text"
+	TypeDate      ColumnType = "date"
+	TypeTimestamp ColumnType = "timestamp"
+	TypeUUID      ColumnType = "uuid"
+	TypeJSON      ColumnType = "json"
+	TypeBinary    ColumnType = "binary"
+)
+
+// defaultStringSize is the size of TypeString columns declared without one
+const defaultStringSize = 255
+
+// renderType returns the SQL type of the column for the dialect
+func (c *Column) renderType(d Dialect) string {
+	size := c.size
+	if c.typ == TypeString && size == 0 {
+		size = defaultStringSize
+	}
+
+	switch d {
+	case DialectPostgres:
+		switch c.typ {
+		case TypeSmallInt:
+			return "SMALLINT"
+		case TypeInteger:
+			if c.autoIncrement {
+				return "SERIAL"
+			}
+			return "INTEGER"
+		case TypeBigInt:
+			if c.autoIncrement {
+				return "BIGSERIAL"
+			}
+			return "BIGINT"
+		case TypeFloat:
+			return "REAL"
+		case TypeDouble:
+			return "DOUBLE PRECISION"
+		case TypeDecimal:
+			return fmt.Sprintf("NUMERIC(%d, %d)", c.precision, c.scale)
+		case TypeBoolean:
+			return "BOOLEAN"
+		case TypeString:
+			return fmt.Sprintf("VARCHAR(%d)", size)
+		case TypeText:
+			return "TEXT"
+		case TypeDate:
+			return "DATE"
+		case TypeTimestamp:
+			return "TIMESTAMPTZ"
+		case TypeUUID:
+			return "UUID"
+		case TypeJSON:
+			return "JSONB"
+		case TypeBinary:
+			return "BYTEA"
+		}
+	case DialectSQLite:
+		switch c.typ {
+		case TypeSmallInt, TypeInteger, TypeBigInt, TypeBoolean:
+			return "INTEGER"
+		case TypeFloat, TypeDouble:
+			return "REAL"
+		case TypeDecimal:
+			return "NUMERIC"
+		case TypeString, TypeText, TypeUUID, TypeJSON:
+			return "TEXT"
+		case TypeDate:
+			return "DATE"
+		case TypeTimestamp:
+			return "DATETIME"
+		case TypeBinary:
+			return "BLOB"
+		}
+	case DialectClickHouse:
+		var typ string
+		switch c.typ {
+		case TypeSmallInt:
+			typ = "Int16"
+		case TypeInteger:
+			typ = "Int32"
+		case TypeBigInt:
+			typ = "Int64"
+		case TypeFloat:
+			typ = "Float32"
+		case TypeDouble:
+			typ = "Float64"
+		case TypeDecimal:
+			typ = fmt.Sprintf("Decimal(%d, %d)", c.precision, c.scale)
+		case TypeBoolean:
+			typ = "Bool"
+		case TypeString, TypeText, TypeJSON, TypeBinary:
+			typ = "String"
+		case TypeDate:
+			typ = "Date"
+		case TypeTimestamp:
+			typ = "DateTime64(3)"
+		case TypeUUID:
+			typ = "UUID"
+		default:
+			typ = string(c.typ)
+		}
+		// ClickHouse columns are not nullable unless wrapped
+		if !c.notNull && !c.primaryKey && !strings.HasPrefix(typ, "Nullable(") {
+			typ = "Nullable(" + typ + ")"
+		}
+		return typ
+	}
+
+	return string(c.typ)
+}
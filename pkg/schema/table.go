@@ -0,0 +1,368 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Column is a column definition, built with the constructors below and refined with its chainable methods:
+//
+//	schema.BigInt("id").PrimaryKey().AutoIncrement()
+//	schema.String("email", 255).NotNull().Unique()
+type Column struct {
+	name          string
+	typ           ColumnType
+	size          int
+	precision     int
+	scale         int
+	notNull       bool
+	defaultValue  string
+	primaryKey    bool
+	autoIncrement bool
+	unique        bool
+}
+
+// NewColumn returns a column of any type, see ColumnType
+func NewColumn(name string, typ ColumnType) *Column {
+	return &Column{name: name, typ: typ}
+}
+
+func SmallInt(name string) *Column  { return NewColumn(name, TypeSmallInt) }
+func Integer(name string) *Column   { return NewColumn(name, TypeInteger) }
+func BigInt(name string) *Column    { return NewColumn(name, TypeBigInt) }
+func Float(name string) *Column     { return NewColumn(name, TypeFloat) }
+func Double(name string) *Column    { return NewColumn(name, TypeDouble) }
+func Boolean(name string) *Column   { return NewColumn(name, TypeBoolean) }
+func Text(name string) *Column      { return NewColumn(name, TypeText) }
+func Date(name string) *Column      { return NewColumn(name, TypeDate) }
+func Timestamp(name string) *Column { return NewColumn(name, TypeTimestamp) }
+func UUID(name string) *Column      { return NewColumn(name, TypeUUID) }
+func JSON(name string) *Column      { return NewColumn(name, TypeJSON) }
+func Binary(name string) *Column    { return NewColumn(name, TypeBinary) }
+
+// String returns a variable length string column, size 0 means 255
+func String(name string, size int) *Column {
+	c := NewColumn(name, TypeString)
+	c.size = size
+	return c
+}
+
+// Decimal returns a fixed precision number column
+func Decimal(name string, precision, scale int) *Column {
+	c := NewColumn(name, TypeDecimal)
+	c.precision = precision
+	c.scale = scale
+	return c
+}
+
+// NotNull adds a NOT NULL constraint, on ClickHouse it keeps the column from being Nullable
+func (c *Column) NotNull() *Column {
+	c.notNull = true
+	return c
+}
+
+// Default sets the default value, expr is a SQL expression such as "0", "'draft'" or "CURRENT_TIMESTAMP"
+func (c *Column) Default(expr string) *Column {
+	c.defaultValue = expr
+	return c
+}
+
+// PrimaryKey makes the column the primary key, use Table.PrimaryKey for composite keys
+func (c *Column) PrimaryKey() *Column {
+	c.primaryKey = true
+	return c
+}
+
+// AutoIncrement generates the values of an integer primary key (SERIAL on Postgres, AUTOINCREMENT on SQLite).
+// ClickHouse has no auto increment.
+func (c *Column) AutoIncrement() *Column {
+	c.autoIncrement = true
+	return c
+}
+
+// Unique adds a UNIQUE constraint. ClickHouse has no unique constraint.
+func (c *Column) Unique() *Column {
+	c.unique = true
+	return c
+}
+
+// render returns the column definition for the dialect
+func (c *Column) render(d Dialect) (string, error) {
+	if err := c.validate(d); err != nil {
+		return "", err
+	}
+
+	parts := []string{c.name, c.renderType(d)}
+	if c.primaryKey && d != DialectClickHouse {
+		parts = append(parts, "PRIMARY KEY")
+		if c.autoIncrement && d == DialectSQLite {
+			parts = append(parts, "AUTOINCREMENT")
+		}
+	}
+	if c.notNull && !c.primaryKey && d != DialectClickHouse {
+		parts = append(parts, "NOT NULL")
+	}
+	if c.unique {
+		parts = append(parts, "UNIQUE")
+	}
+	if c.defaultValue != "" {
+		parts = append(parts, "DEFAULT "+c.defaultValue)
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+func (c *Column) validate(d Dialect) error {
+	if c.name == "" {
+		return errors.New("column name is required")
+	}
+	if c.typ == "" {
+		return fmt.Errorf("type of column %s is required", c.name)
+	}
+
+	switch {
+	case c.autoIncrement && d == DialectClickHouse:
+		return fmt.Errorf("column %s: clickhouse does not support auto increment", c.name)
+	case c.autoIncrement && !c.primaryKey:
+		return fmt.Errorf("column %s: auto increment requires a primary key column", c.name)
+	case c.autoIncrement && c.typ != TypeInteger && c.typ != TypeBigInt:
+		return fmt.Errorf("column %s: auto increment requires an integer column", c.name)
+	case c.unique && d == DialectClickHouse:
+		return fmt.Errorf("column %s: clickhouse does not support unique constraints", c.name)
+	}
+	return nil
+}
+
+// ForeignKey references the columns of another table
+type ForeignKey struct {
+	// Name of the constraint, fk_<table>_<columns> when empty
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	// OnDelete and OnUpdate are referential actions such as CASCADE or SET NULL
+	OnDelete string
+	OnUpdate string
+}
+
+// constraint returns the table constraint clause of the foreign key of table
+func (fk ForeignKey) constraint(table string) (string, error) {
+	if len(fk.Columns) == 0 || fk.RefTable == "" || len(fk.RefColumns) == 0 {
+		return "", fmt.Errorf("foreign key on %s requires columns, a referenced table and referenced columns", table)
+	}
+
+	name := fk.Name
+	if name == "" {
+		name = fmt.Sprintf("fk_%s_%s", table, strings.Join(fk.Columns, "_"))
+	}
+
+	clause := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		name, strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "))
+	if fk.OnDelete != "" {
+		clause += " ON DELETE " + fk.OnDelete
+	}
+	if fk.OnUpdate != "" {
+		clause += " ON UPDATE " + fk.OnUpdate
+	}
+	return clause, nil
+}
+
+// checkConstraint returns the table constraint clause of a check constraint
+func checkConstraint(name, expr string) (string, error) {
+	if name == "" || expr == "" {
+		return "", errors.New("check constraint requires a name and an expression")
+	}
+	return fmt.Sprintf("CONSTRAINT %s CHECK (%s)", name, expr), nil
+}
+
+// Table defines a table in Builder.CreateTable
+type Table struct {
+	name        string
+	columns     []*Column
+	primaryKey  []string
+	foreignKeys []ForeignKey
+	checks      [][2]string
+	engine      string
+	orderBy     []string
+}
+
+// Column adds columns to the table
+func (t *Table) Column(columns ...*Column) *Table {
+	t.columns = append(t.columns, columns...)
+	return t
+}
+
+// PrimaryKey declares a composite primary key
+func (t *Table) PrimaryKey(columns ...string) *Table {
+	t.primaryKey = columns
+	return t
+}
+
+// ForeignKey adds a foreign key constraint. ClickHouse has no foreign keys.
+func (t *Table) ForeignKey(fk ForeignKey) *Table {
+	t.foreignKeys = append(t.foreignKeys, fk)
+	return t
+}
+
+// Check adds a check constraint
+func (t *Table) Check(name, expr string) *Table {
+	t.checks = append(t.checks, [2]string{name, expr})
+	return t
+}
+
+// Engine sets the ClickHouse table engine, MergeTree() by default. Ignored by other dialects.
+func (t *Table) Engine(engine string) *Table {
+	t.engine = engine
+	return t
+}
+
+// OrderBy sets the ClickHouse sorting key, the primary key by default. Ignored by other dialects.
+func (t *Table) OrderBy(columns ...string) *Table {
+	t.orderBy = columns
+	return t
+}
+
+// render returns the CREATE TABLE statement for the dialect
+func (t *Table) render(d Dialect) (string, error) {
+	if t.name == "" {
+		return "", errors.New("table name is required")
+	}
+	if len(t.columns) == 0 {
+		return "", fmt.Errorf("table %s has no columns", t.name)
+	}
+
+	// ClickHouse does not allow Nullable columns in the sorting key
+	keys := make(map[string]bool)
+	if d == DialectClickHouse {
+		for _, name := range slices.Concat(t.primaryKey, t.orderBy) {
+			keys[name] = true
+		}
+	}
+
+	var definitions []string
+	var primaryKey []string
+	for _, c := range t.columns {
+		if keys[c.name] && !c.notNull {
+			key := *c
+			c = key.NotNull()
+		}
+
+		definition, err := c.render(d)
+		if err != nil {
+			return "", fmt.Errorf("table %s: %w", t.name, err)
+		}
+		definitions = append(definitions, definition)
+		if c.primaryKey {
+			primaryKey = append(primaryKey, c.name)
+		}
+	}
+
+	if len(t.primaryKey) > 0 {
+		primaryKey = t.primaryKey
+		if d != DialectClickHouse {
+			definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(t.primaryKey, ", ")))
+		}
+	}
+
+	for _, fk := range t.foreignKeys {
+		if d == DialectClickHouse {
+			return "", fmt.Errorf("table %s: clickhouse does not support foreign keys", t.name)
+		}
+		clause, err := fk.constraint(t.name)
+		if err != nil {
+			return "", err
+		}
+		definitions = append(definitions, clause)
+	}
+
+	for _, check := range t.checks {
+		clause, err := checkConstraint(check[0], check[1])
+		if err != nil {
+			return "", fmt.Errorf("table %s: %w", t.name, err)
+		}
+		definitions = append(definitions, clause)
+	}
+
+	query := fmt.Sprintf("CREATE TABLE %s (%s)", t.name, strings.Join(definitions, ", "))
+	if d != DialectClickHouse {
+		return query, nil
+	}
+
+	engine := t.engine
+	if engine == "" {
+		engine = "MergeTree()"
+	}
+
+	orderBy := "tuple()"
+	if len(t.orderBy) > 0 {
+		orderBy = "(" + strings.Join(t.orderBy, ", ") + ")"
+	} else if len(primaryKey) > 0 {
+		orderBy = "(" + strings.Join(primaryKey, ", ") + ")"
+	}
+
+	return fmt.Sprintf("%s ENGINE = %s ORDER BY %s", query, engine, orderBy), nil
+}
+
+// Index is an index on the columns of a table
+type Index struct {
+	// Name of the index, idx_<table>_<columns> when empty
+	Name    string
+	Table   string
+	Columns []string
+	Unique  bool
+	// Type is the ClickHouse data skipping index type, minmax by default. Ignored by other dialects.
+	Type string
+	// Granularity is the ClickHouse data skipping index granularity, 1 by default. Ignored by other dialects.
+	Granularity int
+}
+
+// IndexName returns the name of the index, Name or idx_<table>_<columns> when empty
+func (idx Index) IndexName() string {
+	if idx.Name != "" {
+		return idx.Name
+	}
+	return fmt.Sprintf("idx_%s_%s", idx.Table, strings.Join(idx.Columns, "_"))
+}
+
+// render returns the statement creating the index for the dialect
+func (idx Index) render(d Dialect) (string, error) {
+	if idx.Table == "" || len(idx.Columns) == 0 {
+		return "", errors.New("index requires a table and columns")
+	}
+
+	if d == DialectClickHouse {
+		if idx.Unique {
+			return "", fmt.Errorf("index %s: clickhouse does not support unique indexes", idx.IndexName())
+		}
+
+		typ := idx.Type
+		if typ == "" {
+			typ = "minmax"
+		}
+		granularity := idx.Granularity
+		if granularity == 0 {
+			granularity = 1
+		}
+
+		return fmt.Sprintf("ALTER TABLE %s ADD INDEX %s (%s) TYPE %s GRANULARITY %d",
+			idx.Table, idx.IndexName(), strings.Join(idx.Columns, ", "), typ, granularity), nil
+	}
+
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, idx.IndexName(), idx.Table, strings.Join(idx.Columns, ", ")), nil
+}
+
+// Name returns the name of the column
+func (c *Column) Name() string {
+	return c.name
+}
+
+// Type returns the type of the column
+func (c *Column) Type() ColumnType {
+	return c.typ
+}
@@ -0,0 +1,97 @@
+package termcolor
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Mode controls when ANSI color codes are emitted
+type Mode int
+
+const (
+	// ModeAuto emits colors when the output is a terminal, honoring NO_COLOR and FORCE_COLOR
+	ModeAuto Mode = iota
+	// ModeAlways always emits colors
+	ModeAlways
+	// ModeNever never emits colors
+	ModeNever
+)
+
+// ParseMode parses a color mode from its flag value: auto, always or never
+func ParseMode(s string) (Mode, error) {
+	switch s {
+	case "", "auto":
+		return ModeAuto, nil
+	case "always":
+		return ModeAlways, nil
+	case "never":
+		return ModeNever, nil
+	default:
+		return ModeAuto, fmt.Errorf("invalid color mode '%s', must be 'auto', 'always' or 'never'", s)
+	}
+}
+
+// String returns the flag value of the mode
+func (m Mode) String() string {
+	switch m {
+	case ModeAlways:
+		return "always"
+	case ModeNever:
+		return "never"
+	default:
+		return "auto"
+	}
+}
+
+// Enabled reports whether colors should be written to w.
+//
+// An explicit ModeAlways or ModeNever wins. In ModeAuto, a non-empty NO_COLOR disables colors, a non-empty
+// FORCE_COLOR (other than "0" or "false") enables them, and otherwise colors are enabled only when w is a
+// terminal and TERM is not "dumb".
+func Enabled(mode Mode, w io.Writer) bool {
+	switch mode {
+	case ModeAlways:
+		return true
+	case ModeNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	if force := os.Getenv("FORCE_COLOR"); force != "" {
+		return force != "0" && force != "false"
+	}
+
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	return IsTerminal(w)
+}
+
+// IsTerminal reports whether v is an *os.File connected to a terminal
+func IsTerminal(v any) bool {
+	f, ok := v.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	// the null device is a character device too, but never a terminal
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+
+	return true
+}
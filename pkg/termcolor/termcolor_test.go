@@ -0,0 +1,61 @@
+package termcolor
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Mode
+		wantErr bool
+	}{
+		{value: "", want: ModeAuto},
+		{value: "auto", want: ModeAuto},
+		{value: "always", want: ModeAlways},
+		{value: "never", want: ModeNever},
+		{value: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseMode(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("mode: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       Mode
+		noColor    string
+		forceColor string
+		want       bool
+	}{
+		{name: "auto on a non terminal", mode: ModeAuto, want: false},
+		{name: "always on a non terminal", mode: ModeAlways, want: true},
+		{name: "never with FORCE_COLOR", mode: ModeNever, forceColor: "1", want: false},
+		{name: "auto with FORCE_COLOR", mode: ModeAuto, forceColor: "1", want: true},
+		{name: "auto with FORCE_COLOR=0", mode: ModeAuto, forceColor: "0", want: false},
+		{name: "NO_COLOR wins over FORCE_COLOR", mode: ModeAuto, noColor: "1", forceColor: "1", want: false},
+		{name: "always wins over NO_COLOR", mode: ModeAlways, noColor: "1", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			t.Setenv("FORCE_COLOR", tt.forceColor)
+
+			if got := Enabled(tt.mode, &bytes.Buffer{}); got != tt.want {
+				t.Errorf("Enabled(): got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
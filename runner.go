@@ -1,8 +1,11 @@
 package amigo
 
 import (
+	"context"
+	"fmt"
 	"slices"
 	"sync"
+	"time"
 )
 
 type Runner struct {
@@ -14,30 +17,44 @@ func NewRunner(config Configuration) *Runner {
 	return &Runner{config: config}
 }
 
-func (r *Runner) filterNonAppliedMigrations(migrations []Migration, appliedMigrations []MigrationRecord) []Migration {
-	appliedDates := make(map[int64]struct{})
-	for _, m := range appliedMigrations {
-		appliedDates[m.Date] = struct{}{}
+// filterNonAppliedMigrations returns the migrations to apply, oldest first and after their dependencies,
+// skipping those not selected by options
+func (r *Runner) filterNonAppliedMigrations(migrations []Migration, appliedMigrations []MigrationRecord, options runnerUpOpts) ([]Migration, error) {
+	appliedDates := appliedDateSet(appliedMigrations)
+
+	sorted, err := sortByDependencies(migrations, appliedDates)
+	if err != nil {
+		return nil, err
 	}
 
 	var result []Migration
-	for _, m := range migrations {
-		if _, applied := appliedDates[m.Date()]; !applied {
+	for _, m := range sorted {
+		if _, applied := appliedDates[m.Date()]; !applied && options.selects(m) {
 			result = append(result, m)
 		}
 	}
 
-	// sort oldest first
-	slices.SortFunc(result, func(a, b Migration) int {
-		if a.Date() < b.Date() {
-			return -1
-		} else if a.Date() > b.Date() {
-			return 1
-		}
-		return 0
-	})
+	return result, nil
+}
+
+// getAppliedMigrations returns the applied migrations, where squashed migrations replace the migrations they
+// replace, see Squashed
+func (r *Runner) getAppliedMigrations(ctx context.Context, migrations []Migration) ([]MigrationRecord, error) {
+	appliedMigrations, err := r.config.Driver.GetAppliedMigrations(ctx, r.config.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
 
-	return result
+	return resolveSquashedMigrations(migrations, appliedMigrations)
+}
+
+// appliedDateSet returns the dates of the applied migrations
+func appliedDateSet(appliedMigrations []MigrationRecord) map[int64]struct{} {
+	appliedDates := make(map[int64]struct{}, len(appliedMigrations))
+	for _, m := range appliedMigrations {
+		appliedDates[m.Date] = struct{}{}
+	}
+	return appliedDates
 }
 
 func (r *Runner) sortNewestFirstMigrationRecord(appliedMigrations []MigrationRecord) {
@@ -50,3 +67,49 @@ func (r *Runner) sortNewestFirstMigrationRecord(appliedMigrations []MigrationRec
 		return 0
 	})
 }
+
+// newMigrationRecord builds the record stored in schema_migrations for a migration applied in duration
+func (r *Runner) newMigrationRecord(m Migration, duration time.Duration) MigrationRecord {
+	return MigrationRecord{
+		Date:         m.Date(),
+		Name:         m.Name(),
+		Environment:  r.config.Environment,
+		Duration:     duration,
+		OSUser:       currentOSUser(),
+		Hostname:     currentHostname(),
+		AmigoVersion: Version(),
+		AppVersion:   r.config.AppVersion,
+		Kind:         migrationKind(m),
+		Checksum:     migrationChecksum(m),
+	}
+}
+
+// migrationKind returns the kind of source a migration is written in
+func migrationKind(m Migration) MigrationKind {
+	switch m.(type) {
+	case SQLMigration, *SQLMigration:
+		return MigrationKindSQL
+	default:
+		return MigrationKindGo
+	}
+}
+
+// isIrreversible reports whether a migration declares it cannot be reverted, see Irreversible
+func isIrreversible(m Migration) bool {
+	irreversible, ok := m.(Irreversible)
+	return ok && irreversible.Irreversible()
+}
+
+type driverContextKey struct{}
+
+// ContextWithDriver returns a copy of ctx carrying the driver. The runner sets it before running migrations,
+// so Up and Down can render statements for the database with DriverFromContext.
+func ContextWithDriver(ctx context.Context, driver Driver) context.Context {
+	return context.WithValue(ctx, driverContextKey{}, driver)
+}
+
+// DriverFromContext returns the driver of the runner executing the migration, see ContextWithDriver
+func DriverFromContext(ctx context.Context) (Driver, bool) {
+	driver, ok := ctx.Value(driverContextKey{}).(Driver)
+	return driver, ok && driver != nil
+}
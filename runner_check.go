@@ -0,0 +1,205 @@
+package amigo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// CheckResult describes how the database compares to the migrations known by the binary
+type CheckResult struct {
+	// Applied is the number of known migrations recorded as applied, including up-to-date repeatable migrations
+	Applied int
+
+	// Pending lists known migrations that are not applied yet, oldest first, followed by the repeatable
+	// migrations that never ran, with a zero Date
+	Pending []MigrationRecord
+
+	// Orphaned lists applied migrations recorded in the database that the binary does not know about, oldest first
+	Orphaned []MigrationRecord
+
+	// Skipped lists known migrations that are not applied and not selected by the environment or tags, oldest first
+	Skipped []MigrationRecord
+
+	// Drifted lists applied migrations whose checksum changed since they were applied, oldest first, followed by
+	// the repeatable migrations whose checksum changed since their last run, with a zero Date. Records keep the
+	// checksum stored in the database. See Checksummer.
+	Drifted []MigrationRecord
+
+	// LayoutUpgradePending is set when amigo's tables have an older layout, which the next command writing to the
+	// database upgrades. Only the date, name and applied_at of the records are read then, so checksums and
+	// repeatable migrations are not checked.
+	LayoutUpgradePending bool
+}
+
+// UpToDate reports whether the database matches the migrations exactly
+func (c CheckResult) UpToDate() bool {
+	return len(c.Pending) == 0 && len(c.Orphaned) == 0 && len(c.Drifted) == 0
+}
+
+// Check compares the applied migrations with the given ones without writing anything to the database.
+// Unlike GetMigrationsStatuses, it never creates the schema_migrations table: when the driver implements
+// SchemaMigrationsTableInspector and the table does not exist, every migration is reported as pending.
+// Migrations not selected by the environment and tags options are reported as skipped instead of pending.
+// Applied migrations are compared with their recorded checksum, when one was recorded, and reported as drifted
+// when it differs, like repeatable migrations that changed since their last run. Squashed migrations keep the
+// record of the newest migration they replace and are not compared.
+func (r *Runner) Check(ctx context.Context, migrations []Migration, opts ...RunnerUpOptsFunc) (CheckResult, error) {
+	var result CheckResult
+	options := r.newRunnerUpOpts(opts)
+	migrations, repeatables := splitRepeatableMigrations(migrations)
+
+	state, err := r.appliedMigrationsReadOnly(ctx, migrations)
+	if err != nil {
+		return result, err
+	}
+	result.LayoutUpgradePending = state.upgradePending
+
+	known := make(map[int64]struct{}, len(migrations))
+	for _, m := range migrations {
+		known[m.Date()] = struct{}{}
+	}
+
+	appliedByDate := make(map[int64]MigrationRecord, len(state.applied))
+	for _, am := range state.applied {
+		appliedByDate[am.Date] = am
+		if _, ok := known[am.Date]; !ok {
+			result.Orphaned = append(result.Orphaned, am)
+		}
+	}
+
+	for _, m := range migrations {
+		if record, ok := appliedByDate[m.Date()]; ok {
+			result.Applied++
+			if len(squashedVersions(m)) == 0 && checksumDrifted(record.Checksum, m) {
+				result.Drifted = append(result.Drifted, MigrationRecord{Date: m.Date(), Name: m.Name(), Checksum: record.Checksum})
+			}
+			continue
+		}
+		if !options.selects(m) {
+			result.Skipped = append(result.Skipped, MigrationRecord{Date: m.Date(), Name: m.Name()})
+			continue
+		}
+		result.Pending = append(result.Pending, MigrationRecord{Date: m.Date(), Name: m.Name()})
+	}
+
+	sortRecords := func(a, b MigrationRecord) int {
+		if a.Date < b.Date {
+			return -1
+		} else if a.Date > b.Date {
+			return 1
+		}
+		return 0
+	}
+	slices.SortFunc(result.Pending, sortRecords)
+	slices.SortFunc(result.Orphaned, sortRecords)
+	slices.SortFunc(result.Skipped, sortRecords)
+	slices.SortFunc(result.Drifted, sortRecords)
+
+	if err := r.checkRepeatables(ctx, repeatables, state, options, &result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// checksumDrifted reports whether a migration changed since it was recorded with checksum. Records without
+// checksum, written before checksums were recorded, and migrations without one never drift.
+func checksumDrifted(checksum string, m Migration) bool {
+	current := migrationChecksum(m)
+	return checksum != "" && current != "" && checksum != current
+}
+
+// checkRepeatables adds the repeatable migrations, sorted by name, to the result of Check: never run ones are
+// pending and changed ones drifted. They are not checked while a layout upgrade is pending.
+func (r *Runner) checkRepeatables(ctx context.Context, repeatables []Migration, state readOnlyState, options runnerUpOpts, result *CheckResult) error {
+	if len(repeatables) == 0 || state.upgradePending {
+		return nil
+	}
+
+	recordsByName := make(map[string]MigrationRecord)
+	if state.tablesExist {
+		driver, ok := r.config.Driver.(RepeatableDriver)
+		if !ok {
+			return fmt.Errorf("%s driver: %w", r.config.Driver.Name(), ErrRepeatableNotSupported)
+		}
+
+		records, err := driver.GetRepeatableMigrations(ctx, r.config.DB)
+		if err != nil {
+			return fmt.Errorf("failed to get repeatable migrations: %w", err)
+		}
+		for _, record := range records {
+			recordsByName[record.Name] = record
+		}
+	}
+
+	for _, m := range repeatables {
+		record, ok := recordsByName[m.Name()]
+		switch {
+		case ok && checksumDrifted(record.Checksum, m):
+			result.Drifted = append(result.Drifted, MigrationRecord{Name: m.Name(), Checksum: record.Checksum})
+		case ok:
+			result.Applied++
+		case !options.selects(m):
+			result.Skipped = append(result.Skipped, MigrationRecord{Name: m.Name()})
+		default:
+			result.Pending = append(result.Pending, MigrationRecord{Name: m.Name()})
+		}
+	}
+
+	return nil
+}
+
+// getAppliedMigrationsReadOnly returns the applied migrations without creating the schema_migrations table,
+// see getAppliedMigrations
+func (r *Runner) getAppliedMigrationsReadOnly(ctx context.Context, migrations []Migration) ([]MigrationRecord, error) {
+	state, err := r.appliedMigrationsReadOnly(ctx, migrations)
+	return state.applied, err
+}
+
+// readOnlyState is the state of amigo's tables read without creating nor upgrading them
+type readOnlyState struct {
+	applied []MigrationRecord
+	// tablesExist is set unless the driver reports that the schema_migrations table does not exist
+	tablesExist bool
+	// upgradePending is set when the layout of the tables is older than the driver's
+	upgradePending bool
+}
+
+// appliedMigrationsReadOnly returns the applied migrations without creating nor upgrading amigo's tables. When
+// their layout is older than the driver's, only the columns of the first layout are read.
+func (r *Runner) appliedMigrationsReadOnly(ctx context.Context, migrations []Migration) (readOnlyState, error) {
+	var state readOnlyState
+
+	if inspector, ok := r.config.Driver.(SchemaMigrationsTableInspector); ok {
+		exists, err := inspector.SchemaMigrationsTableExists(ctx, r.config.DB)
+		if err != nil {
+			return state, fmt.Errorf("failed to check schema migrations table: %w", err)
+		}
+		if !exists {
+			return state, nil
+		}
+	}
+	state.tablesExist = true
+
+	if versioner, ok := r.config.Driver.(SchemaLayoutVersioner); ok {
+		current, latest, err := versioner.SchemaLayoutVersion(ctx, r.config.DB)
+		if err != nil {
+			return state, fmt.Errorf("failed to get schema layout version: %w", err)
+		}
+		state.upgradePending = current < latest
+	}
+
+	getApplied := r.config.Driver.GetAppliedMigrations
+	if reader, ok := r.config.Driver.(baselineRecordsReader); ok && state.upgradePending {
+		getApplied = reader.getBaselineAppliedMigrations
+	}
+
+	appliedMigrations, err := getApplied(ctx, r.config.DB)
+	if err != nil {
+		return state, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	state.applied, err = resolveSquashedMigrations(migrations, appliedMigrations)
+	return state, err
+}
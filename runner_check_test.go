@@ -0,0 +1,259 @@
+package amigo_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alexisvisco/amigo"
+	"github.com/alexisvisco/amigo/pkg/amigotest"
+)
+
+func TestRunner_Check_oldLayout(t *testing.T) {
+	db := openSQLite(t)
+	// schema_migrations as created before the layout was versioned, without the tracking columns
+	_, err := db.ExecContext(t.Context(), `
+		CREATE TABLE schema_migrations (
+			date INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME NOT NULL DEFAULT (DATETIME('now', 'utc'))
+		);
+		INSERT INTO schema_migrations (date, name) VALUES (1, 'first');
+	`)
+	if err != nil {
+		t.Fatalf("create old layout: %v", err)
+	}
+
+	config := amigo.DefaultConfiguration
+	config.DB = db
+	config.Driver = amigo.NewSQLiteDriver("")
+	runner := amigo.NewRunner(config)
+
+	first := amigotest.NewMigration(1, "first", amigotest.Noop, amigotest.Noop)
+	second := amigotest.NewMigration(2, "second", amigotest.Noop, amigotest.Noop)
+
+	result, err := runner.Check(t.Context(), []amigo.Migration{first, second})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !result.LayoutUpgradePending {
+		t.Error("LayoutUpgradePending = false, want true")
+	}
+	if result.Applied != 1 || len(result.Pending) != 1 || result.Pending[0].Date != 2 {
+		t.Errorf("Check() = %+v, want 1 applied and migration 2 pending", result)
+	}
+
+	var tables int
+	if err := db.QueryRowContext(t.Context(), `SELECT count(*) FROM sqlite_master WHERE name LIKE 'schema_migrations_%'`).Scan(&tables); err != nil {
+		t.Fatalf("count tables: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("Check() created %d table(s), want none", tables)
+	}
+
+	// The next up upgrades the layout
+	if err := runner.Up(t.Context(), []amigo.Migration{first, second}); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	result, err = runner.Check(t.Context(), []amigo.Migration{first, second})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.LayoutUpgradePending || !result.UpToDate() {
+		t.Errorf("Check() = %+v after up, want up to date without pending upgrade", result)
+	}
+}
+
+// checksumMigration is a migration with a checksum, see amigo.Checksummer
+type checksumMigration struct {
+	amigo.Migration
+	checksum   string
+	repeatable bool
+}
+
+func (m checksumMigration) Checksum() string { return m.checksum }
+func (m checksumMigration) Repeatable() bool { return m.repeatable }
+
+// checkFixture returns a driver where migrations 1 (checksum "a") and 3 (unknown to the binary) are applied,
+// and the repeatable migration views ran with checksum "v"
+func checkFixture(t *testing.T) (*amigotest.Driver, amigo.Configuration) {
+	t.Helper()
+
+	driver := amigotest.NewDriver(
+		amigo.MigrationRecord{Date: 1, Name: "first", Checksum: "a"},
+		amigo.MigrationRecord{Date: 3, Name: "removed"},
+	)
+	if err := driver.UpsertRepeatableMigration(t.Context(), nil, amigo.MigrationRecord{Name: "views", Checksum: "v"}); err != nil {
+		t.Fatalf("UpsertRepeatableMigration() error = %v", err)
+	}
+
+	config := amigo.DefaultConfiguration
+	config.Driver = driver
+	return driver, config
+}
+
+func TestRunner_Check(t *testing.T) {
+	first := checksumMigration{Migration: amigotest.NewMigration(1, "first", nil, nil), checksum: "a"}
+	second := amigotest.NewMigration(2, "second", nil, nil)
+	views := checksumMigration{Migration: amigotest.NewMigration(0, "views", nil, nil), checksum: "v", repeatable: true}
+	grants := checksumMigration{Migration: amigotest.NewMigration(0, "grants", nil, nil), checksum: "g", repeatable: true}
+
+	t.Run("pending and orphaned", func(t *testing.T) {
+		_, config := checkFixture(t)
+
+		result, err := amigo.NewRunner(config).Check(t.Context(), []amigo.Migration{first, second, views, grants})
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+
+		if result.Applied != 2 {
+			t.Errorf("Applied = %d, want 2", result.Applied)
+		}
+		if got := recordNames(result.Pending); !slices.Equal(got, []string{"second", "grants"}) {
+			t.Errorf("Pending = %v, want [second grants]", got)
+		}
+		if got := recordNames(result.Orphaned); !slices.Equal(got, []string{"removed"}) {
+			t.Errorf("Orphaned = %v, want [removed]", got)
+		}
+		if len(result.Drifted) != 0 {
+			t.Errorf("Drifted = %v, want none", result.Drifted)
+		}
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		_, config := checkFixture(t)
+		edited := first
+		edited.checksum = "b"
+		changedViews := views
+		changedViews.checksum = "w"
+
+		result, err := amigo.NewRunner(config).Check(t.Context(), []amigo.Migration{edited, second, changedViews})
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+
+		if got := recordNames(result.Drifted); !slices.Equal(got, []string{"first", "views"}) {
+			t.Errorf("Drifted = %v, want [first views]", got)
+		}
+		if result.Drifted[0].Checksum != "a" {
+			t.Errorf("Drifted[0].Checksum = %q, want the recorded checksum", result.Drifted[0].Checksum)
+		}
+		if result.UpToDate() {
+			t.Error("UpToDate() = true, want false")
+		}
+	})
+
+	t.Run("without recorded checksum", func(t *testing.T) {
+		_, config := checkFixture(t)
+		removed := checksumMigration{Migration: amigotest.NewMigration(3, "removed", nil, nil), checksum: "r"}
+
+		result, err := amigo.NewRunner(config).Check(t.Context(), []amigo.Migration{first, removed})
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		if !result.UpToDate() {
+			t.Errorf("Check() = %+v, want up to date", result)
+		}
+	})
+
+	t.Run("without table", func(t *testing.T) {
+		driver := amigotest.NewDriver()
+		config := amigo.DefaultConfiguration
+		config.Driver = driver
+
+		result, err := amigo.NewRunner(config).Check(t.Context(), []amigo.Migration{first, views})
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		if got := recordNames(result.Pending); !slices.Equal(got, []string{"first", "views"}) {
+			t.Errorf("Pending = %v, want [first views]", got)
+		}
+		if calls := driver.Calls(amigotest.MethodCreateSchemaMigrationsTableIfNotExists); calls != 0 {
+			t.Errorf("CreateSchemaMigrationsTableIfNotExists called %d times, want 0", calls)
+		}
+	})
+}
+
+// recordNames returns the names of records
+func recordNames(records []amigo.MigrationRecord) []string {
+	names := make([]string, len(records))
+	for i, record := range records {
+		names[i] = record.Name
+	}
+	return names
+}
+
+func TestCLI_check(t *testing.T) {
+	first := checksumMigration{Migration: amigotest.NewMigration(1, "first", nil, nil), checksum: "a"}
+	removed := amigotest.NewMigration(3, "removed", nil, nil)
+
+	tests := []struct {
+		name       string
+		migrations []amigo.Migration
+		wantCode   int
+		wantOutput string
+	}{
+		{
+			name:       "up to date",
+			migrations: []amigo.Migration{first, removed},
+			wantCode:   0,
+			wantOutput: "Database is up to date (2 migration(s) applied)",
+		},
+		{
+			name:       "pending",
+			migrations: []amigo.Migration{first, amigotest.NewMigration(2, "second", nil, nil), removed},
+			wantCode:   2,
+			wantOutput: "1 pending migration(s): 2_second",
+		},
+		{
+			name:       "orphaned",
+			migrations: []amigo.Migration{first},
+			wantCode:   3,
+			wantOutput: "1 applied migration(s) unknown to this binary: 3_removed",
+		},
+		{
+			name:       "drift",
+			migrations: []amigo.Migration{checksumMigration{Migration: first.Migration, checksum: "b"}},
+			wantCode:   4,
+			wantOutput: "1 migration(s) changed since they were applied: 1_first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, config := checkFixture(t)
+
+			var output, errorOutput bytes.Buffer
+			cli := amigo.NewCLI(amigo.CLIConfig{
+				Config:     config,
+				Migrations: tt.migrations,
+				Output:     &output,
+				ErrorOut:   &errorOutput,
+			})
+
+			if code := cli.Run([]string{"--color=never", "check"}); code != tt.wantCode {
+				t.Fatalf("check exit code = %d, want %d, output %q %q", code, tt.wantCode, output.String(), errorOutput.String())
+			}
+			if !strings.Contains(output.String(), tt.wantOutput) {
+				t.Errorf("output = %q, want %q", output.String(), tt.wantOutput)
+			}
+		})
+	}
+
+	t.Run("driver failure", func(t *testing.T) {
+		driver, config := checkFixture(t)
+		driver.FailOn(amigotest.MethodGetAppliedMigrations, errors.New("connection refused"))
+
+		var output, errorOutput bytes.Buffer
+		cli := amigo.NewCLI(amigo.CLIConfig{Config: config, Output: &output, ErrorOut: &errorOutput})
+
+		if code := cli.Run([]string{"--color=never", "check"}); code != 1 {
+			t.Fatalf("check exit code = %d, want 1", code)
+		}
+		if !strings.Contains(errorOutput.String(), "connection refused") {
+			t.Errorf("error output = %q, want the driver error", errorOutput.String())
+		}
+	})
+}
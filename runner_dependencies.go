@@ -0,0 +1,138 @@
+package amigo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// migrationDependencies returns the dates of the migrations a migration depends on, see Dependent.
+// A dependency on a migration replaced by a squashed migration is a dependency on the squashed one.
+func migrationDependencies(m Migration, aliases map[int64]int64) []int64 {
+	dependent, ok := m.(Dependent)
+	if !ok {
+		return nil
+	}
+
+	var dependencies []int64
+	for _, dependency := range dependent.DependsOn() {
+		if squashed, ok := aliases[dependency]; ok {
+			dependency = squashed
+		}
+		if dependency != m.Date() {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	return dependencies
+}
+
+// sortByDependencies returns the migrations oldest first, except that a migration always comes after the
+// migrations it depends on. Dependencies on applied migrations the binary does not know about are satisfied,
+// dependencies on unknown migrations that are not applied are reported, as are cycles.
+func sortByDependencies(migrations []Migration, appliedDates map[int64]struct{}) ([]Migration, error) {
+	aliases := squashAliases(migrations)
+	byDate := make(map[int64]Migration, len(migrations))
+	for _, m := range migrations {
+		byDate[m.Date()] = m
+	}
+
+	// dependents[d] lists the migrations waiting for d, remaining[m] counts the dependencies m waits for
+	dependents := make(map[int64][]int64)
+	remaining := make(map[int64]int, len(migrations))
+	for _, m := range migrations {
+		for _, dependency := range migrationDependencies(m, aliases) {
+			if _, known := byDate[dependency]; !known {
+				if _, applied := appliedDates[dependency]; applied {
+					continue
+				}
+				return nil, fmt.Errorf("migration %d_%s depends on unknown migration %d: %w",
+					m.Date(), m.Name(), dependency, ErrUnmetDependency)
+			}
+			if !slices.Contains(dependents[dependency], m.Date()) {
+				dependents[dependency] = append(dependents[dependency], m.Date())
+				remaining[m.Date()]++
+			}
+		}
+	}
+
+	// Kahn's algorithm, always taking the oldest migration ready to run
+	var ready []int64
+	for _, m := range migrations {
+		if remaining[m.Date()] == 0 {
+			ready = append(ready, m.Date())
+		}
+	}
+
+	sorted := make([]Migration, 0, len(migrations))
+	for len(ready) > 0 {
+		slices.Sort(ready)
+		date := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, byDate[date])
+
+		for _, dependent := range dependents[date] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(sorted) < len(migrations) {
+		var cycle []string
+		for _, m := range migrations {
+			if remaining[m.Date()] > 0 {
+				cycle = append(cycle, fmt.Sprintf("%d_%s", m.Date(), m.Name()))
+			}
+		}
+		slices.Sort(cycle)
+		return nil, fmt.Errorf("dependency cycle between migrations %s", strings.Join(cycle, ", "))
+	}
+
+	return sorted, nil
+}
+
+// checkDependenciesMet verifies that every migration of the plan depends only on applied migrations or on
+// migrations planned before it
+func checkDependenciesMet(plan []Migration, migrations []Migration, appliedDates map[int64]struct{}) error {
+	aliases := squashAliases(migrations)
+	planned := make(map[int64]struct{}, len(plan))
+	for _, m := range plan {
+		for _, dependency := range migrationDependencies(m, aliases) {
+			_, applied := appliedDates[dependency]
+			_, before := planned[dependency]
+			if !applied && !before {
+				return fmt.Errorf("cannot apply migration %d_%s before migration %d: %w",
+					m.Date(), m.Name(), dependency, ErrUnmetDependency)
+			}
+		}
+		planned[m.Date()] = struct{}{}
+	}
+	return nil
+}
+
+// checkNoAppliedDependents verifies that reverting the plan leaves no applied migration depending on a
+// reverted one
+func checkNoAppliedDependents(plan []Migration, migrations []Migration, appliedDates map[int64]struct{}) error {
+	aliases := squashAliases(migrations)
+	reverted := make(map[int64]struct{}, len(plan))
+	for _, m := range plan {
+		reverted[m.Date()] = struct{}{}
+	}
+
+	for _, m := range migrations {
+		if _, applied := appliedDates[m.Date()]; !applied {
+			continue
+		}
+		if _, ok := reverted[m.Date()]; ok {
+			continue
+		}
+		for _, dependency := range migrationDependencies(m, aliases) {
+			if _, ok := reverted[dependency]; ok {
+				return fmt.Errorf("cannot revert migration %d while migration %d_%s depends on it: %w",
+					dependency, m.Date(), m.Name(), ErrUnmetDependency)
+			}
+		}
+	}
+	return nil
+}
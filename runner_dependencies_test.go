@@ -0,0 +1,95 @@
+package amigo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testDependent struct {
+	SQLMigration
+	date      int64
+	dependsOn []int64
+}
+
+func (m testDependent) Date() int64        { return m.date }
+func (m testDependent) DependsOn() []int64 { return m.dependsOn }
+
+func Test_sortByDependencies(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations []Migration
+		applied    []int64
+		want       []int64
+		wantErr    error
+		wantCycle  bool
+	}{
+		{
+			name: "oldest first without dependencies",
+			migrations: []Migration{
+				testDependent{date: 3}, testDependent{date: 1}, testDependent{date: 2},
+			},
+			want: []int64{1, 2, 3},
+		},
+		{
+			name: "dependency on a newer migration runs it first",
+			migrations: []Migration{
+				testDependent{date: 1, dependsOn: []int64{3}}, testDependent{date: 2}, testDependent{date: 3},
+			},
+			want: []int64{2, 3, 1},
+		},
+		{
+			name: "dependency on an applied unknown migration is met",
+			migrations: []Migration{
+				testDependent{date: 2, dependsOn: []int64{1}},
+			},
+			applied: []int64{1},
+			want:    []int64{2},
+		},
+		{
+			name: "dependency on an unknown migration",
+			migrations: []Migration{
+				testDependent{date: 2, dependsOn: []int64{1}},
+			},
+			wantErr: ErrUnmetDependency,
+		},
+		{
+			name: "cycle",
+			migrations: []Migration{
+				testDependent{date: 1, dependsOn: []int64{2}}, testDependent{date: 2, dependsOn: []int64{1}},
+			},
+			wantCycle: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied := make(map[int64]struct{})
+			for _, date := range tt.applied {
+				applied[date] = struct{}{}
+			}
+
+			sorted, err := sortByDependencies(tt.migrations, applied)
+			if tt.wantErr != nil || tt.wantCycle {
+				if err == nil {
+					t.Fatalf("expected an error, got order %v", sorted)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []int64
+			for _, m := range sorted {
+				got = append(got, m.Date())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
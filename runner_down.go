@@ -5,7 +5,8 @@ import (
 )
 
 type runnerDownOpts struct {
-	Steps int
+	Steps             int
+	StatementProgress bool
 }
 
 type RunnerDownOptsFunc func(*runnerDownOpts)
@@ -16,6 +17,14 @@ func RunnerDownOptionSteps(steps int) RunnerDownOptsFunc {
 	}
 }
 
+// RunnerDownOptionStatementProgress makes DownIterator yield, while a SQL migration is reverted, a result with
+// Progress set before each of its statements, see StatementProgress
+func RunnerDownOptionStatementProgress() RunnerDownOptsFunc {
+	return func(opts *runnerDownOpts) {
+		opts.StatementProgress = true
+	}
+}
+
 func defaultRunnerDownOpts() runnerDownOpts {
 	return runnerDownOpts{
 		Steps: -1,
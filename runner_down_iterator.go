@@ -2,96 +2,148 @@ package amigo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"iter"
+	"slices"
 	"time"
 )
 
-// DownIterator returns an iterator that yields migration results as they are reverted
+// DownIterator returns an iterator that yields migration results as they are reverted, in the order of PlanDown
 func (r *Runner) DownIterator(ctx context.Context, migrations []Migration, opts ...RunnerDownOptsFunc) iter.Seq[MigrationResult] {
 	return func(yield func(MigrationResult) bool) {
+		toRevert, err := r.PlanDown(ctx, migrations, opts...)
+		if err != nil {
+			yield(MigrationResult{Error: err})
+			return
+		}
+
+		// Refuse crossing an irreversible migration before reverting anything
+		for _, migration := range toRevert {
+			if isIrreversible(migration) {
+				yield(MigrationResult{
+					Migration: migration,
+					Error:     fmt.Errorf("cannot revert migration %s: %w", migration.Name(), ErrIrreversible),
+				})
+				return
+			}
+		}
+
 		options := defaultRunnerDownOpts()
 		for _, opt := range opts {
 			opt(&options)
 		}
 
-		if options.Steps == 0 {
-			return
-		}
+		history := r.newHistoryRecorder()
 
-		var tableErr error
-		r.ensureTableCreatedOnce.Do(func() {
-			tableErr = r.config.Driver.CreateSchemaMigrationsTableIfNotExists(ctx, r.config.DB)
-		})
-		if tableErr != nil {
-			yield(MigrationResult{
-				Error: fmt.Errorf("failed to create schema migrations table: %w", tableErr),
-			})
-			return
-		}
+		// The schema file reflects the migrations reverted, even when one fails or the consumer stops
+		var schemaFile schemaFileTracker
+		yield = schemaFile.track(yield)
+		defer r.updateSchemaFile(ctx, migrations, &schemaFile, yield)
 
-		appliedMigrations, err := r.config.Driver.GetAppliedMigrations(ctx, r.config.DB)
-		if err != nil {
-			yield(MigrationResult{
-				Error: fmt.Errorf("failed to get applied migrations: %w", err),
-			})
-			return
+		for _, migration := range toRevert {
+			migrationCtx, migrationYield := withStatementProgress(ctx, migration, options.StatementProgress, yield)
+			if !r.revertMigration(migrationCtx, migration, history, migrationYield) {
+				return
+			}
 		}
+	}
+}
 
-		migrationsByDate := make(map[int64]Migration)
-		for _, m := range migrations {
-			migrationsByDate[m.Date()] = m
-		}
+// PlanDown returns the migrations DownIterator would revert, in order: the applied migrations newest first,
+// except that a migration is always reverted before the migrations it depends on (see Dependent).
+// It fails when a migration to revert is a dependency of an applied migration that stays applied.
+func (r *Runner) PlanDown(ctx context.Context, migrations []Migration, opts ...RunnerDownOptsFunc) (plan []Migration, err error) {
+	options := defaultRunnerDownOpts()
+	for _, opt := range opts {
+		opt(&options)
+	}
 
-		r.sortNewestFirstMigrationRecord(appliedMigrations)
-		for _, am := range appliedMigrations {
-			migration, exists := migrationsByDate[am.Date]
-			if !exists {
-				continue
-			}
+	if options.Steps == 0 {
+		return nil, nil
+	}
+
+	r.ensureTableCreatedOnce.Do(func() {
+		err = r.config.Driver.CreateSchemaMigrationsTableIfNotExists(ctx, r.config.DB)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
 
-			start := time.Now()
+	appliedMigrations, err := r.getAppliedMigrations(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+	appliedDates := appliedDateSet(appliedMigrations)
 
-			err := migration.Down(ctx, r.config.DB)
-			duration := time.Since(start)
+	// Repeatable migrations are never reverted
+	versioned, _ := splitRepeatableMigrations(migrations)
+	sorted, err := sortByDependencies(versioned, appliedDates)
+	if err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				if !yield(MigrationResult{
-					Migration: migration,
-					Error:     fmt.Errorf("failed to revert migration %s: %w", migration.Name(), err),
-					Duration:  duration,
-				}) {
-					return
-				}
-				return
-			}
+	for _, m := range slices.Backward(sorted) {
+		if _, applied := appliedDates[m.Date()]; !applied {
+			continue
+		}
+		plan = append(plan, m)
+		if options.Steps > 0 && len(plan) == options.Steps {
+			break
+		}
+	}
 
-			err = r.config.Driver.DeleteMigrations(ctx, r.config.DB, []int64{am.Date})
-			if err != nil {
-				if !yield(MigrationResult{
-					Migration: migration,
-					Error:     fmt.Errorf("failed to delete migration record %s: %w", migration.Name(), err),
-					Duration:  duration,
-				}) {
-					return
-				}
-				return
-			}
+	if err := checkNoAppliedDependents(plan, versioned, appliedDates); err != nil {
+		return nil, err
+	}
 
-			if !yield(MigrationResult{
-				Migration: migration,
-				Error:     nil,
-				Duration:  duration,
-			}) {
-				return
-			}
+	return plan, nil
+}
 
-			if options.Steps > 0 {
-				options.Steps--
-				if options.Steps == 0 {
-					return
-				}
-			}
+// revertMigration runs a migration down, deletes its record and yields the result.
+// It returns false when the iteration must stop, after a failure or when the consumer stopped.
+func (r *Runner) revertMigration(ctx context.Context, migration Migration, history *historyRecorder, yield func(MigrationResult) bool) bool {
+	start := time.Now()
+
+	// The statements of this migration only, for the verifier
+	migrationCtx := contextWithExecutedStatements(ContextWithDriver(ctx, r.config.Driver))
+	err := migration.Down(migrationCtx, r.config.DB)
+	if err == nil {
+		err = r.verifyMigration(migrationCtx, start)
+	}
+	duration := time.Since(start)
+
+	if err != nil {
+		resultErr := fmt.Errorf("failed to revert migration %s: %w", migration.Name(), err)
+		if historyErr := history.record(ctx, migration, MigrationDirectionDown, start, duration, err); historyErr != nil {
+			resultErr = errors.Join(resultErr, historyErr)
 		}
+
+		yield(MigrationResult{
+			Migration: migration,
+			Error:     resultErr,
+			Duration:  duration,
+		})
+		return false
+	}
+
+	// The migration is reverted: forget it even if the context has been canceled in the meantime
+	err = r.config.Driver.DeleteMigrations(context.WithoutCancel(ctx), r.config.DB, []int64{migration.Date()})
+	if err == nil {
+		err = history.record(ctx, migration, MigrationDirectionDown, start, duration, nil)
 	}
+	if err != nil {
+		yield(MigrationResult{
+			Migration: migration,
+			Error:     fmt.Errorf("failed to delete migration record %s: %w", migration.Name(), err),
+			Duration:  duration,
+		})
+		return false
+	}
+
+	return yield(MigrationResult{
+		Migration: migration,
+		Error:     nil,
+		Duration:  duration,
+	})
 }
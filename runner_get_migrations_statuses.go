@@ -6,7 +6,12 @@ import (
 	"slices"
 )
 
-func (r *Runner) GetMigrationsStatuses(ctx context.Context, migrations []Migration) (all []MigrationStatus, err error) {
+// GetMigrationsStatuses returns the status of the versioned migrations, oldest first.
+// Repeatable migrations are reported by GetRepeatableStatuses. Pending migrations not selected by the
+// environment and tags options are marked as skipped, see RunnerUpOptionEnvironment and RunnerUpOptionTags.
+func (r *Runner) GetMigrationsStatuses(ctx context.Context, migrations []Migration, opts ...RunnerUpOptsFunc) (all []MigrationStatus, err error) {
+	options := r.newRunnerUpOpts(opts)
+
 	r.ensureTableCreatedOnce.Do(func() {
 		err = r.config.Driver.CreateSchemaMigrationsTableIfNotExists(ctx, r.config.DB)
 	})
@@ -14,9 +19,9 @@ func (r *Runner) GetMigrationsStatuses(ctx context.Context, migrations []Migrati
 		return nil, fmt.Errorf("failed to create schema migrations table: %w", err)
 	}
 
-	appliedMigrations, err := r.config.Driver.GetAppliedMigrations(ctx, r.config.DB)
+	appliedMigrations, err := r.getAppliedMigrations(ctx, migrations)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+		return nil, err
 	}
 
 	appliedMap := make(map[int64]MigrationRecord)
@@ -24,7 +29,8 @@ func (r *Runner) GetMigrationsStatuses(ctx context.Context, migrations []Migrati
 		appliedMap[am.Date] = am
 	}
 
-	for _, m := range migrations {
+	versioned, _ := splitRepeatableMigrations(migrations)
+	for _, m := range versioned {
 		status := MigrationStatus{
 			Migration: MigrationRecord{
 				Date: m.Date(),
@@ -34,7 +40,10 @@ func (r *Runner) GetMigrationsStatuses(ctx context.Context, migrations []Migrati
 
 		if applied, exists := appliedMap[m.Date()]; exists {
 			status.Applied = true
-			status.Migration.AppliedAt = applied.AppliedAt
+			status.Migration = applied
+			status.Migration.Name = m.Name()
+		} else if !options.selects(m) {
+			status.Skipped = true
 		}
 
 		all = append(all, status)
@@ -53,18 +62,24 @@ func (r *Runner) GetMigrationsStatuses(ctx context.Context, migrations []Migrati
 	return all, nil
 }
 
-// HasPendingMigrations checks if there are any pending migrations to apply
-func (r *Runner) HasPendingMigrations(ctx context.Context, migrations []Migration) (bool, error) {
-	statuses, err := r.GetMigrationsStatuses(ctx, migrations)
+// HasPendingMigrations checks if there are any pending migrations to apply, including repeatable migrations
+// that never ran or changed. Skipped migrations are not pending, see GetMigrationsStatuses.
+func (r *Runner) HasPendingMigrations(ctx context.Context, migrations []Migration, opts ...RunnerUpOptsFunc) (bool, error) {
+	statuses, err := r.GetMigrationsStatuses(ctx, migrations, opts...)
 	if err != nil {
 		return false, err
 	}
 
 	for _, status := range statuses {
-		if !status.Applied {
+		if !status.Applied && !status.Skipped {
 			return true, nil
 		}
 	}
 
-	return false, nil
+	repeatables, err := r.pendingRepeatables(ctx, migrations, opts...)
+	if err != nil {
+		return false, err
+	}
+
+	return len(repeatables) > 0, nil
 }
@@ -0,0 +1,93 @@
+package amigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// historyRecorder appends the outcome of each migration of a run to the history, when the driver keeps one
+type historyRecorder struct {
+	runner *Runner
+	driver HistoryDriver
+	batch  int64
+}
+
+// newHistoryRecorder returns a recorder for a new run. Recording is a no-op when the driver has no history.
+func (r *Runner) newHistoryRecorder() *historyRecorder {
+	driver, _ := r.config.Driver.(HistoryDriver)
+	return &historyRecorder{runner: r, driver: driver}
+}
+
+// record appends the outcome of a migration. The batch number is allocated on the first entry of the run,
+// so runs with nothing to do do not consume one.
+func (h *historyRecorder) record(ctx context.Context, m Migration, direction MigrationDirection, startedAt time.Time, duration time.Duration, migrationErr error) error {
+	if h.driver == nil {
+		return nil
+	}
+
+	// History is bookkeeping: write it even if the context has been canceled during the migration
+	ctx = context.WithoutCancel(ctx)
+
+	if h.batch == 0 {
+		batch, err := h.driver.NextHistoryBatch(ctx, h.runner.config.DB)
+		if err != nil {
+			return fmt.Errorf("failed to allocate history batch: %w", err)
+		}
+		h.batch = batch
+	}
+
+	record := h.runner.newMigrationRecord(m, duration)
+	entry := HistoryRecord{
+		Batch:        h.batch,
+		Date:         record.Date,
+		Name:         record.Name,
+		Direction:    direction,
+		Status:       HistoryStatusSuccess,
+		StartedAt:    startedAt,
+		Duration:     duration,
+		Environment:  record.Environment,
+		OSUser:       record.OSUser,
+		Hostname:     record.Hostname,
+		AmigoVersion: record.AmigoVersion,
+		AppVersion:   record.AppVersion,
+		Kind:         record.Kind,
+	}
+	if migrationErr != nil {
+		entry.Status = HistoryStatusFailure
+		entry.Error = migrationErr.Error()
+	}
+
+	if err := h.driver.InsertHistory(ctx, h.runner.config.DB, []HistoryRecord{entry}); err != nil {
+		return fmt.Errorf("failed to record history of migration %s: %w", m.Name(), err)
+	}
+
+	return nil
+}
+
+// ErrHistoryNotSupported is returned by GetHistory when the driver does not implement HistoryDriver
+var ErrHistoryNotSupported = errors.New("driver does not keep a migration history")
+
+// GetHistory returns the history of migration runs matching the filter, most recent first
+func (r *Runner) GetHistory(ctx context.Context, filter HistoryFilter) ([]HistoryRecord, error) {
+	driver, ok := r.config.Driver.(HistoryDriver)
+	if !ok {
+		return nil, ErrHistoryNotSupported
+	}
+
+	var err error
+	r.ensureTableCreatedOnce.Do(func() {
+		err = r.config.Driver.CreateSchemaMigrationsTableIfNotExists(ctx, r.config.DB)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
+
+	history, err := driver.GetHistory(ctx, r.config.DB, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration history: %w", err)
+	}
+
+	return history, nil
+}
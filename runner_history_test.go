@@ -0,0 +1,147 @@
+package amigo_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alexisvisco/amigo"
+	"github.com/alexisvisco/amigo/pkg/amigotest"
+)
+
+func TestRunner_GetHistory(t *testing.T) {
+	failure := errors.New("boom")
+	first := amigotest.NewMigration(1, "first", amigotest.Noop, amigotest.Noop)
+	second := amigotest.NewMigration(2, "second", amigotest.Noop, amigotest.Noop)
+	broken := amigotest.NewMigration(3, "broken", amigotest.Fail(failure), amigotest.Noop)
+
+	driver := amigotest.NewDriver()
+	runner := amigo.NewRunner(amigo.Configuration{Driver: driver, Environment: "staging"})
+
+	if err := runner.Up(t.Context(), []amigo.Migration{first, second, broken}); !errors.Is(err, failure) {
+		t.Fatalf("Up() error = %v, want %v", err, failure)
+	}
+	if err := runner.Down(t.Context(), []amigo.Migration{first, second}, amigo.RunnerDownOptionSteps(1)); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	// A run with nothing to do records nothing and does not consume a batch
+	if err := runner.Up(t.Context(), []amigo.Migration{first}); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	history, err := runner.GetHistory(t.Context(), amigo.HistoryFilter{})
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+
+	// Most recent first
+	want := []struct {
+		batch     int64
+		date      int64
+		direction amigo.MigrationDirection
+		status    amigo.HistoryStatus
+	}{
+		{batch: 2, date: 2, direction: amigo.MigrationDirectionDown, status: amigo.HistoryStatusSuccess},
+		{batch: 1, date: 3, direction: amigo.MigrationDirectionUp, status: amigo.HistoryStatusFailure},
+		{batch: 1, date: 2, direction: amigo.MigrationDirectionUp, status: amigo.HistoryStatusSuccess},
+		{batch: 1, date: 1, direction: amigo.MigrationDirectionUp, status: amigo.HistoryStatusSuccess},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("GetHistory() returned %d entries, want %d: %+v", len(history), len(want), history)
+	}
+	for i, w := range want {
+		h := history[i]
+		if h.Batch != w.batch || h.Date != w.date || h.Direction != w.direction || h.Status != w.status {
+			t.Errorf("entry %d = batch %d, date %d, %s, %s, want batch %d, date %d, %s, %s",
+				i, h.Batch, h.Date, h.Direction, h.Status, w.batch, w.date, w.direction, w.status)
+		}
+		if h.Environment != "staging" {
+			t.Errorf("entry %d environment = %q, want staging", i, h.Environment)
+		}
+	}
+	if !strings.Contains(history[1].Error, failure.Error()) {
+		t.Errorf("error of the failed entry = %q, want it to contain %q", history[1].Error, failure.Error())
+	}
+	if history[0].Error != "" {
+		t.Errorf("error of a successful entry = %q, want none", history[0].Error)
+	}
+
+	t.Run("filters", func(t *testing.T) {
+		history, err := runner.GetHistory(t.Context(), amigo.HistoryFilter{Date: 2, Limit: 1})
+		if err != nil {
+			t.Fatalf("GetHistory() error = %v", err)
+		}
+		if len(history) != 1 || history[0].Date != 2 || history[0].Direction != amigo.MigrationDirectionDown {
+			t.Errorf("GetHistory() = %+v, want the revert of migration 2", history)
+		}
+	})
+
+	t.Run("driver without history", func(t *testing.T) {
+		runner := amigo.NewRunner(amigo.Configuration{Driver: struct{ amigo.Driver }{amigotest.NewDriver()}})
+		if _, err := runner.GetHistory(t.Context(), amigo.HistoryFilter{}); !errors.Is(err, amigo.ErrHistoryNotSupported) {
+			t.Errorf("GetHistory() error = %v, want ErrHistoryNotSupported", err)
+		}
+	})
+}
+
+func TestCLI_history(t *testing.T) {
+	migrations := []amigo.Migration{
+		amigotest.NewMigration(20240101000000, "create_users", amigotest.Noop, amigotest.Noop),
+		amigotest.NewMigration(20240102000000, "create_posts", amigotest.Noop, amigotest.Noop),
+	}
+
+	driver := amigotest.NewDriver()
+	config := amigo.Configuration{Driver: driver}
+	if err := amigo.NewRunner(config).Up(t.Context(), migrations); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantRows  int
+		wantNames []string
+	}{
+		{name: "all", args: nil, wantRows: 2, wantNames: []string{"create_users", "create_posts"}},
+		{name: "limit", args: []string{"--limit=1"}, wantRows: 1, wantNames: []string{"create_posts"}},
+		{name: "version", args: []string{"--version=20240101000000"}, wantRows: 1, wantNames: []string{"create_users"}},
+		{name: "unknown version", args: []string{"--version=1"}, wantRows: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output, errorOutput bytes.Buffer
+			cli := amigo.NewCLI(amigo.CLIConfig{
+				Config:     config,
+				Migrations: migrations,
+				Output:     &output,
+				ErrorOut:   &errorOutput,
+			})
+
+			if code := cli.Run(append([]string{"--color=never", "history"}, tt.args...)); code != 0 {
+				t.Fatalf("Run() = %d, want 0, errors: %s", code, errorOutput.String())
+			}
+
+			out := output.String()
+			if tt.wantRows == 0 {
+				if !strings.Contains(out, "No migration history") {
+					t.Errorf("output = %q, want no history", out)
+				}
+				return
+			}
+
+			// A header line, then one line per entry
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines)-1 != tt.wantRows {
+				t.Errorf("output has %d entries, want %d:\n%s", len(lines)-1, tt.wantRows, out)
+			}
+			for _, m := range migrations {
+				if want := slices.Contains(tt.wantNames, m.Name()); strings.Contains(out, m.Name()) != want {
+					t.Errorf("output contains %s = %v, want %v:\n%s", m.Name(), !want, want, out)
+				}
+			}
+		})
+	}
+}
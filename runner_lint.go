@@ -0,0 +1,203 @@
+package amigo
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// LintSeverity is the severity of a lint rule
+type LintSeverity string
+
+const (
+	// LintSeverityError marks operations that fail or lock the database, the lint command exits non-zero on them
+	LintSeverityError LintSeverity = "error"
+	// LintSeverityWarning marks operations that are risky on large tables or hard to revert
+	LintSeverityWarning LintSeverity = "warning"
+)
+
+// lintIgnoreAnnotation suppresses lint rules: in a statement, for that statement, and anywhere in a migration for
+// the rules checking the whole migration
+//
+//	-- amigo:lint:ignore index-without-concurrently,add-column-default
+const lintIgnoreAnnotation = "-- amigo:lint:ignore"
+
+// LintSection is the up or down section of an SQL migration, as seen by lint rules
+type LintSection struct {
+	Direction MigrationDirection
+	// Transactional is set when the section is annotated tx=true, even when statements that cannot run in a
+	// transaction run outside it, see Configuration.NonTransactionalOutsideTx
+	Transactional bool
+	// Statements are the statements of the section, split like SplitStatements does, without comments and
+	// with whitespace collapsed
+	Statements []string
+}
+
+// LintMigration is an SQL migration, as seen by lint rules
+type LintMigration struct {
+	// Version is the file name of the migration without extension
+	Version    string
+	Repeatable bool
+	Up         LintSection
+	Down       LintSection
+	// MissingDown is set when the down section is empty and not annotated with "irreversible"
+	MissingDown bool
+}
+
+// LintRule checks SQL migrations for dangerous operations, see DefaultLintRules
+type LintRule struct {
+	// ID identifies the rule in findings and in -- amigo:lint:ignore comments
+	ID          string
+	Severity    LintSeverity
+	Description string
+	// Drivers restricts the rule to the drivers with these names, see Driver.Name. The rule applies to every
+	// driver when empty.
+	Drivers []string
+
+	// CheckStatement returns why a statement of a section breaks the rule, or an empty string
+	CheckStatement func(m LintMigration, section LintSection, statement string) string
+	// CheckMigration returns why a migration breaks the rule, or an empty string
+	CheckMigration func(m LintMigration) string
+}
+
+// LintFinding is a statement or a migration breaking a lint rule
+type LintFinding struct {
+	Rule      string       `json:"rule"`
+	Severity  LintSeverity `json:"severity"`
+	Migration string       `json:"migration"`
+	// Direction and Statement are empty for the rules checking the whole migration
+	Direction MigrationDirection `json:"direction,omitempty"`
+	Statement string             `json:"statement,omitempty"`
+	Message   string             `json:"message"`
+}
+
+type runnerLintOpts struct {
+	Rules []LintRule
+}
+
+type RunnerLintOptsFunc func(*runnerLintOpts)
+
+// RunnerLintOptionRules checks the migrations with these rules instead of DefaultLintRules
+func RunnerLintOptionRules(rules ...LintRule) RunnerLintOptsFunc {
+	return func(opts *runnerLintOpts) {
+		opts.Rules = rules
+	}
+}
+
+// Lint checks the up and down sections of the SQL migrations with the lint rules, in the order of migrations.
+// Go migrations are not checked. Rules suppressed with -- amigo:lint:ignore comments are not reported.
+func (r *Runner) Lint(migrations []Migration, opts ...RunnerLintOptsFunc) []LintFinding {
+	options := runnerLintOpts{Rules: DefaultLintRules()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var rules []LintRule
+	for _, rule := range options.Rules {
+		if len(rule.Drivers) == 0 || slices.Contains(rule.Drivers, r.config.Driver.Name()) {
+			rules = append(rules, rule)
+		}
+	}
+
+	var findings []LintFinding
+	for _, m := range migrations {
+		sqlMigration, ok := m.(SQLMigration)
+		if !ok {
+			continue
+		}
+		findings = append(findings, lintMigration(sqlMigration, rules)...)
+	}
+
+	return findings
+}
+
+// lintMigration returns the findings of the rules on a migration
+func lintMigration(m SQLMigration, rules []LintRule) []LintFinding {
+	up, upRaw := newLintSection(MigrationDirectionUp, m.txUp, m.up)
+	down, downRaw := newLintSection(MigrationDirectionDown, m.txDown, m.down)
+
+	lint := LintMigration{
+		Version:     fmt.Sprintf("%d_%s", m.date, m.name),
+		Repeatable:  m.repeatable,
+		Up:          up,
+		Down:        down,
+		MissingDown: strings.TrimSpace(m.down) == "" && !m.irreversibleAnnotation,
+	}
+	if m.repeatable {
+		lint.Version = repeatableFilePrefix + m.name
+	}
+
+	ignoredInMigration := lintIgnoredRules(m.up + "\n" + m.down)
+
+	var findings []LintFinding
+	for _, rule := range rules {
+		if rule.CheckMigration != nil && !slices.Contains(ignoredInMigration, rule.ID) {
+			if message := rule.CheckMigration(lint); message != "" {
+				findings = append(findings, LintFinding{Rule: rule.ID, Severity: rule.Severity, Migration: lint.Version, Message: message})
+			}
+		}
+
+		if rule.CheckStatement == nil {
+			continue
+		}
+		for _, section := range []struct {
+			section LintSection
+			raw     []string
+		}{{up, upRaw}, {down, downRaw}} {
+			for i, statement := range section.section.Statements {
+				if slices.Contains(lintIgnoredRules(section.raw[i]), rule.ID) {
+					continue
+				}
+				if message := rule.CheckStatement(lint, section.section, statement); message != "" {
+					findings = append(findings, LintFinding{
+						Rule:      rule.ID,
+						Severity:  rule.Severity,
+						Migration: lint.Version,
+						Direction: section.section.Direction,
+						Statement: statement,
+						Message:   message,
+					})
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+// newLintSection splits a section of a migration into statements, returning them as seen by lint rules and as
+// written, with their comments
+func newLintSection(direction MigrationDirection, transactional bool, sql string) (LintSection, []string) {
+	section := LintSection{Direction: direction, Transactional: transactional}
+
+	var raw []string
+	for _, statement := range splitSQLStatementsWithAnnotations(sql) {
+		normalized := normalizeStatement(statement)
+		if normalized == "" {
+			continue
+		}
+		section.Statements = append(section.Statements, normalized)
+		raw = append(raw, statement)
+	}
+
+	return section, raw
+}
+
+// normalizeStatement removes the comments of a statement and collapses its whitespace, to match it with regexps
+func normalizeStatement(statement string) string {
+	return strings.Join(strings.Fields(stripSQLComments(statement)), " ")
+}
+
+var lintIgnoreRegexp = regexp.MustCompile(regexp.QuoteMeta(lintIgnoreAnnotation) + `[ \t]+([^\n]+)`)
+
+// lintIgnoredRules returns the rule ids of the -- amigo:lint:ignore comments of sql
+func lintIgnoredRules(sql string) []string {
+	var ids []string
+	for _, match := range lintIgnoreRegexp.FindAllStringSubmatch(sql, -1) {
+		ids = append(ids, strings.FieldsFunc(match[1], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+	return ids
+}
@@ -0,0 +1,127 @@
+package amigo
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRunner_Lint(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "concurrent index inside a transaction",
+			sql:  "-- migrate:up\nCREATE INDEX CONCURRENTLY idx_users_email ON users(email);\n-- migrate:down\nDROP INDEX IF EXISTS idx_users_email;",
+			want: []string{"concurrent-index-in-transaction"},
+		},
+		{
+			name: "concurrent index outside a transaction",
+			sql:  "-- migrate:up tx=false\nCREATE INDEX CONCURRENTLY idx_users_email ON users(email);\n-- migrate:down\nDROP INDEX IF EXISTS idx_users_email;",
+			want: nil,
+		},
+		{
+			name: "index without concurrently, drop without if exists",
+			sql:  "-- migrate:up\nCREATE INDEX idx_users_email ON users(email);\n-- migrate:down\nDROP INDEX idx_users_email;",
+			want: []string{"index-without-concurrently", "drop-without-if-exists"},
+		},
+		{
+			name: "table created in the same section",
+			sql: "-- migrate:up\nCREATE TABLE users (id INT);\nCREATE INDEX idx_users_id ON users(id);\n" +
+				"ALTER TABLE users ADD COLUMN name TEXT NOT NULL DEFAULT '';\n-- migrate:down\nDROP TABLE IF EXISTS users;",
+			want: nil,
+		},
+		{
+			name: "missing down",
+			sql:  "-- migrate:up\nSELECT 1;\n-- migrate:down\n",
+			want: []string{"missing-down"},
+		},
+		{
+			name: "irreversible annotation",
+			sql:  "-- migrate:up\nSELECT 1;\n-- migrate:down irreversible\n",
+			want: nil,
+		},
+		{
+			name: "columns added with a default or not null",
+			sql: "-- migrate:up\nALTER TABLE users ADD COLUMN price NUMERIC(10,2) NOT NULL, ADD CONSTRAINT c CHECK (price IS NOT NULL);\n" +
+				"ALTER TABLE users ADD COLUMN active BOOLEAN DEFAULT true;\n-- migrate:down\nSELECT 1;",
+			want: []string{"add-column-default", "not-null-column"},
+		},
+		{
+			name: "table rewrites",
+			sql:  "-- migrate:up\nALTER TABLE users ALTER COLUMN id TYPE BIGINT;\nALTER TABLE users ALTER COLUMN name SET NOT NULL;\n-- migrate:down\nSELECT 1;",
+			want: []string{"not-null-column", "table-rewrite"},
+		},
+		{
+			name: "comment markers in strings",
+			sql: "-- migrate:up\nALTER TABLE users ADD COLUMN sep TEXT DEFAULT '--', ADD COLUMN name TEXT NOT NULL; -- comment\n" +
+				"-- migrate:down\nSELECT 1;",
+			want: []string{"add-column-default", "not-null-column"},
+		},
+		{
+			name: "ignored rules",
+			sql: "-- migrate:up\n-- amigo:lint:ignore index-without-concurrently\nCREATE INDEX idx_users_email ON users(email);\n" +
+				"-- migrate:down\n-- amigo:lint:ignore drop-without-if-exists, missing-down\nDROP INDEX idx_users_email;",
+			want: nil,
+		},
+	}
+
+	config := DefaultConfiguration
+	config.Driver = NewPostgresDriver("")
+
+	runner := NewRunner(config)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migration, err := parseSQLFile([]byte(tt.sql), config)
+			if err != nil {
+				t.Fatalf("parseSQLFile() error = %v", err)
+			}
+
+			var got []string
+			for _, finding := range runner.Lint([]Migration{migration}) {
+				got = append(got, finding.Rule)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Lint() rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_normalizeStatement(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		want      string
+	}{
+		{
+			name:      "comments",
+			statement: "-- leading\nALTER TABLE users /* block -- */\n  ADD COLUMN name TEXT -- trailing\n",
+			want:      "ALTER TABLE users ADD COLUMN name TEXT",
+		},
+		{
+			name:      "default in a string",
+			statement: "ALTER TABLE users ADD COLUMN sep TEXT DEFAULT '--' NOT NULL",
+			want:      "ALTER TABLE users ADD COLUMN sep TEXT DEFAULT '--' NOT NULL",
+		},
+		{
+			name:      "comment with a string",
+			statement: "COMMENT ON COLUMN users.sep IS 'a -- b' -- why",
+			want:      "COMMENT ON COLUMN users.sep IS 'a -- b'",
+		},
+		{
+			name:      "quoted identifier and dollar-quoted body",
+			statement: "CREATE FUNCTION \"f--\"() RETURNS TEXT AS $$ SELECT '/*' -- x\n $$ LANGUAGE sql",
+			want:      "CREATE FUNCTION \"f--\"() RETURNS TEXT AS $$ SELECT '/*' -- x $$ LANGUAGE sql",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeStatement(tt.statement); got != tt.want {
+				t.Errorf("normalizeStatement() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package amigo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// isRepeatable reports whether a migration runs again whenever its checksum changes, see Repeatable
+func isRepeatable(m Migration) bool {
+	repeatable, ok := m.(Repeatable)
+	return ok && repeatable.Repeatable()
+}
+
+// migrationChecksum returns the checksum of a migration, or an empty string when it has none, see Checksummer
+func migrationChecksum(m Migration) string {
+	if checksummer, ok := m.(Checksummer); ok {
+		return checksummer.Checksum()
+	}
+	return ""
+}
+
+// splitRepeatableMigrations separates the versioned migrations from the repeatable ones, sorted by name
+func splitRepeatableMigrations(migrations []Migration) (versioned, repeatable []Migration) {
+	for _, m := range migrations {
+		if isRepeatable(m) {
+			repeatable = append(repeatable, m)
+		} else {
+			versioned = append(versioned, m)
+		}
+	}
+
+	slices.SortFunc(repeatable, func(a, b Migration) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return versioned, repeatable
+}
+
+// GetRepeatableStatuses returns the status of every repeatable migration, sorted by name. The environment and
+// tags options tell which ones are skipped, see RunnerUpOptionEnvironment and RunnerUpOptionTags.
+func (r *Runner) GetRepeatableStatuses(ctx context.Context, migrations []Migration, opts ...RunnerUpOptsFunc) (all []RepeatableStatus, err error) {
+	options := r.newRunnerUpOpts(opts)
+
+	_, repeatables := splitRepeatableMigrations(migrations)
+	if len(repeatables) == 0 {
+		return nil, nil
+	}
+
+	driver, ok := r.config.Driver.(RepeatableDriver)
+	if !ok {
+		return nil, fmt.Errorf("%s driver: %w", r.config.Driver.Name(), ErrRepeatableNotSupported)
+	}
+
+	r.ensureTableCreatedOnce.Do(func() {
+		err = r.config.Driver.CreateSchemaMigrationsTableIfNotExists(ctx, r.config.DB)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
+
+	records, err := driver.GetRepeatableMigrations(ctx, r.config.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repeatable migrations: %w", err)
+	}
+
+	recordsByName := make(map[string]MigrationRecord, len(records))
+	for _, record := range records {
+		recordsByName[record.Name] = record
+	}
+
+	for _, m := range repeatables {
+		checksum := migrationChecksum(m)
+		if checksum == "" {
+			return nil, fmt.Errorf("repeatable migration %s has no checksum, it must implement Checksummer", m.Name())
+		}
+
+		status := RepeatableStatus{
+			Migration: MigrationRecord{Name: m.Name(), Kind: migrationKind(m), Checksum: checksum},
+			Checksum:  checksum,
+			State:     RepeatableStatePending,
+		}
+
+		if record, exists := recordsByName[m.Name()]; exists {
+			status.Migration = record
+			status.State = RepeatableStateUpToDate
+			if record.Checksum != checksum {
+				status.State = RepeatableStateChanged
+			}
+		}
+		if status.State != RepeatableStateUpToDate && !options.selects(m) {
+			status.State = RepeatableStateSkipped
+		}
+
+		all = append(all, status)
+	}
+
+	return all, nil
+}
+
+// pendingRepeatables returns the repeatable migrations that never ran or whose checksum changed, sorted by name
+func (r *Runner) pendingRepeatables(ctx context.Context, migrations []Migration, opts ...RunnerUpOptsFunc) ([]Migration, error) {
+	statuses, err := r.GetRepeatableStatuses(ctx, migrations, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	_, repeatables := splitRepeatableMigrations(migrations)
+	byName := make(map[string]Migration, len(repeatables))
+	for _, m := range repeatables {
+		byName[m.Name()] = m
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if status.State == RepeatableStatePending || status.State == RepeatableStateChanged {
+			pending = append(pending, byName[status.Migration.Name])
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,177 @@
+package amigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// ErrRoundtripMismatch is returned when the down of a migration leaves the schema different from before its up.
+// See Runner.RoundtripIterator.
+var ErrRoundtripMismatch = errors.New("down does not revert up")
+
+// RoundtripResult is the result of the roundtrip of a migration, see Runner.RoundtripIterator
+type RoundtripResult struct {
+	Migration Migration
+	// Reverted is set when the migration was applied, reverted and applied again. Irreversible and repeatable
+	// migrations are only applied.
+	Reverted bool
+	// Diff is the difference between the schema before the up and after the down, lines prefixed with "-" only
+	// exist before and lines prefixed with "+" only after. It is set with ErrRoundtripMismatch.
+	Diff  string
+	Error error
+}
+
+// RoundtripIterator applies the pending migrations one by one, in the order of PlanUp, checking that the down
+// of each one reverts its up: it dumps the schema, applies the migration, reverts it, compares the schema with
+// the dump and applies the migration again. It stops at the first failure, or at the first migration whose down
+// leaves the schema different with ErrRoundtripMismatch and the Diff. The driver must implement SchemaDumper.
+// Meant for disposable databases: the migrations stay applied.
+func (r *Runner) RoundtripIterator(ctx context.Context, migrations []Migration, opts ...RunnerUpOptsFunc) iter.Seq[RoundtripResult] {
+	return func(yield func(RoundtripResult) bool) {
+		if _, ok := r.config.Driver.(SchemaDumper); !ok {
+			yield(RoundtripResult{Error: fmt.Errorf("%s driver: %w", r.config.Driver.Name(), ErrSchemaDumpNotSupported)})
+			return
+		}
+
+		plan, err := r.PlanUp(ctx, migrations, opts...)
+		if err != nil {
+			yield(RoundtripResult{Error: err})
+			return
+		}
+
+		history := r.newHistoryRecorder()
+		for _, m := range plan {
+			result := r.roundtripMigration(ctx, m, history)
+			if !yield(result) || result.Error != nil {
+				return
+			}
+		}
+	}
+}
+
+// roundtripMigration applies, reverts and applies again a migration, comparing the schema before the up with
+// the schema after the down
+func (r *Runner) roundtripMigration(ctx context.Context, m Migration, history *historyRecorder) RoundtripResult {
+	result := RoundtripResult{Migration: m}
+
+	var stepErr error
+	capture := func(res MigrationResult) bool {
+		stepErr = res.Error
+		return true
+	}
+	up := func() error {
+		r.applyMigration(ctx, m, history, capture, r.saveAppliedMigration(ctx, m))
+		return stepErr
+	}
+
+	if isIrreversible(m) || isRepeatable(m) {
+		result.Error = up()
+		return result
+	}
+
+	before, err := r.dumpSchemaDDL(ctx)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	if result.Error = up(); result.Error != nil {
+		return result
+	}
+
+	r.revertMigration(ctx, m, history, capture)
+	if result.Error = stepErr; result.Error != nil {
+		return result
+	}
+
+	after, err := r.dumpSchemaDDL(ctx)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+
+	if before != after {
+		result.Diff = diffLines(before, after)
+		result.Error = fmt.Errorf("migration %s: %w", m.Name(), ErrRoundtripMismatch)
+		return result
+	}
+
+	result.Reverted = true
+	result.Error = up()
+	return result
+}
+
+// diffLines returns the lines that differ between a and b, prefixed with "-" for those only in a and "+" for
+// those only in b, with two lines of context around each change
+func diffLines(a, b string) string {
+	const context = 2
+
+	before := strings.Split(a, "\n")
+	after := strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of before[i:] and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		prefix string
+		text   string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			lines = append(lines, line{" ", before[i]})
+			i++
+			j++
+		case j < len(after) && (i == len(before) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, line{"+", after[j]})
+			j++
+		default:
+			lines = append(lines, line{"-", before[i]})
+			i++
+		}
+	}
+
+	// Keep the changed lines and their context, eliding the rest
+	keep := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.prefix == " " {
+			continue
+		}
+		for c := max(0, k-context); c <= min(len(lines)-1, k+context); c++ {
+			keep[c] = true
+		}
+	}
+
+	var out strings.Builder
+	elided := false
+	for k, l := range lines {
+		if !keep[k] {
+			elided = true
+			continue
+		}
+		if elided && out.Len() > 0 {
+			out.WriteString("  ...\n")
+		}
+		elided = false
+		fmt.Fprintf(&out, "%s %s\n", l.prefix, l.text)
+	}
+
+	return out.String()
+}
@@ -0,0 +1,39 @@
+package amigo
+
+import "testing"
+
+func Test_diffLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{
+			name:   "line left by the down",
+			before: "a;\n\nb;\n",
+			after:  "a;\n\nb;\n\nc;\n",
+			want:   "  b;\n  \n+ c;\n+ \n",
+		},
+		{
+			name:   "line removed by the down, far from others",
+			before: "a\nb\nc\nd\ne\nf\ng",
+			after:  "a\nc\nd\ne\nf\ng",
+			want:   "  a\n- b\n  c\n  d\n",
+		},
+		{
+			name:   "changes far apart are elided in between",
+			before: "a\nb\nc\nd\ne\nf\ng\nh",
+			after:  "x\nb\nc\nd\ne\nf\ng\ny",
+			want:   "+ x\n- a\n  b\n  c\n  ...\n  f\n  g\n+ y\n- h\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.before, tt.after); got != tt.want {
+				t.Errorf("diffLines() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
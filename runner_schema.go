@@ -0,0 +1,233 @@
+package amigo
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// schemaAppliedAnnotation starts the lines of a schema dump listing the migrations applied to the database
+const schemaAppliedAnnotation = "-- amigo:applied"
+
+// DumpSchema returns the schema of the database followed by the migrations it reflects, one
+// "-- amigo:applied <version>_<name>" line per applied migration, oldest first. The driver must implement
+// SchemaDumper. See Configuration.SchemaFile.
+func (r *Runner) DumpSchema(ctx context.Context, migrations []Migration) (string, error) {
+	ddl, err := r.dumpSchemaDDL(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	appliedMigrations, err := r.getAppliedMigrationsReadOnly(ctx, migrations)
+	if err != nil {
+		return "", err
+	}
+	slices.SortFunc(appliedMigrations, func(a, b MigrationRecord) int {
+		return cmp.Compare(a.Date, b.Date)
+	})
+
+	var b strings.Builder
+	b.WriteString("-- Schema dumped by amigo, regenerate it with the schema dump command\n\n")
+	if ddl != "" {
+		b.WriteString(ddl)
+		b.WriteString("\n")
+	}
+	for _, record := range appliedMigrations {
+		fmt.Fprintf(&b, "%s %d_%s\n", schemaAppliedAnnotation, record.Date, record.Name)
+	}
+
+	return b.String(), nil
+}
+
+// dumpSchemaDDL returns the DDL statements of the database schema, see SchemaDumper
+func (r *Runner) dumpSchemaDDL(ctx context.Context) (string, error) {
+	dumper, ok := r.config.Driver.(SchemaDumper)
+	if !ok {
+		return "", fmt.Errorf("%s driver: %w", r.config.Driver.Name(), ErrSchemaDumpNotSupported)
+	}
+
+	ddl, err := dumper.DumpSchema(ctx, r.config.DB)
+	if err != nil {
+		return "", fmt.Errorf("failed to dump schema: %w", err)
+	}
+
+	return ddl, nil
+}
+
+// writeSchemaFile rewrites Configuration.SchemaFile with the schema of the database, when one is configured
+func (r *Runner) writeSchemaFile(ctx context.Context, migrations []Migration) error {
+	if r.config.SchemaFile == "" {
+		return nil
+	}
+
+	schema, err := r.DumpSchema(ctx, migrations)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(r.config.SchemaFile, []byte(schema), 0644); err != nil {
+		return fmt.Errorf("failed to write schema file: %w", err)
+	}
+
+	return nil
+}
+
+// schemaFileTracker follows the results yielded by UpIterator and DownIterator, to rewrite the schema file once
+// they stop when a migration changed state
+type schemaFileTracker struct {
+	changed int
+	stopped bool
+}
+
+// track returns yield, counting the migrations applied or reverted and whether the consumer stopped
+func (t *schemaFileTracker) track(yield func(MigrationResult) bool) func(MigrationResult) bool {
+	return func(result MigrationResult) bool {
+		if result.Error == nil && result.Progress == nil {
+			t.changed++
+		}
+		t.stopped = !yield(result)
+		return !t.stopped
+	}
+}
+
+// updateSchemaFile rewrites the schema file when a migration changed state, even when the command was
+// interrupted. A failure is yielded unless the consumer stopped.
+func (r *Runner) updateSchemaFile(ctx context.Context, migrations []Migration, t *schemaFileTracker, yield func(MigrationResult) bool) {
+	if t.changed == 0 {
+		return
+	}
+
+	if err := r.writeSchemaFile(context.WithoutCancel(ctx), migrations); err != nil && !t.stopped {
+		yield(MigrationResult{Error: err})
+	}
+}
+
+type runnerLoadSchemaOpts struct {
+	Force bool
+}
+
+type RunnerLoadSchemaOptsFunc func(*runnerLoadSchemaOpts)
+
+// RunnerLoadSchemaOptionForce loads the schema even when the database already has tables or applied migrations
+func RunnerLoadSchemaOptionForce() RunnerLoadSchemaOptsFunc {
+	return func(opts *runnerLoadSchemaOpts) {
+		opts.Force = true
+	}
+}
+
+// LoadSchema bootstraps an empty database from a schema written by DumpSchema: it runs the schema and records
+// the migrations of its amigo:applied lines in one transaction, so that only newer migrations are left to
+// apply. It returns the recorded migrations, and ErrDatabaseNotEmpty when the database has tables or applied
+// migrations, unless forced. The driver must implement SchemaLoader.
+func (r *Runner) LoadSchema(ctx context.Context, schema string, migrations []Migration, opts ...RunnerLoadSchemaOptsFunc) (records []MigrationRecord, err error) {
+	var options runnerLoadSchemaOpts
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	loader, ok := r.config.Driver.(SchemaLoader)
+	if !ok {
+		return nil, fmt.Errorf("%s driver: %w", r.config.Driver.Name(), ErrSchemaLoadNotSupported)
+	}
+
+	applied, err := parseSchemaAppliedMigrations(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	r.ensureTableCreatedOnce.Do(func() {
+		err = r.config.Driver.CreateSchemaMigrationsTableIfNotExists(ctx, r.config.DB)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
+
+	appliedMigrations, err := r.config.Driver.GetAppliedMigrations(ctx, r.config.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	alreadyApplied := appliedDateSet(appliedMigrations)
+
+	if !options.Force {
+		if len(appliedMigrations) > 0 {
+			return nil, fmt.Errorf("%d migration(s) applied: %w", len(appliedMigrations), ErrDatabaseNotEmpty)
+		}
+		if err := r.checkSchemaEmpty(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	byDate := make(map[int64]Migration, len(migrations))
+	for _, m := range migrations {
+		byDate[m.Date()] = m
+	}
+
+	for _, a := range applied {
+		// A forced load keeps the records of the migrations already applied
+		if _, ok := alreadyApplied[a.Date]; ok {
+			continue
+		}
+
+		record := MigrationRecord{Date: a.Date, Name: a.Name}
+		if m, ok := byDate[a.Date]; ok {
+			record = r.newMigrationRecord(m, 0)
+		}
+		records = append(records, record)
+	}
+
+	err = Tx(ctx, r.config.DB, func(tx *sql.Tx) error {
+		exec := SQLMigration{splitStatements: r.config.SplitStatements}
+		if err := exec.execSQL(ctx, tx, schema, 1); err != nil {
+			return fmt.Errorf("failed to run schema: %w", err)
+		}
+		if err := loader.InsertMigrationsTx(ctx, tx, records); err != nil {
+			return fmt.Errorf("failed to record migrations: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// checkSchemaEmpty returns ErrDatabaseNotEmpty when the database has tables besides amigo's. Without a
+// SchemaDumper to tell, the schema is considered empty.
+func (r *Runner) checkSchemaEmpty(ctx context.Context) error {
+	if _, ok := r.config.Driver.(SchemaDumper); !ok {
+		return nil
+	}
+
+	ddl, err := r.dumpSchemaDDL(ctx)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(ddl) != "" {
+		return fmt.Errorf("the schema is not empty: %w", ErrDatabaseNotEmpty)
+	}
+
+	return nil
+}
+
+// parseSchemaAppliedMigrations returns the migrations of the amigo:applied lines of a schema dump, see DumpSchema
+func parseSchemaAppliedMigrations(schema string) ([]MigrationRecord, error) {
+	var records []MigrationRecord
+	for _, line := range strings.Split(schema, "\n") {
+		if !strings.HasPrefix(line, schemaAppliedAnnotation+" ") {
+			continue
+		}
+
+		version := strings.TrimSpace(strings.TrimPrefix(line, schemaAppliedAnnotation))
+		name, date, err := parseFileName(version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s line %q: %w", schemaAppliedAnnotation, line, err)
+		}
+		records = append(records, MigrationRecord{Date: date, Name: name})
+	}
+	return records, nil
+}
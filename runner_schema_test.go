@@ -0,0 +1,175 @@
+package amigo_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexisvisco/amigo"
+	"github.com/alexisvisco/amigo/pkg/amigotest"
+)
+
+// execSQL returns a migration function running query
+func execSQL(query string) amigotest.MigrationFunc {
+	return func(ctx context.Context, db *sql.DB) error {
+		_, err := db.ExecContext(ctx, query)
+		return err
+	}
+}
+
+// sqliteConfig returns a configuration running on a new SQLite database
+func sqliteConfig(t *testing.T) amigo.Configuration {
+	t.Helper()
+
+	config := amigo.DefaultConfiguration
+	config.DB = openSQLite(t)
+	config.Driver = amigo.NewSQLiteDriver("")
+	return config
+}
+
+func TestRunner_DumpSchema_LoadSchema(t *testing.T) {
+	migrations := []amigo.Migration{
+		amigotest.NewMigration(1, "create_users", execSQL("CREATE TABLE users (id INTEGER PRIMARY KEY)"), execSQL("DROP TABLE users")),
+		amigotest.NewMigration(2, "create_posts", execSQL("CREATE TABLE posts (id INTEGER PRIMARY KEY)"), execSQL("DROP TABLE posts")),
+	}
+
+	source := sqliteConfig(t)
+	if err := amigo.NewRunner(source).Up(t.Context(), migrations); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	schema, err := amigo.NewRunner(source).DumpSchema(t.Context(), migrations)
+	if err != nil {
+		t.Fatalf("DumpSchema() error = %v", err)
+	}
+	for _, want := range []string{"CREATE TABLE users", "CREATE TABLE posts", "-- amigo:applied 1_create_users\n-- amigo:applied 2_create_posts\n"} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("DumpSchema() = %q, want it to contain %q", schema, want)
+		}
+	}
+	if strings.Contains(schema, "schema_migrations") {
+		t.Errorf("DumpSchema() = %q, want amigo's tables left out", schema)
+	}
+
+	target := sqliteConfig(t)
+	records, err := amigo.NewRunner(target).LoadSchema(t.Context(), schema, migrations)
+	if err != nil {
+		t.Fatalf("LoadSchema() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("LoadSchema() recorded %d migration(s), want 2", len(records))
+	}
+	amigotest.AssertApplied(t, target, migrations...)
+
+	loaded, err := amigo.NewRunner(target).DumpSchema(t.Context(), migrations)
+	if err != nil {
+		t.Fatalf("DumpSchema() of the loaded database error = %v", err)
+	}
+	if loaded != schema {
+		t.Errorf("DumpSchema() of the loaded database = %q, want %q", loaded, schema)
+	}
+
+	t.Run("database not empty", func(t *testing.T) {
+		_, err := amigo.NewRunner(source).LoadSchema(t.Context(), schema, migrations)
+		if !errors.Is(err, amigo.ErrDatabaseNotEmpty) {
+			t.Errorf("LoadSchema() error = %v, want ErrDatabaseNotEmpty", err)
+		}
+	})
+
+	t.Run("tables without migrations", func(t *testing.T) {
+		config := sqliteConfig(t)
+		if _, err := config.DB.ExecContext(t.Context(), "CREATE TABLE users (id INTEGER PRIMARY KEY)"); err != nil {
+			t.Fatalf("create table: %v", err)
+		}
+
+		_, err := amigo.NewRunner(config).LoadSchema(t.Context(), schema, migrations)
+		if !errors.Is(err, amigo.ErrDatabaseNotEmpty) {
+			t.Errorf("LoadSchema() error = %v, want ErrDatabaseNotEmpty", err)
+		}
+	})
+}
+
+func TestRunner_SchemaFile(t *testing.T) {
+	failure := errors.New("boom")
+	migrations := []amigo.Migration{
+		amigotest.NewMigration(1, "create_users", execSQL("CREATE TABLE users (id INTEGER PRIMARY KEY)"), execSQL("DROP TABLE users")),
+		amigotest.NewMigration(2, "create_posts", execSQL("CREATE TABLE posts (id INTEGER PRIMARY KEY)"), execSQL("DROP TABLE posts")),
+		amigotest.NewMigration(3, "broken", amigotest.Fail(failure), amigotest.Noop),
+	}
+
+	config := sqliteConfig(t)
+	config.SchemaFile = filepath.Join(t.TempDir(), "schema.sql")
+	runner := amigo.NewRunner(config)
+
+	readSchemaFile := func(t *testing.T) string {
+		t.Helper()
+		content, err := os.ReadFile(config.SchemaFile)
+		if err != nil {
+			t.Fatalf("read schema file: %v", err)
+		}
+		return string(content)
+	}
+
+	t.Run("up failing after a migration", func(t *testing.T) {
+		if err := runner.Up(t.Context(), migrations); !errors.Is(err, failure) {
+			t.Fatalf("Up() error = %v, want %v", err, failure)
+		}
+
+		schema := readSchemaFile(t)
+		if !strings.Contains(schema, "-- amigo:applied 2_create_posts\n") || strings.Contains(schema, "3_broken") {
+			t.Errorf("schema file = %q, want migrations 1 and 2 applied", schema)
+		}
+	})
+
+	t.Run("up stopped by the consumer", func(t *testing.T) {
+		stopConfig := sqliteConfig(t)
+		stopConfig.SchemaFile = filepath.Join(t.TempDir(), "schema.sql")
+
+		// As the CLI does when interrupted, stop after the first migration
+		for range amigo.NewRunner(stopConfig).UpIterator(t.Context(), migrations) {
+			break
+		}
+
+		content, err := os.ReadFile(stopConfig.SchemaFile)
+		if err != nil {
+			t.Fatalf("read schema file: %v", err)
+		}
+		schema := string(content)
+		if !strings.Contains(schema, "-- amigo:applied 1_create_users\n") || strings.Contains(schema, "2_create_posts") {
+			t.Errorf("schema file = %q, want only migration 1 applied", schema)
+		}
+	})
+
+	t.Run("down failing after a migration", func(t *testing.T) {
+		downConfig := sqliteConfig(t)
+		downConfig.SchemaFile = filepath.Join(t.TempDir(), "schema.sql")
+		downRunner := amigo.NewRunner(downConfig)
+
+		reversible := []amigo.Migration{
+			amigotest.NewMigration(1, "create_users", execSQL("CREATE TABLE users (id INTEGER PRIMARY KEY)"), amigotest.Fail(failure)),
+			amigotest.NewMigration(2, "create_posts", execSQL("CREATE TABLE posts (id INTEGER PRIMARY KEY)"), execSQL("DROP TABLE posts")),
+		}
+		if err := downRunner.Up(t.Context(), reversible); err != nil {
+			t.Fatalf("Up() error = %v", err)
+		}
+
+		for result := range downRunner.DownIterator(t.Context(), reversible, amigo.RunnerDownOptionSteps(-1)) {
+			if result.Error != nil {
+				break
+			}
+		}
+
+		content, err := os.ReadFile(downConfig.SchemaFile)
+		if err != nil {
+			t.Fatalf("read schema file: %v", err)
+		}
+		schema := string(content)
+		if strings.Contains(schema, "posts") || !strings.Contains(schema, "-- amigo:applied 1_create_users\n") {
+			t.Errorf("schema file = %q, want migration 2 reverted and 1 applied", schema)
+		}
+	})
+}
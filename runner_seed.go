@@ -0,0 +1,254 @@
+package amigo
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+)
+
+type runnerSeedOpts struct {
+	Rerun bool
+	Names []string
+}
+
+type RunnerSeedOptsFunc func(*runnerSeedOpts)
+
+// RunnerSeedOptionRerun runs seeds that already ran again, instead of skipping them
+func RunnerSeedOptionRerun() RunnerSeedOptsFunc {
+	return func(opts *runnerSeedOpts) {
+		opts.Rerun = true
+	}
+}
+
+// RunnerSeedOptionNames restricts the run to the seeds with the given names
+func RunnerSeedOptionNames(names ...string) RunnerSeedOptsFunc {
+	return func(opts *runnerSeedOpts) {
+		opts.Names = append(opts.Names, names...)
+	}
+}
+
+// SeedResult represents the result of running a seed
+type SeedResult struct {
+	Seed     Seeder
+	Error    error
+	Duration time.Duration
+}
+
+// inEnvironment reports whether something restricted to environments runs in env, see EnvironmentScoped.
+// Nothing is restricted when environments is empty.
+func inEnvironment(environments []string, env string) bool {
+	return len(environments) == 0 || slices.Contains(environments, env)
+}
+
+// seedEnvironments returns the environments a seed is restricted to, see EnvironmentScoped
+func seedEnvironments(s Seeder) []string {
+	if scoped, ok := s.(EnvironmentScoped); ok {
+		return scoped.Environments()
+	}
+	return nil
+}
+
+// seedKind returns the kind of source a seed is written in
+func seedKind(s Seeder) MigrationKind {
+	switch s.(type) {
+	case SQLSeed, *SQLSeed:
+		return MigrationKindSQL
+	default:
+		return MigrationKindGo
+	}
+}
+
+// seedDriver returns the driver as a SeedDriver after creating amigo's tables
+func (r *Runner) seedDriver(ctx context.Context) (driver SeedDriver, err error) {
+	driver, ok := r.config.Driver.(SeedDriver)
+	if !ok {
+		return nil, fmt.Errorf("%s driver: %w", r.config.Driver.Name(), ErrSeedNotSupported)
+	}
+
+	r.ensureTableCreatedOnce.Do(func() {
+		err = r.config.Driver.CreateSchemaMigrationsTableIfNotExists(ctx, r.config.DB)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
+
+	return driver, nil
+}
+
+// GetSeedStatuses returns the status of every seed in the environment of the runner, sorted by name
+func (r *Runner) GetSeedStatuses(ctx context.Context, seeds []Seeder) ([]SeedStatus, error) {
+	if len(seeds) == 0 {
+		return nil, nil
+	}
+
+	driver, err := r.seedDriver(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.seedStatuses(ctx, driver, seeds)
+}
+
+// seedStatuses returns the status of every seed from the records of driver, see GetSeedStatuses
+func (r *Runner) seedStatuses(ctx context.Context, driver SeedDriver, seeds []Seeder) ([]SeedStatus, error) {
+	records, err := driver.GetAppliedSeeds(ctx, r.config.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied seeds: %w", err)
+	}
+
+	recordsByName := make(map[string]MigrationRecord, len(records))
+	for _, record := range records {
+		recordsByName[record.Name] = record
+	}
+
+	var all []SeedStatus
+	for _, s := range seeds {
+		status := SeedStatus{
+			Record:       MigrationRecord{Name: s.Name(), Kind: seedKind(s)},
+			Environments: seedEnvironments(s),
+			State:        SeedStatePending,
+		}
+
+		if record, exists := recordsByName[s.Name()]; exists {
+			status.Record = record
+			status.State = SeedStateApplied
+			if checksum, ok := s.(Checksummer); ok && record.Checksum != "" && record.Checksum != checksum.Checksum() {
+				status.State = SeedStateChanged
+			}
+		}
+
+		if !inEnvironment(status.Environments, r.config.Environment) {
+			status.State = SeedStateSkipped
+		}
+
+		all = append(all, status)
+	}
+
+	slices.SortFunc(all, func(a, b SeedStatus) int {
+		return strings.Compare(a.Record.Name, b.Record.Name)
+	})
+
+	return all, nil
+}
+
+// planSeeds returns the seeds to run, sorted by name: the pending ones, or all of them with Rerun, and the
+// driver to record them with. Seeds of other environments are never run.
+func (r *Runner) planSeeds(ctx context.Context, seeds []Seeder, options runnerSeedOpts) (SeedDriver, []Seeder, error) {
+	byName := make(map[string]Seeder, len(seeds))
+	for _, s := range seeds {
+		if _, exists := byName[s.Name()]; exists {
+			return nil, nil, fmt.Errorf("duplicate seed name %s", s.Name())
+		}
+		byName[s.Name()] = s
+	}
+
+	for _, name := range options.Names {
+		if _, exists := byName[name]; !exists {
+			return nil, nil, fmt.Errorf("unknown seed %s", name)
+		}
+	}
+
+	if len(seeds) == 0 {
+		return nil, nil, nil
+	}
+
+	driver, err := r.seedDriver(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statuses, err := r.seedStatuses(ctx, driver, seeds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var plan []Seeder
+	for _, status := range statuses {
+		if len(options.Names) > 0 && !slices.Contains(options.Names, status.Record.Name) {
+			continue
+		}
+		if status.State == SeedStatePending || (options.Rerun && status.State != SeedStateSkipped) {
+			plan = append(plan, byName[status.Record.Name])
+		}
+	}
+
+	return driver, plan, nil
+}
+
+// SeedIterator returns an iterator that yields seed results as they run
+func (r *Runner) SeedIterator(ctx context.Context, seeds []Seeder, opts ...RunnerSeedOptsFunc) iter.Seq[SeedResult] {
+	return func(yield func(SeedResult) bool) {
+		var options runnerSeedOpts
+		for _, opt := range opts {
+			opt(&options)
+		}
+
+		driver, plan, err := r.planSeeds(ctx, seeds, options)
+		if err != nil {
+			yield(SeedResult{Error: err})
+			return
+		}
+
+		for _, s := range plan {
+			start := time.Now()
+
+			err := s.Seed(ContextWithDriver(ctx, r.config.Driver), r.config.DB)
+			duration := time.Since(start)
+
+			if err != nil {
+				yield(SeedResult{
+					Seed:     s,
+					Error:    fmt.Errorf("failed to run seed %s: %w", s.Name(), err),
+					Duration: duration,
+				})
+				return
+			}
+
+			// The seed ran: record it even if the context has been canceled in the meantime
+			err = driver.UpsertSeed(context.WithoutCancel(ctx), r.config.DB, r.newSeedRecord(s, duration))
+			if err != nil {
+				yield(SeedResult{
+					Seed:     s,
+					Error:    fmt.Errorf("failed to record seed %s: %w", s.Name(), err),
+					Duration: duration,
+				})
+				return
+			}
+
+			if !yield(SeedResult{Seed: s, Duration: duration}) {
+				return
+			}
+		}
+	}
+}
+
+// Seed runs the pending seeds of the environment of the runner
+func (r *Runner) Seed(ctx context.Context, seeds []Seeder, opts ...RunnerSeedOptsFunc) error {
+	for result := range r.SeedIterator(ctx, seeds, opts...) {
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
+}
+
+// newSeedRecord builds the record stored in the seeds table for a seed that ran in duration
+func (r *Runner) newSeedRecord(s Seeder, duration time.Duration) MigrationRecord {
+	record := MigrationRecord{
+		Name:         s.Name(),
+		Environment:  r.config.Environment,
+		Duration:     duration,
+		OSUser:       currentOSUser(),
+		Hostname:     currentHostname(),
+		AmigoVersion: Version(),
+		AppVersion:   r.config.AppVersion,
+		Kind:         seedKind(s),
+	}
+	if checksum, ok := s.(Checksummer); ok {
+		record.Checksum = checksum.Checksum()
+	}
+	return record
+}
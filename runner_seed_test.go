@@ -0,0 +1,176 @@
+package amigo_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alexisvisco/amigo"
+	"github.com/alexisvisco/amigo/pkg/amigotest"
+)
+
+// testSeed is a seed counting its runs, scoped to environments and checksummed when set
+type testSeed struct {
+	name         string
+	environments []string
+	checksum     string
+	runs         int
+}
+
+func (s *testSeed) Name() string           { return s.name }
+func (s *testSeed) Environments() []string { return s.environments }
+func (s *testSeed) Checksum() string       { return s.checksum }
+
+func (s *testSeed) Seed(ctx context.Context, db *sql.DB) error {
+	s.runs++
+	return nil
+}
+
+// seedNames returns the names of the seeds of results
+func seedNames(results []amigo.SeedResult) []string {
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Seed.Name())
+	}
+	return names
+}
+
+func TestRunner_Seed(t *testing.T) {
+	newRunner := func(driver amigo.Driver) *amigo.Runner {
+		return amigo.NewRunner(amigo.Configuration{Driver: driver, Environment: "production"})
+	}
+
+	t.Run("plan", func(t *testing.T) {
+		countries := &testSeed{name: "countries"}
+		fixtures := &testSeed{name: "fixtures", environments: []string{"development"}}
+		currencies := &testSeed{name: "currencies"}
+		driver := amigotest.NewDriver()
+
+		var results []amigo.SeedResult
+		for result := range newRunner(driver).SeedIterator(t.Context(), []amigo.Seeder{fixtures, currencies, countries}) {
+			if result.Error != nil {
+				t.Fatalf("SeedIterator() error = %v", result.Error)
+			}
+			results = append(results, result)
+		}
+
+		// Seeds run in name order, those of other environments are skipped
+		if got, want := seedNames(results), []string{"countries", "currencies"}; !slices.Equal(got, want) {
+			t.Errorf("seeds run = %v, want %v", got, want)
+		}
+		if fixtures.runs != 0 {
+			t.Errorf("fixtures ran %d time(s) outside its environment", fixtures.runs)
+		}
+		if got := len(driver.Seeds()); got != 2 {
+			t.Errorf("recorded %d seed(s), want 2", got)
+		}
+		for _, record := range driver.Seeds() {
+			if record.Environment != "production" {
+				t.Errorf("seed %s recorded in environment %q, want production", record.Name, record.Environment)
+			}
+		}
+
+		statuses, err := newRunner(driver).GetSeedStatuses(t.Context(), []amigo.Seeder{fixtures, currencies, countries})
+		if err != nil {
+			t.Fatalf("GetSeedStatuses() error = %v", err)
+		}
+		want := map[string]amigo.SeedState{
+			"countries":  amigo.SeedStateApplied,
+			"currencies": amigo.SeedStateApplied,
+			"fixtures":   amigo.SeedStateSkipped,
+		}
+		for _, status := range statuses {
+			if status.State != want[status.Record.Name] {
+				t.Errorf("state of %s = %s, want %s", status.Record.Name, status.State, want[status.Record.Name])
+			}
+		}
+	})
+
+	t.Run("rerun", func(t *testing.T) {
+		countries := &testSeed{name: "countries"}
+		currencies := &testSeed{name: "currencies"}
+		fixtures := &testSeed{name: "fixtures", environments: []string{"development"}}
+		seeds := []amigo.Seeder{countries, currencies, fixtures}
+		runner := newRunner(amigotest.NewDriver())
+
+		if err := runner.Seed(t.Context(), seeds); err != nil {
+			t.Fatalf("Seed() error = %v", err)
+		}
+		if err := runner.Seed(t.Context(), seeds); err != nil {
+			t.Fatalf("Seed() again error = %v", err)
+		}
+		if countries.runs != 1 {
+			t.Errorf("countries ran %d time(s) without rerun, want 1", countries.runs)
+		}
+
+		if err := runner.Seed(t.Context(), seeds, amigo.RunnerSeedOptionRerun(), amigo.RunnerSeedOptionNames("countries")); err != nil {
+			t.Fatalf("Seed() with rerun error = %v", err)
+		}
+		if countries.runs != 2 || currencies.runs != 1 {
+			t.Errorf("runs after rerun of countries = %d countries, %d currencies, want 2 and 1", countries.runs, currencies.runs)
+		}
+
+		if err := runner.Seed(t.Context(), seeds, amigo.RunnerSeedOptionRerun()); err != nil {
+			t.Fatalf("Seed() with rerun error = %v", err)
+		}
+		if fixtures.runs != 0 {
+			t.Errorf("fixtures ran %d time(s) outside its environment with rerun", fixtures.runs)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		countries := &testSeed{name: "countries", checksum: "v1"}
+		driver := amigotest.NewDriver()
+		runner := newRunner(driver)
+
+		if err := runner.Seed(t.Context(), []amigo.Seeder{countries}); err != nil {
+			t.Fatalf("Seed() error = %v", err)
+		}
+		if got := driver.Seeds()[0].Checksum; got != "v1" {
+			t.Errorf("recorded checksum = %q, want v1", got)
+		}
+
+		countries.checksum = "v2"
+		statuses, err := runner.GetSeedStatuses(t.Context(), []amigo.Seeder{countries})
+		if err != nil {
+			t.Fatalf("GetSeedStatuses() error = %v", err)
+		}
+		if statuses[0].State != amigo.SeedStateChanged {
+			t.Errorf("state = %s, want %s", statuses[0].State, amigo.SeedStateChanged)
+		}
+
+		// A changed seed runs again only when asked to
+		if err := runner.Seed(t.Context(), []amigo.Seeder{countries}); err != nil {
+			t.Fatalf("Seed() error = %v", err)
+		}
+		if countries.runs != 1 {
+			t.Errorf("changed seed ran %d time(s) without rerun, want 1", countries.runs)
+		}
+	})
+
+	t.Run("driver without seeds", func(t *testing.T) {
+		// Only the methods of amigo.Driver are promoted, not the ones of SeedDriver
+		driver := struct{ amigo.Driver }{amigotest.NewDriver()}
+
+		err := newRunner(driver).Seed(t.Context(), []amigo.Seeder{&testSeed{name: "countries"}})
+		if !errors.Is(err, amigo.ErrSeedNotSupported) {
+			t.Errorf("Seed() error = %v, want ErrSeedNotSupported", err)
+		}
+		if err := newRunner(driver).Seed(t.Context(), nil); err != nil {
+			t.Errorf("Seed() without seeds error = %v, want nil", err)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		driver := amigotest.NewDriver()
+		failure := errors.New("boom")
+		driver.FailOn(amigotest.MethodUpsertSeed, failure)
+
+		err := newRunner(driver).Seed(t.Context(), []amigo.Seeder{&testSeed{name: "countries"}})
+		if !errors.Is(err, failure) {
+			t.Errorf("Seed() error = %v, want %v", err, failure)
+		}
+	})
+}
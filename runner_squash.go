@@ -0,0 +1,73 @@
+package amigo
+
+import (
+	"fmt"
+	"slices"
+)
+
+// squashedVersions returns the dates of the migrations a migration replaces, see Squashed
+func squashedVersions(m Migration) []int64 {
+	if squashed, ok := m.(Squashed); ok {
+		return squashed.Squashes()
+	}
+	return nil
+}
+
+// squashAliases maps the date of every migration replaced by a squashed migration to the date of the squashed one
+func squashAliases(migrations []Migration) map[int64]int64 {
+	aliases := make(map[int64]int64)
+	for _, m := range migrations {
+		for _, version := range squashedVersions(m) {
+			aliases[version] = m.Date()
+		}
+	}
+	return aliases
+}
+
+// resolveSquashedMigrations replaces, in the applied records, the migrations replaced by a squashed migration
+// with a record of the squashed migration when the newest of them is applied. It fails when only older ones
+// are applied: running the squashed migration would apply them twice.
+func resolveSquashedMigrations(migrations []Migration, appliedMigrations []MigrationRecord) ([]MigrationRecord, error) {
+	appliedByDate := make(map[int64]MigrationRecord, len(appliedMigrations))
+	for _, record := range appliedMigrations {
+		appliedByDate[record.Date] = record
+	}
+
+	replaced := make(map[int64]struct{})
+	var squashedRecords []MigrationRecord
+	for _, m := range migrations {
+		versions := squashedVersions(m)
+		if len(versions) == 0 {
+			continue
+		}
+
+		record, applied := appliedByDate[m.Date()]
+		if !applied {
+			for _, version := range versions {
+				if _, ok := appliedByDate[version]; ok {
+					return nil, fmt.Errorf("migration %d_%s replaces migration %d which is applied while migration %d is not, "+
+						"apply the original migrations first: %w", m.Date(), m.Name(), version, m.Date(), ErrPartiallySquashed)
+				}
+			}
+			continue
+		}
+
+		for _, version := range versions {
+			replaced[version] = struct{}{}
+		}
+		replaced[m.Date()] = struct{}{}
+
+		record.Name = m.Name()
+		squashedRecords = append(squashedRecords, record)
+	}
+
+	if len(squashedRecords) == 0 {
+		return appliedMigrations, nil
+	}
+
+	resolved := slices.DeleteFunc(slices.Clone(appliedMigrations), func(record MigrationRecord) bool {
+		_, ok := replaced[record.Date]
+		return ok
+	})
+	return append(resolved, squashedRecords...), nil
+}
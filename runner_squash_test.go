@@ -0,0 +1,50 @@
+package amigo
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func Test_resolveSquashedMigrations(t *testing.T) {
+	squashed := SQLMigration{name: "squashed", date: 3, squashes: []int64{1, 2, 3}}
+	migrations := []Migration{squashed, SQLMigration{name: "later", date: 4}}
+
+	tests := []struct {
+		name    string
+		applied []int64
+		want    []int64
+		wantErr error
+	}{
+		{name: "fresh database", applied: nil, want: nil},
+		{name: "originals applied", applied: []int64{1, 2, 3, 4}, want: []int64{3, 4}},
+		{name: "squashed migration applied", applied: []int64{3}, want: []int64{3}},
+		{name: "originals partially applied", applied: []int64{1, 2}, wantErr: ErrPartiallySquashed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var records []MigrationRecord
+			for _, date := range tt.applied {
+				records = append(records, MigrationRecord{Date: date, Name: "original"})
+			}
+
+			resolved, err := resolveSquashedMigrations(migrations, records)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			var got []int64
+			for _, record := range resolved {
+				got = append(got, record.Date)
+				if record.Date == squashed.date && record.Name != squashed.name {
+					t.Errorf("record %d is named %s, want %s", record.Date, record.Name, squashed.name)
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package amigo_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alexisvisco/amigo"
+	"github.com/alexisvisco/amigo/pkg/amigotest"
+)
+
+// newTestRunner returns a runner backed by an in-memory driver
+func newTestRunner(driver *amigotest.Driver) (*amigo.Runner, amigo.Configuration) {
+	config := amigo.DefaultConfiguration
+	config.Driver = driver
+	return amigo.NewRunner(config), config
+}
+
+// recordCalls returns a MigrationFunc appending name to calls
+func recordCalls(calls *[]string, name string) amigotest.MigrationFunc {
+	return func(ctx context.Context, db *sql.DB) error {
+		*calls = append(*calls, name)
+		return nil
+	}
+}
+
+func TestRunner_Up(t *testing.T) {
+	var calls []string
+	first := amigotest.NewMigration(1, "first", recordCalls(&calls, "first"), amigotest.Noop)
+	second := amigotest.NewMigration(2, "second", recordCalls(&calls, "second"), amigotest.Noop)
+	third := amigotest.NewMigration(3, "third", recordCalls(&calls, "third"), amigotest.Noop)
+
+	runner, config := newTestRunner(amigotest.NewDriver(amigo.MigrationRecord{Date: 1, Name: "first"}))
+
+	if err := runner.Up(t.Context(), []amigo.Migration{third, first, second}, amigo.RunnerUpOptionSteps(1)); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	if want := []string{"second"}; !slices.Equal(calls, want) {
+		t.Errorf("ran %v, want %v", calls, want)
+	}
+	amigotest.AssertApplied(t, config, first, second)
+	amigotest.AssertPending(t, config, third)
+}
+
+func TestRunner_Up_migrationFailure(t *testing.T) {
+	errBoom := errors.New("boom")
+	first := amigotest.NewMigration(1, "first", amigotest.Noop, amigotest.Noop)
+	failing := amigotest.NewMigration(2, "failing", amigotest.Fail(errBoom), amigotest.Noop)
+	last := amigotest.NewMigration(3, "last", amigotest.Noop, amigotest.Noop)
+
+	runner, config := newTestRunner(amigotest.NewDriver())
+
+	err := runner.Up(t.Context(), []amigo.Migration{first, failing, last})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Up() error = %v, want %v", err, errBoom)
+	}
+
+	amigotest.AssertApplied(t, config, first)
+	amigotest.AssertPending(t, config, failing, last)
+}
+
+func TestRunner_Up_driverFailure(t *testing.T) {
+	errInsert := errors.New("insert failed")
+	driver := amigotest.NewDriver()
+	driver.FailOn(amigotest.MethodInsertMigrations, errInsert)
+
+	runner, config := newTestRunner(driver)
+	first := amigotest.NewMigration(1, "first", amigotest.Noop, amigotest.Noop)
+	second := amigotest.NewMigration(2, "second", amigotest.Noop, amigotest.Noop)
+
+	err := runner.Up(t.Context(), []amigo.Migration{first, second})
+	if !errors.Is(err, errInsert) {
+		t.Fatalf("Up() error = %v, want %v", err, errInsert)
+	}
+	if got := driver.Calls(amigotest.MethodInsertMigrations); got != 1 {
+		t.Errorf("InsertMigrations called %d times, want 1", got)
+	}
+
+	driver.FailOn(amigotest.MethodInsertMigrations, nil)
+	amigotest.AssertPending(t, config, first, second)
+}
+
+// verifyingDriver is an in-memory driver failing the verification of migrations with err
+type verifyingDriver struct {
+	*amigotest.Driver
+	err error
+}
+
+func (d verifyingDriver) VerifyMigration(ctx context.Context, db *sql.DB, start time.Time) error {
+	return d.err
+}
+
+func TestRunner_Up_verificationFailure(t *testing.T) {
+	errVerify := errors.New("failed on a replica")
+	first := amigotest.NewMigration(1, "first", amigotest.Noop, amigotest.Noop)
+
+	runner, config := newTestRunner(amigotest.NewDriver())
+	config.Driver = verifyingDriver{Driver: config.Driver.(*amigotest.Driver), err: errVerify}
+	runner = amigo.NewRunner(config)
+
+	err := runner.Up(t.Context(), []amigo.Migration{first})
+	if !errors.Is(err, errVerify) {
+		t.Fatalf("Up() error = %v, want %v", err, errVerify)
+	}
+
+	amigotest.AssertPending(t, config, first)
+}
+
+func TestRunner_Down(t *testing.T) {
+	var calls []string
+	first := amigotest.NewMigration(1, "first", amigotest.Noop, recordCalls(&calls, "first"))
+	second := amigotest.NewMigration(2, "second", amigotest.Noop, recordCalls(&calls, "second"))
+
+	runner, config := newTestRunner(amigotest.NewDriver(
+		amigo.MigrationRecord{Date: 1, Name: "first"},
+		amigo.MigrationRecord{Date: 2, Name: "second"},
+	))
+
+	if err := runner.Down(t.Context(), []amigo.Migration{first, second}, amigo.RunnerDownOptionSteps(1)); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+
+	if want := []string{"second"}; !slices.Equal(calls, want) {
+		t.Errorf("reverted %v, want %v", calls, want)
+	}
+	amigotest.AssertApplied(t, config, first)
+	amigotest.AssertPending(t, config, second)
+}
+
+func TestRunner_Down_irreversible(t *testing.T) {
+	irreversible := amigotest.NewMigration(1, "irreversible", amigotest.Noop, nil)
+
+	runner, config := newTestRunner(amigotest.NewDriver(amigo.MigrationRecord{Date: 1, Name: "irreversible"}))
+
+	err := runner.Down(t.Context(), []amigo.Migration{irreversible})
+	if !errors.Is(err, amigo.ErrIrreversible) {
+		t.Fatalf("Down() error = %v, want %v", err, amigo.ErrIrreversible)
+	}
+
+	amigotest.AssertApplied(t, config, irreversible)
+}
+
+func TestRunner_GetMigrationsStatuses_driverFailure(t *testing.T) {
+	errRead := errors.New("read failed")
+	driver := amigotest.NewDriver()
+	driver.FailOn(amigotest.MethodGetAppliedMigrations, errRead)
+
+	runner, _ := newTestRunner(driver)
+
+	_, err := runner.GetMigrationsStatuses(t.Context(), []amigo.Migration{amigotest.NewMigration(1, "first", nil, nil)})
+	if !errors.Is(err, errRead) {
+		t.Fatalf("GetMigrationsStatuses() error = %v, want %v", err, errRead)
+	}
+}
@@ -2,10 +2,14 @@ package amigo
 
 import (
 	"context"
+	"slices"
 )
 
 type runnerUpOpts struct {
-	Steps int
+	Steps             int
+	Environment       string
+	Tags              []string
+	StatementProgress bool
 }
 
 type RunnerUpOptsFunc func(*runnerUpOpts)
@@ -16,12 +20,61 @@ func RunnerUpOptionSteps(steps int) RunnerUpOptsFunc {
 	}
 }
 
+// RunnerUpOptionEnvironment selects the migrations of an environment instead of Configuration.Environment.
+// Migrations restricted to other environments are skipped, see EnvironmentScoped.
+func RunnerUpOptionEnvironment(environment string) RunnerUpOptsFunc {
+	return func(opts *runnerUpOpts) {
+		opts.Environment = environment
+	}
+}
+
+// RunnerUpOptionTags selects the migrations having one of the tags, and those without tags.
+// Without this option, tags are ignored. See Tagged.
+func RunnerUpOptionTags(tags ...string) RunnerUpOptsFunc {
+	return func(opts *runnerUpOpts) {
+		opts.Tags = append(opts.Tags, tags...)
+	}
+}
+
+// RunnerUpOptionStatementProgress makes UpIterator yield, while a SQL migration runs, a result with Progress set
+// before each of its statements, see StatementProgress
+func RunnerUpOptionStatementProgress() RunnerUpOptsFunc {
+	return func(opts *runnerUpOpts) {
+		opts.StatementProgress = true
+	}
+}
+
 func defaultRunnerUpOpts() runnerUpOpts {
 	return runnerUpOpts{
 		Steps: -1,
 	}
 }
 
+// newRunnerUpOpts returns the options of a run, selecting the migrations of the configured environment by default
+func (r *Runner) newRunnerUpOpts(opts []RunnerUpOptsFunc) runnerUpOpts {
+	options := defaultRunnerUpOpts()
+	options.Environment = r.config.Environment
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// selects reports whether a migration matches the environment and tags of the run
+func (o runnerUpOpts) selects(m Migration) bool {
+	if scoped, ok := m.(EnvironmentScoped); ok && !inEnvironment(scoped.Environments(), o.Environment) {
+		return false
+	}
+
+	tagged, ok := m.(Tagged)
+	if !ok || len(o.Tags) == 0 || len(tagged.Tags()) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(tagged.Tags(), func(tag string) bool {
+		return slices.Contains(o.Tags, tag)
+	})
+}
+
 func (r *Runner) Up(ctx context.Context, migrations []Migration, opts ...RunnerUpOptsFunc) error {
 	for result := range r.UpIterator(ctx, migrations, opts...) {
 		if result.Error != nil {
@@ -2,6 +2,7 @@ package amigo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"iter"
 	"time"
@@ -12,88 +13,184 @@ type MigrationResult struct {
 	Migration Migration
 	Error     error
 	Duration  time.Duration
+	// Progress is set on the results reporting the progress of a migration still running, see
+	// RunnerUpOptionStatementProgress and RunnerDownOptionStatementProgress
+	Progress *StatementProgress
 }
 
-// UpIterator returns an iterator that yields migration results as they are applied
+// UpIterator returns an iterator that yields migration results as they are applied, in the order of PlanUp
 func (r *Runner) UpIterator(ctx context.Context, migrations []Migration, opts ...RunnerUpOptsFunc) iter.Seq[MigrationResult] {
 	return func(yield func(MigrationResult) bool) {
-		options := defaultRunnerUpOpts()
-		for _, opt := range opts {
-			opt(&options)
-		}
-
-		if options.Steps == 0 {
+		plan, err := r.PlanUp(ctx, migrations, opts...)
+		if err != nil {
+			yield(MigrationResult{Error: err})
 			return
 		}
 
-		var tableErr error
-		r.ensureTableCreatedOnce.Do(func() {
-			tableErr = r.config.Driver.CreateSchemaMigrationsTableIfNotExists(ctx, r.config.DB)
-		})
-		if tableErr != nil {
-			yield(MigrationResult{
-				Error: fmt.Errorf("failed to create schema migrations table: %w", tableErr),
-			})
-			return
-		}
+		progress := r.newRunnerUpOpts(opts).StatementProgress
+		history := r.newHistoryRecorder()
 
-		appliedMigrations, err := r.config.Driver.GetAppliedMigrations(ctx, r.config.DB)
-		if err != nil {
-			yield(MigrationResult{
-				Error: fmt.Errorf("failed to get applied migrations: %w", err),
-			})
-			return
+		// The schema file reflects the migrations applied, even when one fails or the consumer stops
+		var schemaFile schemaFileTracker
+		yield = schemaFile.track(yield)
+		defer r.updateSchemaFile(ctx, migrations, &schemaFile, yield)
+
+		for _, m := range plan {
+			migrationCtx, migrationYield := withStatementProgress(ctx, m, progress, yield)
+			if !r.applyMigration(migrationCtx, m, history, migrationYield, r.saveAppliedMigration(ctx, m)) {
+				return
+			}
 		}
+	}
+}
+
+// PlanUp returns the migrations UpIterator would apply, in order: the pending versioned migrations oldest
+// first and after their dependencies (see Dependent), then the repeatable migrations that never ran or
+// changed, in name order. Repeatable migrations are left out when steps stop before the last versioned one.
+// It fails when the dependencies of a migration cannot be met.
+func (r *Runner) PlanUp(ctx context.Context, migrations []Migration, opts ...RunnerUpOptsFunc) (plan []Migration, err error) {
+	options := r.newRunnerUpOpts(opts)
+
+	if options.Steps == 0 {
+		return nil, nil
+	}
 
-		nonAppliedMigrations := r.filterNonAppliedMigrations(migrations, appliedMigrations)
+	r.ensureTableCreatedOnce.Do(func() {
+		err = r.config.Driver.CreateSchemaMigrationsTableIfNotExists(ctx, r.config.DB)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
 
-		for _, m := range nonAppliedMigrations {
-			start := time.Now()
+	appliedMigrations, err := r.getAppliedMigrations(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
 
-			err := m.Up(ctx, r.config.DB)
-			duration := time.Since(start)
+	versioned, _ := splitRepeatableMigrations(migrations)
+	plan, err = r.filterNonAppliedMigrations(versioned, appliedMigrations, options)
+	if err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				if !yield(MigrationResult{
-					Migration: m,
-					Error:     fmt.Errorf("failed to apply migration %s: %w", m.Name(), err),
-					Duration:  duration,
-				}) {
-					return
-				}
-				return
-			}
+	// Repeatable migrations run once every versioned migration is applied, so not when steps stop earlier
+	runRepeatables := true
+	if options.Steps > 0 && options.Steps < len(plan) {
+		plan = plan[:options.Steps]
+		runRepeatables = false
+	}
 
-			record := MigrationRecord{
-				Date: m.Date(),
-				Name: m.Name(),
-			}
-			err = r.config.Driver.InsertMigrations(ctx, r.config.DB, []MigrationRecord{record})
-			if err != nil {
-				if !yield(MigrationResult{
-					Migration: m,
-					Error:     fmt.Errorf("failed to record applied migration %s: %w", m.Name(), err),
-					Duration:  duration,
-				}) {
-					return
-				}
-				return
-			}
+	if err := checkDependenciesMet(plan, versioned, appliedDateSet(appliedMigrations)); err != nil {
+		return nil, err
+	}
 
-			if !yield(MigrationResult{
-				Migration: m,
-				Error:     nil,
-				Duration:  duration,
-			}) {
-				return
-			}
+	if !runRepeatables {
+		return plan, nil
+	}
 
-			if options.Steps > 0 {
-				options.Steps--
-				if options.Steps == 0 {
-					return
-				}
-			}
+	repeatables, err := r.pendingRepeatables(ctx, migrations, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(plan, repeatables...), nil
+}
+
+// withStatementProgress returns the context to run a migration with and the function yielding its results. When
+// progress is set, the context yields the progress of its statements, see ContextWithStatementProgress. The
+// consumer can stop during the migration: the function then returns false without yielding again.
+func withStatementProgress(ctx context.Context, m Migration, progress bool, yield func(MigrationResult) bool) (context.Context, func(MigrationResult) bool) {
+	if !progress {
+		return ctx, yield
+	}
+
+	stopped := false
+	guarded := func(result MigrationResult) bool {
+		if stopped {
+			return false
 		}
+		stopped = !yield(result)
+		return !stopped
+	}
+
+	ctx = ContextWithStatementProgress(ctx, func(p StatementProgress) {
+		guarded(MigrationResult{Migration: m, Progress: &p})
+	})
+	return ctx, guarded
+}
+
+// saveAppliedMigration returns the function recording a migration as applied: in schema_migrations, or in the
+// table of repeatable migrations
+func (r *Runner) saveAppliedMigration(ctx context.Context, m Migration) func(record MigrationRecord) error {
+	if isRepeatable(m) {
+		return func(record MigrationRecord) error {
+			return r.config.Driver.(RepeatableDriver).UpsertRepeatableMigration(context.WithoutCancel(ctx), r.config.DB, record)
+		}
+	}
+	return func(record MigrationRecord) error {
+		return r.config.Driver.InsertMigrations(context.WithoutCancel(ctx), r.config.DB, []MigrationRecord{record})
+	}
+}
+
+// verifyMigration checks a migration started at start with the driver when it is a MigrationVerifier
+func (r *Runner) verifyMigration(ctx context.Context, start time.Time) error {
+	verifier, ok := r.config.Driver.(MigrationVerifier)
+	if !ok {
+		return nil
 	}
+	return verifier.VerifyMigration(ctx, r.config.DB, start)
+}
+
+// applyMigration runs a migration up, stores its record with save and yields the result.
+// It returns false when the iteration must stop, after a failure or when the consumer stopped.
+func (r *Runner) applyMigration(
+	ctx context.Context,
+	m Migration,
+	history *historyRecorder,
+	yield func(MigrationResult) bool,
+	save func(record MigrationRecord) error,
+) bool {
+	start := time.Now()
+
+	// The statements of this migration only, for the verifier
+	migrationCtx := contextWithExecutedStatements(ContextWithDriver(ctx, r.config.Driver))
+	err := m.Up(migrationCtx, r.config.DB)
+	if err == nil {
+		err = r.verifyMigration(migrationCtx, start)
+	}
+	duration := time.Since(start)
+
+	if err != nil {
+		resultErr := fmt.Errorf("failed to apply migration %s: %w", m.Name(), err)
+		if historyErr := history.record(ctx, m, MigrationDirectionUp, start, duration, err); historyErr != nil {
+			resultErr = errors.Join(resultErr, historyErr)
+		}
+
+		yield(MigrationResult{
+			Migration: m,
+			Error:     resultErr,
+			Duration:  duration,
+		})
+		return false
+	}
+
+	// The migration is applied: record it even if the context has been canceled in the meantime
+	err = save(r.newMigrationRecord(m, duration))
+	if err == nil {
+		err = history.record(ctx, m, MigrationDirectionUp, start, duration, nil)
+	}
+	if err != nil {
+		yield(MigrationResult{
+			Migration: m,
+			Error:     fmt.Errorf("failed to record applied migration %s: %w", m.Name(), err),
+			Duration:  duration,
+		})
+		return false
+	}
+
+	return yield(MigrationResult{
+		Migration: m,
+		Error:     nil,
+		Duration:  duration,
+	})
 }
@@ -0,0 +1,24 @@
+package amigo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alexisvisco/amigo/pkg/schema"
+)
+
+// NewSchemaBuilder returns a schema.Builder rendering statements for the driver running the migration,
+// see DriverFromContext. exec is usually the *sql.DB given to Up and Down, or a *sql.Tx from Tx.
+func NewSchemaBuilder(ctx context.Context, exec schema.Executor) *schema.Builder {
+	dialect, _ := contextDialect(ctx)
+	return schema.New(ctx, exec, dialect)
+}
+
+// contextDialect returns the schema dialect of the driver carried by ctx
+func contextDialect(ctx context.Context) (schema.Dialect, error) {
+	driver, ok := DriverFromContext(ctx)
+	if !ok {
+		return "", errors.New("no driver in context: migrations must be run by a Runner or use ContextWithDriver")
+	}
+	return schema.ParseDialect(driver.Name())
+}
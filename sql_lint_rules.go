@@ -0,0 +1,201 @@
+package amigo
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	lintConcurrentlyRegexp  = regexp.MustCompile(`(?i)^(CREATE|DROP|REINDEX)\b.*\bCONCURRENTLY\b`)
+	lintCreateIndexRegexp   = regexp.MustCompile(`(?i)^CREATE\s+(UNIQUE\s+)?INDEX\b.*?\bON\s+(ONLY\s+)?([^\s(]+)`)
+	lintDropRegexp          = regexp.MustCompile(`(?i)^DROP\s+(TABLE|INDEX|VIEW|MATERIALIZED\s+VIEW|SEQUENCE|SCHEMA|TYPE|DOMAIN|FUNCTION|PROCEDURE|TRIGGER|EXTENSION|DICTIONARY|DATABASE)\s+(CONCURRENTLY\s+)?(IF\s+EXISTS\b)?`)
+	lintCreateTableRegexp   = regexp.MustCompile(`(?i)^CREATE\s+(TEMP\s+|TEMPORARY\s+|UNLOGGED\s+)?TABLE\s+(IF\s+NOT\s+EXISTS\s+)?([^\s(]+)`)
+	lintAlterTableRegexp    = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+(IF\s+EXISTS\s+)?(ONLY\s+)?([^\s(]+)`)
+	lintAddConstraintRegexp = regexp.MustCompile(`(?i)^ADD\s+(CONSTRAINT|PRIMARY|UNIQUE|FOREIGN|CHECK|EXCLUDE)\b`)
+	lintSetNotNullRegexp    = regexp.MustCompile(`(?i)\bALTER\s+(COLUMN\s+)?\S+\s+SET\s+NOT\s+NULL\b`)
+	lintNotNullRegexp       = regexp.MustCompile(`(?i)\bNOT\s+NULL\b`)
+	lintDefaultRegexp       = regexp.MustCompile(`(?i)\bDEFAULT\b`)
+	lintAlterTypeRegexp     = regexp.MustCompile(`(?i)\bALTER\s+(COLUMN\s+)?\S+\s+(SET\s+DATA\s+)?TYPE\b`)
+	lintRewriteRegexp       = regexp.MustCompile(`(?i)^(VACUUM\s+(\(.*\bFULL\b.*\)|FULL\b)|CLUSTER\b)`)
+)
+
+// DefaultLintRules returns the rules the lint command and Runner.Lint check by default
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		{
+			ID:          "concurrent-index-in-transaction",
+			Severity:    LintSeverityError,
+			Description: "CONCURRENTLY cannot run inside a transaction block",
+			Drivers:     []string{"postgres"},
+			CheckStatement: func(m LintMigration, section LintSection, statement string) string {
+				if !section.Transactional || !lintConcurrentlyRegexp.MatchString(statement) {
+					return ""
+				}
+				return "CONCURRENTLY fails inside a transaction, or splits the transaction of the section with " +
+					"Configuration.NonTransactionalOutsideTx, annotate the " + string(section.Direction) + " section with tx=false"
+			},
+		},
+		{
+			ID:          "index-without-concurrently",
+			Severity:    LintSeverityWarning,
+			Description: "CREATE INDEX without CONCURRENTLY blocks writes to the table while the index builds",
+			Drivers:     []string{"postgres"},
+			CheckStatement: func(m LintMigration, section LintSection, statement string) string {
+				matches := lintCreateIndexRegexp.FindStringSubmatch(statement)
+				if matches == nil || lintConcurrentlyRegexp.MatchString(statement) || lintTableCreated(section, matches[3]) {
+					return ""
+				}
+				return "the index build blocks writes to " + matches[3] + ", use CREATE INDEX CONCURRENTLY in a tx=false section"
+			},
+		},
+		{
+			ID:          "missing-down",
+			Severity:    LintSeverityWarning,
+			Description: "the down section is empty",
+			CheckMigration: func(m LintMigration) string {
+				if !m.MissingDown || m.Repeatable {
+					return ""
+				}
+				return "the down section is empty, write it or annotate it with irreversible"
+			},
+		},
+		{
+			ID:          "drop-without-if-exists",
+			Severity:    LintSeverityWarning,
+			Description: "DROP without IF EXISTS fails when the object is already gone",
+			CheckStatement: func(m LintMigration, section LintSection, statement string) string {
+				matches := lintDropRegexp.FindStringSubmatch(statement)
+				if matches == nil || matches[3] != "" {
+					return ""
+				}
+				return "DROP " + strings.ToUpper(matches[1]) + " fails when the object does not exist, use DROP " + strings.ToUpper(matches[1]) + " IF EXISTS"
+			},
+		},
+		{
+			ID:          "add-column-default",
+			Severity:    LintSeverityWarning,
+			Description: "ADD COLUMN with a DEFAULT can rewrite the table",
+			Drivers:     []string{"postgres"},
+			CheckStatement: func(m LintMigration, section LintSection, statement string) string {
+				table, ok := lintAlteredTable(section, statement)
+				if !ok {
+					return ""
+				}
+				for _, column := range lintAddedColumns(statement) {
+					if lintDefaultRegexp.MatchString(column) {
+						return "adding a column with a default rewrites " + table + " with a volatile default or before PostgreSQL 11, " +
+							"add the column without default, then set it and backfill in batches"
+					}
+				}
+				return ""
+			},
+		},
+		{
+			ID:          "not-null-column",
+			Severity:    LintSeverityWarning,
+			Description: "adding NOT NULL to an existing table scans it or fails when it has rows",
+			Drivers:     []string{"postgres"},
+			CheckStatement: func(m LintMigration, section LintSection, statement string) string {
+				table, ok := lintAlteredTable(section, statement)
+				if !ok {
+					return ""
+				}
+				if lintSetNotNullRegexp.MatchString(statement) {
+					return "SET NOT NULL scans " + table + " under an exclusive lock, add a NOT VALID check constraint, " +
+						"validate it, then set NOT NULL"
+				}
+				for _, column := range lintAddedColumns(statement) {
+					if lintNotNullRegexp.MatchString(column) && !lintDefaultRegexp.MatchString(column) {
+						return "adding a NOT NULL column without default fails when " + table + " has rows"
+					}
+				}
+				return ""
+			},
+		},
+		{
+			ID:          "table-rewrite",
+			Severity:    LintSeverityWarning,
+			Description: "the statement rewrites the table under an exclusive lock",
+			Drivers:     []string{"postgres"},
+			CheckStatement: func(m LintMigration, section LintSection, statement string) string {
+				if lintRewriteRegexp.MatchString(statement) {
+					return "the statement rewrites the table under an exclusive lock"
+				}
+				table, ok := lintAlteredTable(section, statement)
+				if !ok || !lintAlterTypeRegexp.MatchString(statement) {
+					return ""
+				}
+				return "changing the type of a column rewrites " + table + " under an exclusive lock, " +
+					"unless the new type is binary compatible"
+			},
+		},
+	}
+}
+
+// lintAlteredTable returns the table an ALTER TABLE statement changes, unless the section creates it: changing
+// a new, empty table is safe
+func lintAlteredTable(section LintSection, statement string) (string, bool) {
+	matches := lintAlterTableRegexp.FindStringSubmatch(statement)
+	if matches == nil || lintTableCreated(section, matches[3]) {
+		return "", false
+	}
+	return matches[3], true
+}
+
+// lintAddedColumns returns the ADD clauses of an ALTER TABLE statement adding columns
+func lintAddedColumns(statement string) []string {
+	prefix := lintAlterTableRegexp.FindString(statement)
+	if prefix == "" {
+		return nil
+	}
+
+	var columns []string
+	for _, clause := range lintSplitClauses(statement[len(prefix):]) {
+		clause = strings.TrimSpace(clause)
+		if len(clause) > 4 && strings.EqualFold(clause[:4], "ADD ") && !lintAddConstraintRegexp.MatchString(clause) {
+			columns = append(columns, clause)
+		}
+	}
+	return columns
+}
+
+// lintSplitClauses splits the clauses of a statement on the commas outside parentheses and quotes
+func lintSplitClauses(s string) []string {
+	var clauses []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == ',' && depth == 0:
+			clauses = append(clauses, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(clauses, s[start:])
+}
+
+// lintTableCreated reports whether a section creates the table
+func lintTableCreated(section LintSection, table string) bool {
+	for _, statement := range section.Statements {
+		matches := lintCreateTableRegexp.FindStringSubmatch(statement)
+		if matches != nil && lintTableName(matches[3]) == lintTableName(table) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintTableName returns a table name without quotes, lowercased
+func lintTableName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, `"`, ""))
+}
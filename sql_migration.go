@@ -4,11 +4,14 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"embed"
+	"encoding/hex"
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -20,60 +23,128 @@ type SQLMigration struct {
 
 	txUp   bool
 	txDown bool
+	// upSegments and downSegments are set when a tx=true section has statements that cannot run in a
+	// transaction and Configuration.NonTransactionalOutsideTx is set
+	upSegments   []sqlSegment
+	downSegments []sqlSegment
+	// upErr and downErr are set when a tx=true section has statements that cannot run in a transaction and
+	// Configuration.NonTransactionalOutsideTx is not set, see ErrNonTransactionalStatement
+	upErr   error
+	downErr error
+
+	// upLine and downLine are the lines the up and down sections start at in the file, see StatementError
+	upLine   int
+	downLine int
+
+	// irreversible is set when the down section is empty or annotated with "irreversible"
+	irreversible bool
+	// irreversibleAnnotation is set when the down annotation has "irreversible", see the missing-down lint rule
+	irreversibleAnnotation bool
+
+	// repeatable is set for files named R_<name>.sql, see Repeatable
+	repeatable bool
+	// checksum is the sha256 of the file content, after template rendering
+	checksum string
+	// path and source are the path and content of a file rendered as a template, see renderTemplates
+	path   string
+	source []byte
+
+	// environments and tags come from the env= and tags= options of the up annotation
+	environments []string
+	tags         []string
+	// dependsOn comes from the depends= option of the up annotation
+	dependsOn []int64
+	// squashes comes from the amigo:squashed lines written by the squash command
+	squashes []int64
 
 	splitStatements bool
 }
 
 func (s SQLMigration) Up(ctx context.Context, db *sql.DB) error {
+	if s.upErr != nil {
+		return s.upErr
+	}
+
+	if s.upSegments != nil {
+		return s.execSegments(ctx, db, s.upSegments)
+	}
+
 	if s.txUp {
 		return Tx(ctx, db, func(tx *sql.Tx) error {
-			return s.execSQL(ctx, tx, s.up)
+			return s.execSQL(ctx, tx, s.up, s.upLine)
 		})
 	}
 
-	return s.execSQLDB(ctx, db, s.up)
+	return s.execSQL(ctx, db, s.up, s.upLine)
 }
 
 func (s SQLMigration) Down(ctx context.Context, db *sql.DB) error {
+	if s.irreversible {
+		return ErrIrreversible
+	}
+
+	if s.downErr != nil {
+		return s.downErr
+	}
+
+	if s.downSegments != nil {
+		return s.execSegments(ctx, db, s.downSegments)
+	}
+
 	if s.txDown {
 		return Tx(ctx, db, func(tx *sql.Tx) error {
-			return s.execSQL(ctx, tx, s.down)
+			return s.execSQL(ctx, tx, s.down, s.downLine)
 		})
 	}
 
-	return s.execSQLDB(ctx, db, s.down)
+	return s.execSQL(ctx, db, s.down, s.downLine)
 }
 
-// execSQL executes SQL within a transaction, either as a single exec or split by statements
-func (s SQLMigration) execSQL(ctx context.Context, tx *sql.Tx, query string) error {
-	if !s.splitStatements {
-		_, err := tx.ExecContext(ctx, query)
-		return err
+// execSQL executes SQL starting at firstLine of the file, either as a single exec or split by statements,
+// see StatementError. The failure of a single exec is located in the statements of the query.
+func (s SQLMigration) execSQL(ctx context.Context, exec sqlExecutor, query string, firstLine int) error {
+	_, inTx := exec.(*sql.Tx)
+	statements := s.statements(query, firstLine, inTx)
+	err := execStatements(ctx, exec, statements, 0, len(statements))
+	if err != nil && !s.splitStatements && len(statements) == 1 {
+		return locateStatementError(err, query, firstLine)
 	}
+	return err
+}
 
-	statements := splitSQLStatementsWithAnnotations(query)
-	for _, stmt := range statements {
-		if _, err := tx.ExecContext(ctx, stmt); err != nil {
-			return err
-		}
-	}
-	return nil
+// Irreversible reports whether the migration cannot be reverted, see Irreversible
+func (s SQLMigration) Irreversible() bool {
+	return s.irreversible
 }
 
-// execSQLDB executes SQL on a DB, either as a single exec or split by statements
-func (s SQLMigration) execSQLDB(ctx context.Context, db *sql.DB, query string) error {
-	if !s.splitStatements {
-		_, err := db.ExecContext(ctx, query)
-		return err
-	}
+// Repeatable reports whether the migration runs again when its content changes, see Repeatable
+func (s SQLMigration) Repeatable() bool {
+	return s.repeatable
+}
 
-	statements := splitSQLStatementsWithAnnotations(query)
-	for _, stmt := range statements {
-		if _, err := db.ExecContext(ctx, stmt); err != nil {
-			return err
-		}
-	}
-	return nil
+// Checksum returns the sha256 of the migration file as rendered, see Checksummer
+func (s SQLMigration) Checksum() string {
+	return s.checksum
+}
+
+// Environments returns the environments of the env= annotation, see EnvironmentScoped
+func (s SQLMigration) Environments() []string {
+	return s.environments
+}
+
+// Tags returns the tags of the tags= annotation, see Tagged
+func (s SQLMigration) Tags() []string {
+	return s.tags
+}
+
+// DependsOn returns the migration versions of the depends= annotation, see Dependent
+func (s SQLMigration) DependsOn() []int64 {
+	return s.dependsOn
+}
+
+// Squashes returns the migration versions of the amigo:squashed lines, see Squashed
+func (s SQLMigration) Squashes() []int64 {
+	return s.squashes
 }
 
 func (s SQLMigration) Name() string {
@@ -91,19 +162,46 @@ func SQLFileToMigration(fs embed.FS, filepath string, config Configuration) Migr
 		panic(fmt.Sprintf("failed to read migration file %s: %v", filepath, err))
 	}
 
-	name, date, err := parseFileName(filepath)
+	migration, err := sqlFileToMigration(filepath, file, config)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load migration file %s: %v", filepath, err))
+	}
+
+	return migration
+}
+
+// sqlFileToMigration renders and parses the content of a migration file. The content of a file rendered as a
+// template is kept, to render it again with the configuration the migrations run with, see renderTemplates.
+func sqlFileToMigration(filepath string, file []byte, config Configuration) (SQLMigration, error) {
+	name, repeatable := parseRepeatableFileName(filepath)
+	var date int64
+	if !repeatable {
+		var err error
+		name, date, err = parseFileName(filepath)
+		if err != nil {
+			return SQLMigration{}, err
+		}
+	}
+	rendered, err := renderSQLTemplate(filepath, file, config)
 	if err != nil {
-		panic(fmt.Sprintf("failed to parse migration file name %s: %v", filepath, err))
+		return SQLMigration{}, err
 	}
-	migration, err := parseSQLFile(file, config)
+	migration, err := parseSQLFile(rendered, config)
 	if err != nil {
-		panic(fmt.Sprintf("failed to parse migration file %s: %v", filepath, err))
+		return SQLMigration{}, err
 	}
 	migration.name = name
 	migration.date = date
-	migration.splitStatements = config.SplitStatements
+	migration.repeatable = repeatable
+	if config.TemplateData != nil {
+		migration.path = filepath
+		migration.source = file
+	}
 
-	return migration
+	sum := sha256.Sum256(rendered)
+	migration.checksum = hex.EncodeToString(sum[:])
+
+	return migration, nil
 }
 
 // parseFileName parses the migration file name to extract the name and date
@@ -133,6 +231,22 @@ func parseFileName(filePath string) (name string, date int64, err error) {
 	return name, toDate, nil
 }
 
+// repeatableFilePrefix is the prefix of repeatable migration files, see Repeatable
+const repeatableFilePrefix = "R_"
+
+// parseRepeatableFileName returns the name of a repeatable migration file
+//
+//	ex: "R_refresh_views.sql" -> gives "refresh_views", true
+func parseRepeatableFileName(filePath string) (name string, ok bool) {
+	filename := filepath.Base(filePath)
+	if !strings.HasPrefix(filename, repeatableFilePrefix) {
+		return "", false
+	}
+
+	name = strings.TrimSuffix(strings.TrimPrefix(filename, repeatableFilePrefix), filepath.Ext(filename))
+	return name, name != ""
+}
+
 func parseVersionToDate(version any) (int64, error) {
 	vStr, ok := version.(string)
 	if !ok {
@@ -156,29 +270,67 @@ func parseVersionToDate(version any) (int64, error) {
 // -- migrate:down tx=false
 // DROP TABLE users;
 // In this example, the up migration will be run in a transaction, while the down migration will not
+// The up annotation can restrict the migration with env=dev,staging and tags=eu,us, see EnvironmentScoped and Tagged,
+// and declare the versions it needs applied first with depends=20240101120000,20240102120000, see Dependent
+// Lines "-- amigo:squashed 20240101120000_create_users" before the up annotation list the migrations a squashed
+// migration replaces, see Squashed
+// A migration whose down section is empty, or annotated with "-- migrate:down irreversible", is irreversible
+// A tx=true section with statements that cannot run in a transaction for the driver of config fails to run,
+// see ErrNonTransactionalStatement
 func parseSQLFile(fileContent []byte, config Configuration) (SQLMigration, error) {
 	file := SQLMigration{
-		up:     "",
-		down:   "",
-		txUp:   true,
-		txDown: true,
+		up:              "",
+		down:            "",
+		txUp:            true,
+		txDown:          true,
+		splitStatements: config.SplitStatements,
 	}
 
 	var upLines, downLines [][]byte
 	var current *[][]byte // nil = before up, &upLines = in up, &downLines = in down
 
 	txRegexp := regexp.MustCompile(`tx=(true|false)`)
+	irreversibleRegexp := regexp.MustCompile(`(^|\s)irreversible(\s|$)`)
+	envRegexp := regexp.MustCompile(`env=(\S+)`)
+	tagsRegexp := regexp.MustCompile(`tags=(\S+)`)
+	dependsRegexp := regexp.MustCompile(`depends=(\S+)`)
 	scanner := bufio.NewScanner(bytes.NewReader(fileContent))
+	lineNumber := 0
 	for scanner.Scan() {
 		line := scanner.Bytes()
+		lineNumber++
 
 		if bytes.HasPrefix(line, []byte(config.SQLFileUpAnnotation)) {
+			file.upLine = lineNumber + 1
 			parseTxAnnotation(scanner.Text(), &file.txUp, txRegexp)
+			file.environments = parseListAnnotation(scanner.Text(), envRegexp)
+			file.tags = parseListAnnotation(scanner.Text(), tagsRegexp)
+			for _, version := range parseListAnnotation(scanner.Text(), dependsRegexp) {
+				date, err := strconv.ParseInt(version, 10, 64)
+				if err != nil {
+					return file, fmt.Errorf("invalid depends= version %q", version)
+				}
+				file.dependsOn = append(file.dependsOn, date)
+			}
 			current = &upLines
 			continue
 		}
+		if current == nil && bytes.HasPrefix(line, []byte(squashedAnnotation+" ")) {
+			version, _, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(scanner.Text(), squashedAnnotation)), "_")
+			date, err := strconv.ParseInt(version, 10, 64)
+			if err != nil {
+				return file, fmt.Errorf("invalid %s version %q", squashedAnnotation, version)
+			}
+			file.squashes = append(file.squashes, date)
+			continue
+		}
 		if bytes.HasPrefix(line, []byte(config.SQLFileDownAnnotation)) {
+			file.downLine = lineNumber + 1
 			parseTxAnnotation(scanner.Text(), &file.txDown, txRegexp)
+			if irreversibleRegexp.MatchString(strings.TrimPrefix(scanner.Text(), config.SQLFileDownAnnotation)) {
+				file.irreversible = true
+				file.irreversibleAnnotation = true
+			}
 			current = &downLines
 			continue
 		}
@@ -194,10 +346,18 @@ func parseSQLFile(fileContent []byte, config Configuration) (SQLMigration, error
 
 	file.up = string(bytes.Join(upLines, []byte("\n")))
 	file.down = string(bytes.Join(downLines, []byte("\n")))
+	if strings.TrimSpace(file.down) == "" {
+		file.irreversible = true
+	}
+
+	file.checkNonTransactional(config)
 
 	return file, nil
 }
 
+// squashedAnnotation starts the lines listing the migrations replaced by a squashed migration, see Squashed
+const squashedAnnotation = "-- amigo:squashed"
+
 // parseTxAnnotation parses the tx annotation from the given text and sets the value of b accordingly
 // A typical refexp is regexp.MustCompile(`tx=(true|false)`)
 func parseTxAnnotation(line string, b *bool, annotation *regexp.Regexp) {
@@ -211,6 +371,16 @@ func parseTxAnnotation(line string, b *bool, annotation *regexp.Regexp) {
 	}
 }
 
+// parseListAnnotation returns the comma separated values of an annotation such as env=dev,staging,
+// or nil when the line does not have it
+func parseListAnnotation(line string, annotation *regexp.Regexp) []string {
+	matches := annotation.FindStringSubmatch(line)
+	if len(matches) != 2 {
+		return nil
+	}
+	return strings.Split(matches[1], ",")
+}
+
 const (
 	statementBeginAnnotation = "-- amigo:statement:begin"
 	statementEndAnnotation   = "-- amigo:statement:end"
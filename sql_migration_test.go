@@ -76,10 +76,11 @@ DROP TABLE users;`,
 			name:    "empty file",
 			content: "",
 			want: SQLMigration{
-				up:     "",
-				down:   "",
-				txUp:   true,
-				txDown: true,
+				up:           "",
+				down:         "",
+				txUp:         true,
+				txDown:       true,
+				irreversible: true,
 			},
 		},
 		{
@@ -87,10 +88,68 @@ DROP TABLE users;`,
 			content: `-- +migrate Up
 CREATE TABLE users (id INT);`,
 			want: SQLMigration{
-				up:     "CREATE TABLE users (id INT);",
-				down:   "",
-				txUp:   true,
-				txDown: true,
+				up:           "CREATE TABLE users (id INT);",
+				down:         "",
+				txUp:         true,
+				txDown:       true,
+				irreversible: true,
+			},
+		},
+		{
+			name: "empty down section",
+			content: `-- +migrate Up
+DELETE FROM users WHERE id = 1;
+-- +migrate Down
+`,
+			want: SQLMigration{
+				up:           "DELETE FROM users WHERE id = 1;",
+				down:         "",
+				txUp:         true,
+				txDown:       true,
+				irreversible: true,
+			},
+		},
+		{
+			name: "irreversible annotation on down",
+			content: `-- +migrate Up
+ALTER TABLE users DROP COLUMN name;
+-- +migrate Down tx=false irreversible
+SELECT 1;`,
+			want: SQLMigration{
+				up:           "ALTER TABLE users DROP COLUMN name;",
+				down:         "SELECT 1;",
+				txUp:         true,
+				txDown:       false,
+				irreversible: true,
+			},
+		},
+		{
+			name: "env and tags on up",
+			content: `-- +migrate Up tx=false env=dev,staging tags=eu
+INSERT INTO regions VALUES ('eu');
+-- +migrate Down
+DELETE FROM regions;`,
+			want: SQLMigration{
+				up:           "INSERT INTO regions VALUES ('eu');",
+				down:         "DELETE FROM regions;",
+				txUp:         false,
+				txDown:       true,
+				environments: []string{"dev", "staging"},
+				tags:         []string{"eu"},
+			},
+		},
+		{
+			name: "depends on up",
+			content: `-- +migrate Up depends=20240101120000,20240102120000
+ALTER TABLE users ADD COLUMN region_id INT;
+-- +migrate Down
+ALTER TABLE users DROP COLUMN region_id;`,
+			want: SQLMigration{
+				up:        "ALTER TABLE users ADD COLUMN region_id INT;",
+				down:      "ALTER TABLE users DROP COLUMN region_id;",
+				txUp:      true,
+				txDown:    true,
+				dependsOn: []int64{20240101120000, 20240102120000},
 			},
 		},
 	}
@@ -114,6 +173,18 @@ CREATE TABLE users (id INT);`,
 			if got.txDown != tt.want.txDown {
 				t.Errorf("txDown: got %v, want %v", got.txDown, tt.want.txDown)
 			}
+			if got.irreversible != tt.want.irreversible {
+				t.Errorf("irreversible: got %v, want %v", got.irreversible, tt.want.irreversible)
+			}
+			if !reflect.DeepEqual(got.environments, tt.want.environments) {
+				t.Errorf("environments: got %v, want %v", got.environments, tt.want.environments)
+			}
+			if !reflect.DeepEqual(got.tags, tt.want.tags) {
+				t.Errorf("tags: got %v, want %v", got.tags, tt.want.tags)
+			}
+			if !reflect.DeepEqual(got.dependsOn, tt.want.dependsOn) {
+				t.Errorf("dependsOn: got %v, want %v", got.dependsOn, tt.want.dependsOn)
+			}
 		})
 	}
 }
@@ -188,6 +259,32 @@ func Test_parseFileName(t *testing.T) {
 	}
 }
 
+func Test_parseRepeatableFileName(t *testing.T) {
+	tests := []struct {
+		filepath string
+		wantName string
+		wantOk   bool
+	}{
+		{filepath: "R_refresh_views.sql", wantName: "refresh_views", wantOk: true},
+		{filepath: "migrations/R_grants.sql", wantName: "grants", wantOk: true},
+		{filepath: "R_.sql", wantOk: false},
+		{filepath: "20240101120000_create_users_table.sql", wantOk: false},
+		{filepath: "r_lowercase.sql", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filepath, func(t *testing.T) {
+			gotName, gotOk := parseRepeatableFileName(tt.filepath)
+			if gotOk != tt.wantOk {
+				t.Fatalf("ok: got %v, want %v", gotOk, tt.wantOk)
+			}
+			if gotName != tt.wantName && tt.wantOk {
+				t.Errorf("name: got %q, want %q", gotName, tt.wantName)
+			}
+		})
+	}
+}
+
 func Test_splitSQLStatementsWithAnnotations(t *testing.T) {
 	tests := []struct {
 		name string
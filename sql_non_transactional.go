@@ -0,0 +1,153 @@
+package amigo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrNonTransactionalStatement is returned when running the tx=true section of an SQL migration with a statement
+// that cannot run inside a transaction, unless Configuration.NonTransactionalOutsideTx is set. The section is not
+// executed.
+var ErrNonTransactionalStatement = errors.New("statement cannot run inside a transaction")
+
+// nonTransactionalStatements are, per driver name, the statements that fail inside a transaction
+var nonTransactionalStatements = map[string][]*regexp.Regexp{
+	"postgres": {
+		regexp.MustCompile(`(?i)^(CREATE|DROP|REINDEX)\b.*\bCONCURRENTLY\b`),
+		// Fails inside a transaction before PostgreSQL 12, and the value cannot be used in the same transaction after
+		regexp.MustCompile(`(?i)^ALTER\s+TYPE\b.*\bADD\s+VALUE\b`),
+		regexp.MustCompile(`(?i)^VACUUM\b`),
+		regexp.MustCompile(`(?i)^(CREATE|DROP)\s+(DATABASE|TABLESPACE)\b`),
+		regexp.MustCompile(`(?i)^ALTER\s+SYSTEM\b`),
+		regexp.MustCompile(`(?i)^REINDEX\s+(DATABASE|SYSTEM)\b`),
+	},
+	"sqlite": {
+		regexp.MustCompile(`(?i)^VACUUM\b`),
+	},
+}
+
+// isNonTransactionalStatement reports whether a statement fails inside a transaction with the driver
+func isNonTransactionalStatement(driverName, statement string) bool {
+	normalized := normalizeStatement(statement)
+	for _, re := range nonTransactionalStatements[driverName] {
+		if re.MatchString(normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlSegment is a run of statements of a section executed together, in a transaction or not
+type sqlSegment struct {
+	statements []sqlStatement
+	tx         bool
+}
+
+// nonTransactionalSegments splits the statements of a transactional section into runs of statements executed in a
+// transaction and statements executed outside, in the order they are written. It returns nil segments and an
+// empty statement when every statement can run in a transaction, and the first one that cannot otherwise.
+func nonTransactionalSegments(driverName string, statements []sqlStatement) (segments []sqlSegment, statement string) {
+	if len(nonTransactionalStatements[driverName]) == 0 {
+		return nil, ""
+	}
+
+	for _, stmt := range statements {
+		tx := !isNonTransactionalStatement(driverName, stmt.sql)
+		if !tx && statement == "" {
+			statement = stmt.sql
+		}
+
+		if len(segments) > 0 && segments[len(segments)-1].tx == tx {
+			last := &segments[len(segments)-1]
+			last.statements = append(last.statements, stmt)
+			continue
+		}
+		segments = append(segments, sqlSegment{statements: []sqlStatement{stmt}, tx: tx})
+	}
+
+	if statement == "" {
+		return nil, ""
+	}
+	return segments, statement
+}
+
+// checkNonTransactional looks for the statements that cannot run inside a transaction with the driver of config
+// in the tx=true sections of the migration. When config.NonTransactionalOutsideTx is set, it prepares the section
+// to run them outside the transaction, else the section fails with ErrNonTransactionalStatement when run, the
+// concurrent-index-in-transaction lint rule reporting them beforehand.
+// Statements are split like SplitStatements does when it is set, else like splitSQLForLocation, which keeps
+// dollar-quoted bodies whole. A section splitSQLForLocation cannot split fails too, as its statements cannot be
+// run apart safely.
+func (s *SQLMigration) checkNonTransactional(config Configuration) {
+	if config.Driver == nil {
+		return
+	}
+
+	for _, section := range []struct {
+		direction MigrationDirection
+		tx        bool
+		sql       string
+		line      int
+		segments  *[]sqlSegment
+		err       *error
+	}{
+		{MigrationDirectionUp, s.txUp, s.up, s.upLine, &s.upSegments, &s.upErr},
+		{MigrationDirectionDown, s.txDown, s.down, s.downLine, &s.downSegments, &s.downErr},
+	} {
+		if !section.tx {
+			continue
+		}
+
+		statements := splitSQLStatementsWithLines(section.sql, section.line)
+		splittable := true
+		if !s.splitStatements {
+			if located, ok := splitSQLForLocation(section.sql, section.line); ok {
+				statements = located
+			} else {
+				splittable = false
+			}
+		}
+
+		segments, statement := nonTransactionalSegments(config.Driver.Name(), statements)
+		switch {
+		case segments == nil:
+		case !config.NonTransactionalOutsideTx:
+			*section.err = fmt.Errorf("%w: %q in the tx=true %s section, annotate it with tx=false or set "+
+				"Configuration.NonTransactionalOutsideTx", ErrNonTransactionalStatement, normalizeStatement(statement), section.direction)
+		case !splittable:
+			*section.err = fmt.Errorf("%w: %q in the tx=true %s section, which cannot be split in statements to run "+
+				"it outside the transaction, annotate it with tx=false or set Configuration.SplitStatements",
+				ErrNonTransactionalStatement, normalizeStatement(statement), section.direction)
+		default:
+			*section.segments = segments
+		}
+	}
+}
+
+// execSegments executes the segments of a section, each run of transactional statements in its own transaction
+func (s SQLMigration) execSegments(ctx context.Context, db *sql.DB, segments []sqlSegment) error {
+	count := 0
+	for _, segment := range segments {
+		count += len(segment.statements)
+	}
+
+	offset := 0
+	for _, segment := range segments {
+		var err error
+		if segment.tx {
+			err = Tx(ctx, db, func(tx *sql.Tx) error {
+				return execStatements(ctx, tx, segment.statements, offset, count)
+			})
+		} else {
+			err = execStatements(ctx, db, segment.statements, offset, count)
+		}
+		if err != nil {
+			return err
+		}
+		offset += len(segment.statements)
+	}
+	return nil
+}
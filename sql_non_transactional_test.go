@@ -0,0 +1,123 @@
+package amigo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_parseSQLFile_nonTransactional(t *testing.T) {
+	const content = "-- migrate:up\nCREATE TABLE users (id INT);\nCREATE INDEX CONCURRENTLY idx_users_id ON users(id);\n" +
+		"-- amigo:comment\nVACUUM users;\nINSERT INTO users VALUES (1);\n-- migrate:down\nDROP TABLE users;"
+
+	config := DefaultConfiguration
+	config.Driver = NewPostgresDriver("")
+
+	t.Run("fails when run by default", func(t *testing.T) {
+		// Parsing succeeds so that other commands and the lint rule still work with the migration
+		migration, err := parseSQLFile([]byte(content), config)
+		if err != nil {
+			t.Fatalf("parseSQLFile() error = %v", err)
+		}
+		if err := migration.Up(t.Context(), nil); !errors.Is(err, ErrNonTransactionalStatement) {
+			t.Fatalf("Up() error = %v, want %v", err, ErrNonTransactionalStatement)
+		}
+	})
+
+	t.Run("split statements", func(t *testing.T) {
+		split := config
+		split.SplitStatements = true
+		migration, err := parseSQLFile([]byte(content), split)
+		if err != nil {
+			t.Fatalf("parseSQLFile() error = %v", err)
+		}
+		if err := migration.Up(t.Context(), nil); !errors.Is(err, ErrNonTransactionalStatement) {
+			t.Fatalf("Up() error = %v, want %v", err, ErrNonTransactionalStatement)
+		}
+
+		split.NonTransactionalOutsideTx = true
+		migration, err = parseSQLFile([]byte(content), split)
+		if err != nil {
+			t.Fatalf("parseSQLFile() error = %v", err)
+		}
+		if len(migration.upSegments) != 3 {
+			t.Errorf("upSegments = %#v, want 3 segments", migration.upSegments)
+		}
+	})
+
+	t.Run("section that cannot be split", func(t *testing.T) {
+		outside := config
+		outside.NonTransactionalOutsideTx = true
+		migration, err := parseSQLFile([]byte("-- migrate:up\nVACUUM users;\nSELECT 'unterminated;\n-- migrate:down\nSELECT 1;"), outside)
+		if err != nil {
+			t.Fatalf("parseSQLFile() error = %v", err)
+		}
+		if err := migration.Up(t.Context(), nil); !errors.Is(err, ErrNonTransactionalStatement) {
+			t.Fatalf("Up() error = %v, want %v", err, ErrNonTransactionalStatement)
+		}
+	})
+
+	t.Run("tx=false section", func(t *testing.T) {
+		migration, err := parseSQLFile([]byte("-- migrate:up tx=false\nVACUUM users;\n-- migrate:down\nSELECT 1;"), config)
+		if err != nil {
+			t.Fatalf("parseSQLFile() error = %v", err)
+		}
+		if migration.upErr != nil {
+			t.Errorf("upErr = %v, want nil", migration.upErr)
+		}
+	})
+
+	t.Run("other dialect", func(t *testing.T) {
+		clickhouse := config
+		clickhouse.Driver = NewClickHouseDriver("", "")
+		migration, err := parseSQLFile([]byte(content), clickhouse)
+		if err != nil {
+			t.Fatalf("parseSQLFile() error = %v", err)
+		}
+		if migration.upErr != nil || migration.upSegments != nil {
+			t.Errorf("up section checked for another dialect: %v, %#v", migration.upErr, migration.upSegments)
+		}
+	})
+
+	t.Run("outside the transaction", func(t *testing.T) {
+		outside := config
+		outside.NonTransactionalOutsideTx = true
+		migration, err := parseSQLFile([]byte(content), outside)
+		if err != nil {
+			t.Fatalf("parseSQLFile() error = %v", err)
+		}
+
+		want := []sqlSegment{
+			{statements: []sqlStatement{{sql: "CREATE TABLE users (id INT)", line: 2}}, tx: true},
+			{statements: []sqlStatement{
+				{sql: "CREATE INDEX CONCURRENTLY idx_users_id ON users(id)", line: 3},
+				{sql: "VACUUM users", line: 5},
+			}, tx: false},
+			{statements: []sqlStatement{{sql: "INSERT INTO users VALUES (1)", line: 6}}, tx: true},
+		}
+		if !reflect.DeepEqual(migration.upSegments, want) {
+			t.Errorf("upSegments = %#v, want %#v", migration.upSegments, want)
+		}
+		if migration.downSegments != nil {
+			t.Errorf("downSegments = %#v, want nil", migration.downSegments)
+		}
+	})
+
+	t.Run("dollar-quoted body", func(t *testing.T) {
+		outside := config
+		outside.NonTransactionalOutsideTx = true
+		const function = "CREATE FUNCTION f() RETURNS void AS $$\nBEGIN\n  PERFORM 1;\n  VACUUM;\nEND;\n$$ LANGUAGE plpgsql"
+		migration, err := parseSQLFile([]byte("-- migrate:up\n"+function+";\nVACUUM users;\n-- migrate:down\nSELECT 1;"), outside)
+		if err != nil {
+			t.Fatalf("parseSQLFile() error = %v", err)
+		}
+
+		want := []sqlSegment{
+			{statements: []sqlStatement{{sql: function, line: 2}}, tx: true},
+			{statements: []sqlStatement{{sql: "VACUUM users", line: 8}}, tx: false},
+		}
+		if !reflect.DeepEqual(migration.upSegments, want) {
+			t.Errorf("upSegments = %#v, want %#v", migration.upSegments, want)
+		}
+	})
+}
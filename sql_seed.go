@@ -0,0 +1,137 @@
+package amigo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// seedAnnotation starts the optional first line of a SQL seed carrying its options
+const seedAnnotation = "-- seed"
+
+// SQLSeed is a seed read from a SQL file, see SQLFileToSeed
+type SQLSeed struct {
+	name         string
+	body         string
+	tx           bool
+	environments []string
+	checksum     string
+	// path and source are the path and content of a file rendered as a template, see renderTemplates
+	path   string
+	source []byte
+	// line is the line the body starts at in the file, after the annotation line, see StatementError
+	line int
+
+	splitStatements bool
+}
+
+func (s SQLSeed) Seed(ctx context.Context, db *sql.DB) error {
+	exec := SQLMigration{splitStatements: s.splitStatements}
+	if s.tx {
+		return Tx(ctx, db, func(tx *sql.Tx) error {
+			return exec.execSQL(ctx, tx, s.body, s.line)
+		})
+	}
+
+	return exec.execSQL(ctx, db, s.body, s.line)
+}
+
+func (s SQLSeed) Name() string {
+	return s.name
+}
+
+// Environments returns the environments of the env annotation, see EnvironmentScoped
+func (s SQLSeed) Environments() []string {
+	return s.environments
+}
+
+// Checksum returns the sha256 of the seed file as rendered, see Checksummer
+func (s SQLSeed) Checksum() string {
+	return s.checksum
+}
+
+// SQLFileToSeed converts a sql file from an embedded filesystem to a Seeder named after the file
+//
+//	ex: "seeds/20240101120000_countries.sql" -> gives a seed named "20240101120000_countries"
+func SQLFileToSeed(fs embed.FS, path string, config Configuration) Seeder {
+	file, err := fs.ReadFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("failed to read seed file %s: %v", path, err))
+	}
+
+	seed, err := sqlFileToSeed(path, file, config)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load seed file %s: %v", path, err))
+	}
+
+	return seed
+}
+
+// sqlFileToSeed renders and parses the content of a seed file. The content of a file rendered as a template is
+// kept, to render it again with the configuration the seeds run with, see renderTemplates.
+func sqlFileToSeed(path string, file []byte, config Configuration) (SQLSeed, error) {
+	rendered, err := renderSQLTemplate(path, file, config)
+	if err != nil {
+		return SQLSeed{}, err
+	}
+	seed, err := parseSQLSeedFile(rendered)
+	if err != nil {
+		return SQLSeed{}, err
+	}
+	seed.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	seed.splitStatements = config.SplitStatements
+	if config.TemplateData != nil {
+		seed.path = path
+		seed.source = file
+	}
+
+	sum := sha256.Sum256(rendered)
+	seed.checksum = hex.EncodeToString(sum[:])
+
+	return seed, nil
+}
+
+// parseSQLSeedFile parses the content of a SQL seed file. The whole file is the seed, an optional annotation
+// line sets its options:
+// -- seed env=development,test tx=false
+// INSERT INTO countries (code) VALUES ('FR'), ('DE');
+// In this example, the seed only runs in the development and test environments, outside a transaction.
+func parseSQLSeedFile(fileContent []byte) (SQLSeed, error) {
+	seed := SQLSeed{tx: true, line: 1}
+
+	txRegexp := regexp.MustCompile(`tx=(true|false)`)
+	envRegexp := regexp.MustCompile(`env=(\S+)`)
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(fileContent))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == seedAnnotation || strings.HasPrefix(line, seedAnnotation+" ") {
+			if len(lines) == 0 {
+				seed.line++
+			}
+			parseTxAnnotation(line, &seed.tx, txRegexp)
+			seed.environments = parseListAnnotation(line, envRegexp)
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return seed, fmt.Errorf("failed to scan file: %w", err)
+	}
+
+	seed.body = strings.Join(lines, "\n")
+
+	return seed, nil
+}
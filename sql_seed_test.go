@@ -0,0 +1,55 @@
+package amigo
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_parseSQLSeedFile(t *testing.T) {
+	tests := []struct {
+		name             string
+		content          string
+		wantBody         string
+		wantTx           bool
+		wantEnvironments []string
+	}{
+		{
+			name:     "without annotation",
+			content:  "INSERT INTO countries (code) VALUES ('FR');",
+			wantBody: "INSERT INTO countries (code) VALUES ('FR');",
+			wantTx:   true,
+		},
+		{
+			name:             "environments and no transaction",
+			content:          "-- seed env=development,test tx=false\nINSERT INTO users (name) VALUES ('dev');",
+			wantBody:         "INSERT INTO users (name) VALUES ('dev');",
+			wantTx:           false,
+			wantEnvironments: []string{"development", "test"},
+		},
+		{
+			name:     "comment starting like the annotation",
+			content:  "-- seeds the countries\nINSERT INTO countries (code) VALUES ('FR');",
+			wantBody: "-- seeds the countries\nINSERT INTO countries (code) VALUES ('FR');",
+			wantTx:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSQLSeedFile([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.body != tt.wantBody {
+				t.Errorf("body: got %q, want %q", got.body, tt.wantBody)
+			}
+			if got.tx != tt.wantTx {
+				t.Errorf("tx: got %v, want %v", got.tx, tt.wantTx)
+			}
+			if !slices.Equal(got.environments, tt.wantEnvironments) {
+				t.Errorf("environments: got %v, want %v", got.environments, tt.wantEnvironments)
+			}
+		})
+	}
+}
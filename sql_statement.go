@@ -0,0 +1,432 @@
+package amigo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// statementPreviewLines is the number of lines of a statement kept in StatementError and StatementProgress
+const statementPreviewLines = 3
+
+// StatementError is returned by SQL migrations and seeds when a statement fails, locating it in the file.
+// Lines are the ones of the file as rendered when Configuration.TemplateData is set, a template adding or
+// removing lines shifts them from the lines of the source.
+type StatementError struct {
+	// Index is the position of the statement in its section, from 1 to Count. It is 0 when the section ran as a
+	// single exec and the database did not report where it failed, Line and Statement are then the ones of the
+	// first statement.
+	Index int
+	Count int
+	// Line is the line the statement starts at in the file
+	Line int
+	// Statement is the first lines of the statement
+	Statement string
+	Err       error
+}
+
+func (e *StatementError) Error() string {
+	first, _, _ := strings.Cut(e.Statement, "\n")
+	if e.Index == 0 {
+		return fmt.Sprintf("one of the %d statements from line %d (%s): %v", e.Count, e.Line, first, e.Err)
+	}
+	return fmt.Sprintf("statement %d/%d at line %d (%s): %v", e.Index, e.Count, e.Line, first, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// StatementProgress is reported before every statement of an SQL migration runs, see ContextWithStatementProgress
+type StatementProgress struct {
+	// Index is the position of the statement in its section, from 1 to Count
+	Index int
+	Count int
+	// Line is the line the statement starts at in the file
+	Line int
+	// Statement is the first lines of the statement
+	Statement string
+}
+
+type statementProgressContextKey struct{}
+
+// ContextWithStatementProgress returns a copy of ctx carrying report, which SQL migrations and seeds run with ctx
+// call before every statement. See RunnerUpOptionStatementProgress to receive the progress from UpIterator.
+func ContextWithStatementProgress(ctx context.Context, report func(StatementProgress)) context.Context {
+	return context.WithValue(ctx, statementProgressContextKey{}, report)
+}
+
+// reportStatementProgress calls the function of ContextWithStatementProgress, if any
+func reportStatementProgress(ctx context.Context, progress StatementProgress) {
+	if report, ok := ctx.Value(statementProgressContextKey{}).(func(StatementProgress)); ok {
+		report(progress)
+	}
+}
+
+type executedStatementsContextKey struct{}
+
+// executedStatements collects the statements of SQL sections executed for a migration, see
+// contextWithExecutedStatements
+type executedStatements struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+// contextWithExecutedStatements returns a copy of ctx collecting the statements executed with it, as rewritten by
+// StatementDriver. The runner sets it for every migration so that a MigrationVerifier checks only the
+// statements of that migration, even after an earlier one failed.
+func contextWithExecutedStatements(ctx context.Context) context.Context {
+	return context.WithValue(ctx, executedStatementsContextKey{}, &executedStatements{})
+}
+
+// recordExecutedStatement adds a statement to the ones collected by ctx, if any
+func recordExecutedStatement(ctx context.Context, statement string) {
+	if executed, ok := ctx.Value(executedStatementsContextKey{}).(*executedStatements); ok {
+		executed.mu.Lock()
+		defer executed.mu.Unlock()
+		executed.statements = append(executed.statements, statement)
+	}
+}
+
+// executedStatementsFromContext returns the statements collected by ctx, see contextWithExecutedStatements
+func executedStatementsFromContext(ctx context.Context) []string {
+	executed, ok := ctx.Value(executedStatementsContextKey{}).(*executedStatements)
+	if !ok {
+		return nil
+	}
+	executed.mu.Lock()
+	defer executed.mu.Unlock()
+	return slices.Clone(executed.statements)
+}
+
+// sqlStatement is a statement of an SQL file with the line it starts at
+type sqlStatement struct {
+	sql  string
+	line int
+}
+
+// statements returns the statements of a section of a file starting at firstLine: split by semicolons when
+// SplitStatements is set, else the whole section as a single statement. A section running in a transaction is
+// split anyway when splitSQLForLocation can do it safely, since running its statements one by one in the
+// transaction is the same as a single exec, so that progress and failures point at a statement.
+func (s SQLMigration) statements(section string, firstLine int, inTx bool) []sqlStatement {
+	if s.splitStatements {
+		return splitSQLStatementsWithLines(section, firstLine)
+	}
+	if inTx {
+		if statements, ok := splitSQLForLocation(section, firstLine); ok {
+			return statements
+		}
+	}
+	return []sqlStatement{{sql: section, line: statementLine(section, 0, strings.TrimSpace(section), firstLine)}}
+}
+
+// locateStatementError locates the statement of a section run as a single exec that failed with err, splitting
+// the section with splitSQLForLocation. The statement is found from the position of the error in the section
+// when the database reports one, see errorPosition. err is returned as is when it is not a StatementError or
+// the section cannot be split.
+func locateStatementError(err error, section string, firstLine int) error {
+	var statementErr *StatementError
+	if !errors.As(err, &statementErr) {
+		return err
+	}
+	statements, ok := splitSQLForLocation(section, firstLine)
+	if !ok || len(statements) < 2 {
+		return err
+	}
+
+	located := &StatementError{Count: len(statements), Line: statements[0].line, Statement: statementPreview(statements[0].sql), Err: statementErr.Err}
+	if position := errorPosition(statementErr.Err); position > 0 {
+		offset := len(section)
+		for i := range section {
+			if position--; position == 0 {
+				offset = i
+				break
+			}
+		}
+		line := firstLine + strings.Count(section[:offset], "\n")
+		for i, stmt := range statements {
+			if stmt.line > line && i > 0 {
+				break
+			}
+			located.Index, located.Line, located.Statement = i+1, stmt.line, statementPreview(stmt.sql)
+		}
+	}
+	return located
+}
+
+// errorPosition returns the position of an error in the query that caused it, in characters from 1, from the
+// Position field of the errors of PostgreSQL drivers: an integer with pgx, a string with lib/pq. It returns 0
+// when the error has no position.
+func errorPosition(err error) int {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				continue
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+
+		switch field := v.FieldByName("Position"); field.Kind() {
+		case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+			if field.Int() > 0 {
+				return int(field.Int())
+			}
+		case reflect.String:
+			if position, err := strconv.Atoi(field.String()); err == nil && position > 0 {
+				return position
+			}
+		}
+	}
+	return 0
+}
+
+// splitSQLForLocation splits a section of a file starting at firstLine on the semicolons outside of strings,
+// quoted identifiers, comments and dollar-quoted bodies, with the line the code of every statement starts at.
+// Sections with -- amigo:statement:begin blocks are split by splitSQLStatementsWithLines. It returns false when
+// the section cannot be split safely: unterminated quotes or comments, or BEGIN blocks such as trigger bodies.
+func splitSQLForLocation(section string, firstLine int) ([]sqlStatement, bool) {
+	if strings.Contains(section, statementBeginAnnotation) {
+		return splitSQLStatementsWithLines(section, firstLine), true
+	}
+
+	var statements []sqlStatement
+	codeStart := -1
+	flush := func(end int) {
+		if codeStart >= 0 {
+			statements = append(statements, sqlStatement{
+				sql:  strings.TrimSpace(section[codeStart:end]),
+				line: firstLine + strings.Count(section[:codeStart], "\n"),
+			})
+		}
+		codeStart = -1
+	}
+
+	for i := 0; i < len(section); i++ {
+		ch := section[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			continue
+		case strings.HasPrefix(section[i:], "--"):
+			end := strings.IndexByte(section[i:], '\n')
+			if end < 0 {
+				i = len(section)
+				continue
+			}
+			i += end
+			continue
+		case strings.HasPrefix(section[i:], "/*"):
+			end := strings.Index(section[i+2:], "*/")
+			if end < 0 {
+				return nil, false
+			}
+			i += 2 + end + 1
+			continue
+		case ch == ';':
+			flush(i)
+			continue
+		}
+
+		if codeStart < 0 {
+			codeStart = i
+		}
+
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := quotedEnd(section, i, i > 0 && (section[i-1] == 'E' || section[i-1] == 'e') && ch == '\'')
+			if end < 0 {
+				return nil, false
+			}
+			i = end
+		case ch == '$' && (i == 0 || !isIdentifierByte(section[i-1])):
+			tag := dollarQuoteTag(section[i:])
+			if tag == "" {
+				continue
+			}
+			end := strings.Index(section[i+len(tag):], tag)
+			if end < 0 {
+				return nil, false
+			}
+			i += len(tag) + end + len(tag) - 1
+		case isIdentifierByte(ch) && (i == 0 || !isIdentifierByte(section[i-1])):
+			end := i
+			for end < len(section) && isIdentifierByte(section[end]) {
+				end++
+			}
+			if strings.EqualFold(section[i:end], "BEGIN") {
+				return nil, false
+			}
+			i = end - 1
+		}
+	}
+	flush(len(section))
+
+	return statements, true
+}
+
+// quotedEnd returns the offset of the quote closing the string or identifier opened at start, doubled quotes
+// being escaped, or -1 when it is not closed. Backslashes escape characters in escape strings.
+func quotedEnd(section string, start int, backslashEscapes bool) int {
+	quote := section[start]
+	for i := start + 1; i < len(section); i++ {
+		switch {
+		case backslashEscapes && section[i] == '\\':
+			i++
+		case section[i] == quote && i+1 < len(section) && section[i+1] == quote:
+			i++
+		case section[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// dollarQuoteTag returns the $tag$ opening a dollar-quoted string at the start of s, or an empty string
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1]
+		case !isIdentifierByte(s[i]) || (i == 1 && s[i] >= '0' && s[i] <= '9'):
+			return ""
+		}
+	}
+	return ""
+}
+
+// stripSQLComments replaces the -- and /* */ comments of sql outside of strings, quoted identifiers and
+// dollar-quoted bodies with a space. An unterminated quote or comment runs to the end of sql.
+func stripSQLComments(sql string) string {
+	var b strings.Builder
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return b.String()
+			}
+			b.WriteByte(' ')
+			i += end - 1
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			b.WriteByte(' ')
+			i += 2 + end + 1
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := quotedEnd(sql, i, i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && ch == '\'')
+			if end < 0 {
+				end = len(sql) - 1
+			}
+			b.WriteString(sql[i : end+1])
+			i = end
+		case ch == '$' && (i == 0 || !isIdentifierByte(sql[i-1])) && dollarQuoteTag(sql[i:]) != "":
+			tag := dollarQuoteTag(sql[i:])
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				b.WriteString(sql[i:])
+				return b.String()
+			}
+			b.WriteString(sql[i : i+len(tag)+end+len(tag)])
+			i += len(tag) + end + len(tag) - 1
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// isIdentifierByte reports whether ch can be part of an unquoted identifier
+func isIdentifierByte(ch byte) bool {
+	return ch == '_' || ch == '$' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+// splitSQLStatementsWithLines splits a section of a file starting at firstLine like
+// splitSQLStatementsWithAnnotations, with the line every statement starts at
+func splitSQLStatementsWithLines(section string, firstLine int) []sqlStatement {
+	var statements []sqlStatement
+	cursor := 0
+	for _, stmt := range splitSQLStatementsWithAnnotations(section) {
+		line := statementLine(section, cursor, stmt, firstLine)
+		first, _, _ := strings.Cut(stmt, "\n")
+		if i := strings.Index(section[cursor:], first); i >= 0 {
+			cursor += i + len(first)
+		}
+		statements = append(statements, sqlStatement{sql: stmt, line: line})
+	}
+	return statements
+}
+
+// statementLine returns the line a statement starts at, searching its first line in section from cursor
+func statementLine(section string, cursor int, stmt string, firstLine int) int {
+	first, _, _ := strings.Cut(stmt, "\n")
+	i := strings.Index(section[cursor:], first)
+	if i < 0 {
+		return firstLine
+	}
+	return firstLine + strings.Count(section[:cursor+i], "\n")
+}
+
+// statementPreview returns the first lines of a statement
+func statementPreview(stmt string) string {
+	lines := strings.Split(strings.TrimSpace(stmt), "\n")
+	if len(lines) > statementPreviewLines {
+		lines = append(lines[:statementPreviewLines], "...")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// execStatements executes statements one by one, reporting their progress. offset and count place them among
+// the statements of their section. When the driver of ctx is a StatementDriver, its session statements run first
+// on the same connection and it rewrites every statement.
+func execStatements(ctx context.Context, exec sqlExecutor, statements []sqlStatement, offset, count int) error {
+	driver, _ := DriverFromContext(ctx)
+	statementDriver, _ := driver.(StatementDriver)
+	if statementDriver != nil {
+		if session := statementDriver.SessionStatements(); len(session) > 0 {
+			// Session settings only hold on the connection they are set on
+			if db, ok := exec.(*sql.DB); ok {
+				conn, err := db.Conn(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to get a connection: %w", err)
+				}
+				defer conn.Close()
+				exec = conn
+			}
+
+			for _, stmt := range session {
+				if _, err := exec.ExecContext(ctx, stmt); err != nil {
+					return fmt.Errorf("failed to run session statement %q: %w", stmt, err)
+				}
+			}
+		}
+	}
+
+	for i, stmt := range statements {
+		preview := statementPreview(stmt.sql)
+		reportStatementProgress(ctx, StatementProgress{Index: offset + i + 1, Count: count, Line: stmt.line, Statement: preview})
+
+		query := stmt.sql
+		if statementDriver != nil {
+			query = statementDriver.RewriteStatement(query)
+		}
+		recordExecutedStatement(ctx, query)
+
+		if _, err := exec.ExecContext(ctx, query); err != nil {
+			return &StatementError{Index: offset + i + 1, Count: count, Line: stmt.line, Statement: preview, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package amigo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func Test_splitSQLStatementsWithLines(t *testing.T) {
+	section := "\nCREATE TABLE a (id INT);\n\n-- comment\nCREATE TABLE b (\n  id INT\n); CREATE TABLE c (id INT);\n" +
+		"-- amigo:statement:begin\nCREATE FUNCTION f() AS $$ SELECT 1; $$;\n-- amigo:statement:end"
+
+	var lines []int
+	for _, stmt := range splitSQLStatementsWithLines(section, 10) {
+		lines = append(lines, stmt.line)
+	}
+
+	if want := []int{11, 13, 16, 18}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %v, want %v", lines, want)
+	}
+}
+
+// failingExecutor fails the statements containing fail
+type failingExecutor struct {
+	sqlExecutor
+	fail string
+}
+
+func (e failingExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if strings.Contains(query, e.fail) {
+		return nil, errors.New("syntax error")
+	}
+	return nil, nil
+}
+
+func TestSQLMigration_execSQL(t *testing.T) {
+	migration, err := parseSQLFile([]byte("-- migrate:up\nCREATE TABLE a (id INT);\nCREATE TABLE b (\n  id INTT\n);\n-- migrate:down\nSELECT 1;"), DefaultConfiguration)
+	if err != nil {
+		t.Fatalf("parseSQLFile() error = %v", err)
+	}
+	migration.splitStatements = true
+
+	var progress []StatementProgress
+	ctx := ContextWithStatementProgress(context.Background(), func(p StatementProgress) {
+		progress = append(progress, p)
+	})
+
+	err = migration.execSQL(ctx, failingExecutor{fail: "INTT"}, migration.up, migration.upLine)
+
+	var statementErr *StatementError
+	if !errors.As(err, &statementErr) {
+		t.Fatalf("execSQL() error = %v, want a StatementError", err)
+	}
+	want := &StatementError{Index: 2, Count: 2, Line: 3, Statement: "CREATE TABLE b (\n  id INTT\n)", Err: statementErr.Err}
+	if !reflect.DeepEqual(statementErr, want) {
+		t.Errorf("execSQL() error = %#v, want %#v", statementErr, want)
+	}
+
+	if len(progress) != 2 || progress[0].Index != 1 || progress[0].Line != 2 || progress[1].Index != 2 {
+		t.Errorf("progress = %+v, want statements 1/2 at line 2 and 2/2", progress)
+	}
+}
+
+func Test_splitSQLForLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		section string
+		want    []sqlStatement
+		wantOK  bool
+	}{
+		{
+			name:    "statements, strings and comments",
+			section: "\nCREATE TABLE a (id INT); -- a; comment\n/* b; */ INSERT INTO a VALUES ('x;\n''y');\nSELECT \"a;\" FROM a",
+			want: []sqlStatement{
+				{sql: "CREATE TABLE a (id INT)", line: 11},
+				{sql: "INSERT INTO a VALUES ('x;\n''y')", line: 12},
+				{sql: "SELECT \"a;\" FROM a", line: 14},
+			},
+			wantOK: true,
+		},
+		{
+			name:    "dollar-quoted function",
+			section: "CREATE FUNCTION f() RETURNS INT AS $body$\nBEGIN\n  RETURN 1;\nEND;\n$body$ LANGUAGE plpgsql;\nSELECT f();",
+			want: []sqlStatement{
+				{sql: "CREATE FUNCTION f() RETURNS INT AS $body$\nBEGIN\n  RETURN 1;\nEND;\n$body$ LANGUAGE plpgsql", line: 10},
+				{sql: "SELECT f()", line: 15},
+			},
+			wantOK: true,
+		},
+		{
+			name:    "trigger body",
+			section: "CREATE TRIGGER t AFTER INSERT ON a BEGIN\n  DELETE FROM b;\nEND;",
+			wantOK:  false,
+		},
+		{
+			name:    "unterminated string",
+			section: "SELECT 'a;",
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := splitSQLForLocation(tt.section, 10)
+			if ok != tt.wantOK {
+				t.Fatalf("splitSQLForLocation() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSQLForLocation() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLMigration_statements_unsplit(t *testing.T) {
+	migration, err := parseSQLFile([]byte("-- migrate:up tx=true\nCREATE TABLE a (id INT);\n\nCREATE TABLE b (\n  id INTT\n);\nCREATE TABLE c (id INT);\n-- migrate:down\nSELECT 1;"), DefaultConfiguration)
+	if err != nil {
+		t.Fatalf("parseSQLFile() error = %v", err)
+	}
+
+	if got := migration.statements(migration.up, migration.upLine, false); len(got) != 1 {
+		t.Errorf("statements() outside a transaction = %d statement(s), want a single exec", len(got))
+	}
+
+	statements := migration.statements(migration.up, migration.upLine, true)
+	err = execStatements(context.Background(), failingExecutor{fail: "INTT"}, statements, 0, len(statements))
+
+	var statementErr *StatementError
+	if !errors.As(err, &statementErr) {
+		t.Fatalf("execStatements() error = %v, want a StatementError", err)
+	}
+	if statementErr.Index != 2 || statementErr.Count != 3 || statementErr.Line != 4 {
+		t.Errorf("execStatements() error = statement %d/%d at line %d, want statement 2/3 at line 4",
+			statementErr.Index, statementErr.Count, statementErr.Line)
+	}
+}
+
+// pgxError and pqError have the Position field of the errors of pgx and lib/pq
+type pgxError struct{ Position int32 }
+
+func (e *pgxError) Error() string { return "syntax error" }
+
+type pqError struct{ Position string }
+
+func (e pqError) Error() string { return "syntax error" }
+
+// positionExecutor fails with err
+type positionExecutor struct {
+	sqlExecutor
+	err error
+}
+
+func (e positionExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return nil, e.err
+}
+
+func TestSQLMigration_execSQL_singleExec(t *testing.T) {
+	const content = "-- migrate:up tx=false\n-- é\nCREATE TABLE a (id INT);\n\nCREATE TABLE b (\n  id INTT\n); CREATE TABLE c (id INT);\n-- migrate:down\nSELECT 1;"
+	migration, err := parseSQLFile([]byte(content), DefaultConfiguration)
+	if err != nil {
+		t.Fatalf("parseSQLFile() error = %v", err)
+	}
+	// Positions count characters, é is two bytes
+	position := utf8.RuneCountInString(migration.up[:strings.Index(migration.up, "INTT")]) + 1
+
+	tests := []struct {
+		name string
+		err  error
+		want StatementError
+	}{
+		{
+			name: "pgx position",
+			err:  &pgxError{Position: int32(position)},
+			want: StatementError{Index: 2, Count: 3, Line: 5, Statement: "CREATE TABLE b (\n  id INTT\n)"},
+		},
+		{
+			name: "wrapped lib/pq position",
+			err:  fmt.Errorf("exec: %w", pqError{Position: strconv.Itoa(position)}),
+			want: StatementError{Index: 2, Count: 3, Line: 5, Statement: "CREATE TABLE b (\n  id INTT\n)"},
+		},
+		{
+			name: "without position",
+			err:  errors.New("syntax error"),
+			want: StatementError{Index: 0, Count: 3, Line: 3, Statement: "CREATE TABLE a (id INT)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := migration.execSQL(t.Context(), positionExecutor{err: tt.err}, migration.up, migration.upLine)
+
+			var statementErr *StatementError
+			if !errors.As(err, &statementErr) {
+				t.Fatalf("execSQL() error = %v, want a StatementError", err)
+			}
+			tt.want.Err = tt.err
+			if !reflect.DeepEqual(*statementErr, tt.want) {
+				t.Errorf("execSQL() error = %#v, want %#v", *statementErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLMigration_execSQL_template(t *testing.T) {
+	// The lines of the rendered file: the template removes the line of the condition
+	const source = "-- migrate:up\n{{if .Audit}}CREATE TABLE audit (id INT);\n{{end}}CREATE TABLE a (id INTT);\n-- migrate:down\nSELECT 1;"
+
+	config := DefaultConfiguration
+	config.TemplateData = map[string]any{"Audit": false}
+	migration, err := sqlFileToMigration("20240101000000_create_a.sql", []byte(source), config)
+	if err != nil {
+		t.Fatalf("sqlFileToMigration() error = %v", err)
+	}
+
+	err = migration.execSQL(t.Context(), failingExecutor{fail: "INTT"}, migration.up, migration.upLine)
+
+	var statementErr *StatementError
+	if !errors.As(err, &statementErr) {
+		t.Fatalf("execSQL() error = %v, want a StatementError", err)
+	}
+	if statementErr.Line != 2 {
+		t.Errorf("execSQL() error at line %d, want line 2 of the rendered file", statementErr.Line)
+	}
+}
@@ -0,0 +1,86 @@
+package amigo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"text/template"
+)
+
+// clusterDriver is implemented by drivers running DDL on a cluster, such as ClickHouseDriver
+type clusterDriver interface {
+	Cluster() string
+}
+
+// templateData returns the data SQL files are rendered with: Environment, Cluster when the driver runs on a
+// cluster, and Configuration.TemplateData, which takes precedence
+func templateData(config Configuration) map[string]any {
+	data := map[string]any{
+		"Environment": config.Environment,
+		"Cluster":     "",
+	}
+	if driver, ok := config.Driver.(clusterDriver); ok {
+		data["Cluster"] = driver.Cluster()
+	}
+	maps.Copy(data, config.TemplateData)
+	return data
+}
+
+// renderSQLTemplate renders a SQL file with text/template when Configuration.TemplateData is set, and returns it
+// unchanged otherwise. A key missing from the data is an error.
+func renderSQLTemplate(name string, content []byte, config Configuration) ([]byte, error) {
+	if config.TemplateData == nil {
+		return content, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData(config)); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// renderTemplates renders again the SQL migrations and seeds loaded as templates, with config. Files are loaded
+// before the CLI applies CLIConfig.Environment and CLIConfig.AppVersion, rendering them again with the final
+// configuration makes .Environment the environment recorded with the migrations.
+func renderTemplates(migrations []Migration, seeds []Seeder, config Configuration) ([]Migration, []Seeder, error) {
+	var errs []error
+
+	migrations = slices.Clone(migrations)
+	for i, m := range migrations {
+		migration, ok := m.(SQLMigration)
+		if !ok || migration.source == nil {
+			continue
+		}
+		rendered, err := sqlFileToMigration(migration.path, migration.source, config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load migration file %s: %w", migration.path, err))
+			continue
+		}
+		migrations[i] = rendered
+	}
+
+	seeds = slices.Clone(seeds)
+	for i, s := range seeds {
+		seed, ok := s.(SQLSeed)
+		if !ok || seed.source == nil {
+			continue
+		}
+		rendered, err := sqlFileToSeed(seed.path, seed.source, config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load seed file %s: %w", seed.path, err))
+			continue
+		}
+		seeds[i] = rendered
+	}
+
+	return migrations, seeds, errors.Join(errs...)
+}
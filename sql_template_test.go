@@ -0,0 +1,123 @@
+package amigo
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func Test_renderSQLTemplate(t *testing.T) {
+	const source = "-- migrate:up\nCREATE TABLE t ON CLUSTER '{{.Cluster}}' (id UInt64) -- {{.Environment}} {{.Role}}"
+
+	tests := []struct {
+		name    string
+		source  string
+		config  Configuration
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "without template data",
+			source: source,
+			config: Configuration{Environment: "production"},
+			want:   source,
+		},
+		{
+			name:   "environment, cluster and template data",
+			source: source,
+			config: Configuration{
+				Environment:  "production",
+				Driver:       NewClickHouseDriver("", "main"),
+				TemplateData: map[string]any{"Role": "app"},
+			},
+			want: "-- migrate:up\nCREATE TABLE t ON CLUSTER 'main' (id UInt64) -- production app",
+		},
+		{
+			name:    "missing key",
+			source:  source,
+			config:  Configuration{TemplateData: map[string]any{}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid template",
+			source:  "SELECT '{{.Environment'",
+			config:  Configuration{TemplateData: map[string]any{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderSQLTemplate("migration.sql", []byte(tt.source), tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderSQLTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("renderSQLTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_renderTemplates(t *testing.T) {
+	const migrationSource = "-- migrate:up\nCREATE TABLE t_{{.Environment}} (id INT);\n-- migrate:down\nDROP TABLE t_{{.Environment}};"
+	const seedSource = "INSERT INTO t_{{.Environment}} VALUES (1);"
+
+	loaded := DefaultConfiguration
+	loaded.Environment = "development"
+	loaded.TemplateData = map[string]any{}
+
+	migration, err := sqlFileToMigration("20240101000000_create_t.sql", []byte(migrationSource), loaded)
+	if err != nil {
+		t.Fatalf("sqlFileToMigration() error = %v", err)
+	}
+	seed, err := sqlFileToSeed("seeds/countries.sql", []byte(seedSource), loaded)
+	if err != nil {
+		t.Fatalf("sqlFileToSeed() error = %v", err)
+	}
+
+	final := loaded
+	final.Environment = "production"
+	migrations, seeds, err := renderTemplates([]Migration{migration}, []Seeder{seed}, final)
+	if err != nil {
+		t.Fatalf("renderTemplates() error = %v", err)
+	}
+
+	rendered := migrations[0].(SQLMigration)
+	if rendered.up != "CREATE TABLE t_production (id INT);" || rendered.name != "create_t" {
+		t.Errorf("rendered migration = %q named %q, want it rendered for production", rendered.up, rendered.name)
+	}
+	if rendered.checksum == migration.checksum {
+		t.Errorf("checksum = %s, want the checksum of the rendered file to change", rendered.checksum)
+	}
+	if body := seeds[0].(SQLSeed).body; body != "INSERT INTO t_production VALUES (1);" {
+		t.Errorf("rendered seed = %q, want it rendered for production", body)
+	}
+	if migration.up != "CREATE TABLE t_development (id INT);" {
+		t.Errorf("migration loaded for development changed to %q", migration.up)
+	}
+
+	t.Run("error with the final configuration", func(t *testing.T) {
+		const source = "-- migrate:up\n{{if eq .Environment \"production\"}}GRANT SELECT ON t TO {{.Role}};{{end}}\n-- migrate:down\nSELECT 1;"
+		migration, err := sqlFileToMigration("20240101000000_grant.sql", []byte(source), loaded)
+		if err != nil {
+			t.Fatalf("sqlFileToMigration() error = %v", err)
+		}
+
+		var errorOutput bytes.Buffer
+		cli := NewCLI(CLIConfig{
+			Config:      loaded,
+			Environment: "production",
+			Migrations:  []Migration{migration},
+			Output:      io.Discard,
+			ErrorOut:    &errorOutput,
+		})
+		if code := cli.Run([]string{"--color=never", "status"}); code != 1 {
+			t.Fatalf("Run() = %d, want 1", code)
+		}
+		if !strings.Contains(errorOutput.String(), "20240101000000_grant.sql") {
+			t.Errorf("errors = %q, want the file named", errorOutput.String())
+		}
+	})
+}
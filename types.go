@@ -3,6 +3,7 @@ package amigo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 )
 
@@ -21,9 +22,38 @@ type Configuration struct {
 	SQLFileDownAnnotation string
 
 	// SplitStatements controls whether SQL migrations are split by semicolons.
-	// Default false: entire migration sent as single exec (PostgreSQL, SQLite)
+	// Default false: entire migration sent as single exec (PostgreSQL, SQLite), except sections running in a
+	// transaction, run statement by statement when they can be split safely to report progress and locate failures
 	// When true: split by semicolons, respecting -- amigo:statement:begin/end annotations (ClickHouse)
 	SplitStatements bool
+
+	// NonTransactionalOutsideTx runs the statements that cannot run inside a transaction, such as
+	// CREATE INDEX CONCURRENTLY or VACUUM on PostgreSQL, outside the transaction of their tx=true section: the
+	// statements before and after them run in their own transactions. Default false: running the section
+	// fails with ErrNonTransactionalStatement.
+	NonTransactionalOutsideTx bool
+
+	// Environment is the name of the environment migrations run against (e.g. production, staging).
+	// It is recorded with every applied migration in the schema_migrations table.
+	Environment string
+
+	// AppVersion is the version of the application embedding the migrations.
+	// It is recorded with every applied migration in the schema_migrations table.
+	AppVersion string
+
+	// TemplateData, when not nil, renders SQL migration and seed files with text/template when they are loaded,
+	// with these values and by default .Environment and .Cluster, the cluster of drivers running on one such as
+	// ClickHouseDriver. A value missing from the data fails the load. The CLI renders the files again once
+	// CLIConfig.Environment is applied, so that .Environment is the environment recorded with the migrations.
+	// Checksums are computed on the rendered files: a repeatable migration or seed runs again when a value
+	// it uses changes.
+	TemplateData map[string]any
+
+	// SchemaFile, when not empty, is the path of a file rewritten with the schema of the database after every
+	// up or down that applied or reverted a migration, even when a later one failed or the command was
+	// interrupted, so that the schema changes of a migration can be reviewed with it. The driver must implement
+	// SchemaDumper.
+	SchemaFile string
 }
 
 var DefaultConfiguration = Configuration{
@@ -38,10 +68,85 @@ type Migration interface {
 	Date() int64
 }
 
+// ErrIrreversible is returned when reverting a migration that cannot be undone.
+// Down methods of Go migrations can return it, SQL migrations return it when their down section is empty
+// or annotated with "irreversible".
+var ErrIrreversible = errors.New("migration is irreversible")
+
+// ErrRepeatableNotSupported is returned when running repeatable migrations with a driver that does not
+// implement RepeatableDriver.
+var ErrRepeatableNotSupported = errors.New("repeatable migrations are not supported")
+
+// ErrUnmetDependency is returned when a migration cannot be applied before a migration it depends on, or
+// reverted while an applied migration depends on it. See Dependent.
+var ErrUnmetDependency = errors.New("unmet migration dependency")
+
+// ErrPartiallySquashed is returned when a database has applied some of the migrations replaced by a squashed
+// migration, but not the newest one. See Squashed.
+var ErrPartiallySquashed = errors.New("migrations replaced by a squashed migration are partially applied")
+
+// ErrSchemaDumpNotSupported is returned when dumping the schema with a driver that does not implement SchemaDumper.
+var ErrSchemaDumpNotSupported = errors.New("schema dump is not supported")
+
+// ErrSchemaLoadNotSupported is returned when loading a schema with a driver that does not implement SchemaLoader.
+var ErrSchemaLoadNotSupported = errors.New("schema load is not supported")
+
+// ErrDatabaseNotEmpty is returned when loading a schema into a database that already has tables or applied
+// migrations, see RunnerLoadSchemaOptionForce.
+var ErrDatabaseNotEmpty = errors.New("database is not empty")
+
+// ErrSeedNotSupported is returned when running seeds with a driver that does not implement SeedDriver.
+var ErrSeedNotSupported = errors.New("seeds are not supported")
+
+// Irreversible is an optional interface for migrations that cannot be undone.
+// When Irreversible returns true, DownIterator refuses to revert across the migration before reverting anything.
+type Irreversible interface {
+	Irreversible() bool
+}
+
+// MigrationKind is the kind of source a migration is written in
+type MigrationKind string
+
+const (
+	MigrationKindSQL MigrationKind = "sql"
+	MigrationKindGo  MigrationKind = "go"
+)
+
 type MigrationRecord struct {
-	Date      int64
-	Name      string
-	AppliedAt time.Time
+	Date        int64
+	Name        string
+	AppliedAt   time.Time
+	Environment string
+
+	// Duration is how long the migration took to apply
+	Duration time.Duration
+	// OSUser is the OS user that applied the migration
+	OSUser string
+	// Hostname is the host that applied the migration
+	Hostname string
+	// AmigoVersion is the version of amigo that applied the migration, see Version
+	AmigoVersion string
+	// AppVersion is the application version that applied the migration, see Configuration.AppVersion
+	AppVersion string
+	// Kind is the kind of the migration (sql or go)
+	Kind MigrationKind
+	// Checksum is the checksum of the migration source when it was applied, empty when unknown, see Checksummer
+	Checksum string
+}
+
+// Checksummer is an optional interface for migrations that can fingerprint their source.
+// The checksum is recorded when the migration is applied: a different checksum afterwards means the migration
+// was edited (drift) or, for repeatable migrations, that it must run again. SQL migrations implement it.
+type Checksummer interface {
+	Checksum() string
+}
+
+// Repeatable is an optional interface for migrations without a date that run again whenever their checksum
+// changes, such as views, functions or grants. Repeatable migrations must also implement Checksummer; they run
+// after all the versioned migrations, in name order, and are never reverted.
+// SQL files named R_<name>.sql are repeatable.
+type Repeatable interface {
+	Repeatable() bool
 }
 
 type Driver interface {
@@ -52,7 +157,217 @@ type Driver interface {
 	Name() string
 }
 
+// SchemaMigrationsTableInspector is implemented by drivers that can tell whether the schema_migrations
+// table exists without creating it. Read-only commands such as check rely on it to never write to the database.
+type SchemaMigrationsTableInspector interface {
+	SchemaMigrationsTableExists(ctx context.Context, db *sql.DB) (bool, error)
+}
+
+// MigrationDirection is the direction a migration ran in
+type MigrationDirection string
+
+const (
+	MigrationDirectionUp   MigrationDirection = "up"
+	MigrationDirectionDown MigrationDirection = "down"
+)
+
+// HistoryStatus is the outcome of a migration run recorded in the history
+type HistoryStatus string
+
+const (
+	HistoryStatusSuccess HistoryStatus = "success"
+	HistoryStatusFailure HistoryStatus = "failure"
+)
+
+// HistoryRecord is an entry of the append-only schema_migrations_history table.
+// Every applied, reverted or failed migration adds one entry.
+type HistoryRecord struct {
+	// Batch identifies the run (one up or down invocation) the entry belongs to
+	Batch     int64
+	Date      int64
+	Name      string
+	Direction MigrationDirection
+	Status    HistoryStatus
+	// Error is the error message of a failed migration
+	Error     string
+	StartedAt time.Time
+	Duration  time.Duration
+
+	Environment  string
+	OSUser       string
+	Hostname     string
+	AmigoVersion string
+	AppVersion   string
+	Kind         MigrationKind
+}
+
+// HistoryFilter restricts the entries returned by HistoryDriver.GetHistory
+type HistoryFilter struct {
+	// Date only returns the entries of the migration with this date when not zero
+	Date int64
+	// Limit only returns the most recent entries when positive
+	Limit int
+}
+
+// HistoryDriver is implemented by drivers that keep an append-only history of migration runs, including
+// reverts and failures, next to the schema_migrations table. The history table is created by
+// CreateSchemaMigrationsTableIfNotExists.
+type HistoryDriver interface {
+	// InsertHistory appends entries to the history
+	InsertHistory(ctx context.Context, db *sql.DB, list []HistoryRecord) error
+	// GetHistory returns the entries matching the filter, most recent first
+	GetHistory(ctx context.Context, db *sql.DB, filter HistoryFilter) ([]HistoryRecord, error)
+	// NextHistoryBatch returns the batch number to use for a new run
+	NextHistoryBatch(ctx context.Context, db *sql.DB) (int64, error)
+}
+
+// SchemaLayoutVersioner is implemented by drivers that version the layout of amigo's own tables and upgrade
+// them in CreateSchemaMigrationsTableIfNotExists.
+type SchemaLayoutVersioner interface {
+	// SchemaLayoutVersion returns the layout version stored in the database, 0 when the tables were never
+	// created or predate layout versioning, and the latest layout version known by the driver.
+	// It never creates nor upgrades tables.
+	SchemaLayoutVersion(ctx context.Context, db *sql.DB) (current, latest int, err error)
+}
+
+// SchemaDumper is implemented by drivers that can dump the schema of the database as DDL statements, without
+// amigo's own tables. The output must be deterministic, the same schema always giving the same dump, so that it
+// diffs cleanly. See Runner.DumpSchema.
+type SchemaDumper interface {
+	DumpSchema(ctx context.Context, db *sql.DB) (string, error)
+}
+
+// SchemaLoader is implemented by drivers that can record migrations inside a transaction, so that a schema dump
+// and the migrations it covers are loaded atomically. See Runner.LoadSchema.
+type SchemaLoader interface {
+	InsertMigrationsTx(ctx context.Context, tx *sql.Tx, list []MigrationRecord) error
+}
+
+// StatementDriver is implemented by drivers adapting how SQL migrations and seeds run their statements, such as
+// ClickHouseDriver running DDL on its cluster
+type StatementDriver interface {
+	// SessionStatements returns the statements to run on the connection before the statements of a section,
+	// such as SET statements. The section then runs on a single connection.
+	SessionStatements() []string
+	// RewriteStatement returns the statement to run instead of a statement of a section
+	RewriteStatement(statement string) string
+}
+
+// MigrationVerifier is implemented by drivers that check a migration took effect everywhere before the runner
+// records it as applied or reverted, such as ClickHouseDriver checking distributed DDL on every host of its
+// cluster. The migration fails when it returns an error.
+type MigrationVerifier interface {
+	// VerifyMigration checks the work the database did for a migration started at start
+	VerifyMigration(ctx context.Context, db *sql.DB, start time.Time) error
+}
+
+// RepeatableDriver is implemented by drivers that track repeatable migrations, by name, in a table created
+// by CreateSchemaMigrationsTableIfNotExists. See Repeatable.
+type RepeatableDriver interface {
+	// GetRepeatableMigrations returns the last run of every repeatable migration, Date is always 0
+	GetRepeatableMigrations(ctx context.Context, db *sql.DB) ([]MigrationRecord, error)
+	// UpsertRepeatableMigration records the run of a repeatable migration, replacing the previous one
+	UpsertRepeatableMigration(ctx context.Context, db *sql.DB, record MigrationRecord) error
+}
+
+// Seeder inserts data, such as reference data or development fixtures, separately from the schema migrations
+// so that reverting a migration never depends on it. Seeds are tracked by name in their own table: they run
+// once, in name order, and again only when asked to, so a re-runnable seed must be idempotent.
+// SQL files are turned into seeders by SQLFileToSeed.
+type Seeder interface {
+	Name() string
+	Seed(ctx context.Context, db *sql.DB) error
+}
+
+// EnvironmentScoped is an optional interface restricting the environments a seed or a migration runs in,
+// such as development fixtures. When Environments returns names, it runs only when Configuration.Environment
+// (or RunnerUpOptionEnvironment) is one of them. SQL files declare it with an env= annotation.
+type EnvironmentScoped interface {
+	Environments() []string
+}
+
+// Dependent is an optional interface for migrations that must run after other migrations, identified by
+// their dates, instead of relying on the date order alone: a data backfill after the migration adding its
+// column, while independent migrations of other branches keep their own order. Migrations are applied in
+// date order, moved after their dependencies; cycles and dependencies on unknown migrations are errors.
+// SQL files declare it with a depends= annotation.
+type Dependent interface {
+	DependsOn() []int64
+}
+
+// Squashed is an optional interface for migrations replacing older migrations, identified by their dates, that
+// were combined into one by the squash command. A squashed migration has the date of the newest migration it
+// replaces: a database where that one is applied considers the squashed migration applied, and the records of
+// the replaced migrations are no longer reported as unknown. SQL files declare it with amigo:squashed lines.
+type Squashed interface {
+	Squashes() []int64
+}
+
+// Tagged is an optional interface for migrations that only run for some deployments, such as a region.
+// When a run selects tags with RunnerUpOptionTags, migrations with tags run only if one of them is selected.
+// SQL files declare it with a tags= annotation.
+type Tagged interface {
+	Tags() []string
+}
+
+// SeedDriver is implemented by drivers that track seeds, by name, in a table created by
+// CreateSchemaMigrationsTableIfNotExists. See Seeder.
+type SeedDriver interface {
+	// GetAppliedSeeds returns the last run of every seed, Date is always 0
+	GetAppliedSeeds(ctx context.Context, db *sql.DB) ([]MigrationRecord, error)
+	// UpsertSeed records the run of a seed, replacing the previous one
+	UpsertSeed(ctx context.Context, db *sql.DB, record MigrationRecord) error
+}
+
 type MigrationStatus struct {
 	Migration MigrationRecord
 	Applied   bool
+	// Skipped is set for pending migrations not selected by the environment or tags of the run
+	Skipped bool
+}
+
+// RepeatableState tells whether a repeatable migration must run
+type RepeatableState string
+
+const (
+	// RepeatableStatePending is a repeatable migration that never ran
+	RepeatableStatePending RepeatableState = "pending"
+	// RepeatableStateChanged is a repeatable migration whose checksum changed since its last run
+	RepeatableStateChanged RepeatableState = "changed"
+	// RepeatableStateUpToDate is a repeatable migration that ran with its current checksum
+	RepeatableStateUpToDate RepeatableState = "up-to-date"
+	// RepeatableStateSkipped is a repeatable migration not selected by the environment or tags of the run
+	RepeatableStateSkipped RepeatableState = "skipped"
+)
+
+// SeedState tells whether a seed ran
+type SeedState string
+
+const (
+	// SeedStatePending is a seed that never ran
+	SeedStatePending SeedState = "pending"
+	// SeedStateApplied is a seed that ran
+	SeedStateApplied SeedState = "applied"
+	// SeedStateChanged is a seed that ran with another checksum, see Checksummer
+	SeedStateChanged SeedState = "changed"
+	// SeedStateSkipped is a seed scoped to other environments, see EnvironmentScoped
+	SeedStateSkipped SeedState = "skipped"
+)
+
+// SeedStatus is the status of a seed. Record is the record of its last run, with the current name when it
+// never ran.
+type SeedStatus struct {
+	Record MigrationRecord
+	// Environments are the environments the seed is restricted to, empty when it runs everywhere
+	Environments []string
+	State        SeedState
+}
+
+// RepeatableStatus is the status of a repeatable migration. Migration is the record of its last run, with the
+// current name and checksum when it never ran.
+type RepeatableStatus struct {
+	Migration MigrationRecord
+	// Checksum is the current checksum of the migration
+	Checksum string
+	State    RepeatableState
 }
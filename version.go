@@ -0,0 +1,52 @@
+package amigo
+
+import (
+	"os"
+	"os/user"
+	"runtime/debug"
+	"sync"
+)
+
+const modulePath = "github.com/alexisvisco/amigo"
+
+// Version returns the version of amigo compiled into the binary, read from the build information.
+// It returns "(devel)" when amigo is built from a local checkout or a replace directive.
+var Version = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil || dep.Version == "" {
+			return "(devel)"
+		}
+		return dep.Version
+	}
+
+	return "(devel)"
+})
+
+// currentOSUser returns the name of the OS user running the process, or an empty string when unknown
+func currentOSUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// currentHostname returns the host name of the machine running the process, or an empty string when unknown
+func currentHostname() string {
+	hostname, _ := os.Hostname()
+	return hostname
+}